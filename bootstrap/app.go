@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-03 16:04:06
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-05-13 05:08:21
+ * @LastEditTime: 2026-10-23 14:26:41
  * @Description: app
  */
 
@@ -19,12 +19,21 @@ import (
 
 	"github.com/liziwei01/gin-lib/library/conf"
 	"github.com/liziwei01/gin-lib/library/env"
+	"github.com/liziwei01/gin-lib/library/logit"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
 	appConfCertsDir = "certs"
+
+	// 管理接口的超时，/debug/logs/tail 是长连接，不受 WriteTimeout 限制
+	adminReadHeaderTimeout = 5 * time.Second
+	adminReadTimeout       = 10 * time.Second
+	adminWriteTimeout      = 30 * time.Second
+	adminIdleTimeout       = time.Minute
+	// 关闭管理接口时等待请求结束的最长时间，超时后强制断开
+	adminShutdownTimeout = 5 * time.Second
 )
 
 var (
@@ -39,8 +48,17 @@ type Config struct {
 	APPName string
 	RunMode string
 
+	// requestID 生成器，为空时使用 logit 默认的 uint32
+	RequestID string
+
 	Env env.AppEnv
 
+	// 调试模式下启动时执行 conf/migrations 下的 mysql 迁移
+	AutoMigrate bool
+
+	// 将 slog 的默认 handler 设置为 service 日志，标准库 log 包的输出也会转到 service 日志
+	SlogDefault bool
+
 	// conf of http service
 	HTTPServer struct {
 		Listen       string
@@ -48,6 +66,11 @@ type Config struct {
 		WriteTimeout int // ms
 		IdleTimeout  int // ms
 	}
+
+	// 管理接口的 http service，如 /debug/logs，Listen 为空时不启动
+	AdminServer struct {
+		Listen string
+	}
 }
 
 // ParserAppConfig
@@ -71,6 +94,9 @@ func ParserAppConfig(filePath string) (*Config, error) {
 		ConfDir: filepath.Join(rootDir, filepath.Base(filepath.Dir(confPath))),
 	}
 	c.Env = env.New(opt)
+	if err := logit.SetRequestIDGenerator(c.RequestID); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
@@ -79,11 +105,14 @@ type App struct {
 	ctx    context.Context
 	config *Config
 	server *http.Server
+	admin  *http.Server
 	close  func()
 }
 
 // NewApp establish an APP
-func NewApp(ctx context.Context, c *Config, handler *gin.Engine) *App {
+//
+//	admin 为管理接口的 handler，未配置 AdminServer 时忽略
+func NewApp(ctx context.Context, c *Config, handler *gin.Engine, admin *gin.Engine) *App {
 	ctxRet, cancel := context.WithCancel(ctx)
 	app := &App{
 		ctx:    ctxRet,
@@ -91,6 +120,7 @@ func NewApp(ctx context.Context, c *Config, handler *gin.Engine) *App {
 		close:  cancel,
 	}
 	app.initHTTPServer(handler)
+	app.initAdminServer(admin)
 	return app
 }
 
@@ -105,8 +135,50 @@ func (app *App) initHTTPServer(handler *gin.Engine) {
 	app.server = ser
 }
 
+// initAdminServer 管理接口使用单独的端口，不经过业务路由的跨域等中间件
+func (app *App) initAdminServer(handler *gin.Engine) {
+	if handler == nil || app.config.AdminServer.Listen == "" {
+		return
+	}
+	app.admin = &http.Server{
+		Addr:              app.config.AdminServer.Listen,
+		Handler:           handler,
+		ReadHeaderTimeout: adminReadHeaderTimeout,
+		ReadTimeout:       adminReadTimeout,
+		WriteTimeout:      adminWriteTimeout,
+		IdleTimeout:       adminIdleTimeout,
+	}
+}
+
+// startAdmin 后台启动管理接口，退出时只打印错误，不影响业务端口
+func (app *App) startAdmin() {
+	if app.admin == nil {
+		return
+	}
+	fmt.Fprintf(DefaultWriter, "[APP START] Listening and serving admin HTTP on %s\n", app.config.AdminServer.Listen)
+	go func() {
+		if err := app.admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(DefaultWriter, "[APP ERROR] admin server exit: %v\n", err)
+		}
+	}()
+}
+
+// stopAdmin 业务端口退出时关闭管理接口，SSE 等长连接在超时后强制断开
+func (app *App) stopAdmin() {
+	if app.admin == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+	defer cancel()
+	if err := app.admin.Shutdown(ctx); err != nil {
+		app.admin.Close()
+	}
+}
+
 // Start start the service
 func (app *App) Start() error {
+	app.startAdmin()
+	defer app.stopAdmin()
 	// start listening to port
 	fmt.Fprintf(DefaultWriter, "[APP START] Listening and serving HTTP on %s\n", app.config.HTTPServer.Listen)
 	// start distribute routers
@@ -115,6 +187,8 @@ func (app *App) Start() error {
 
 // Start start the https service
 func (app *App) StartTLS() error {
+	app.startAdmin()
+	defer app.stopAdmin()
 	// start listening to port
 	fmt.Fprintf(DefaultWriter, "[APP START] Listening and serving HTTPS on %s\n", app.config.HTTPServer.Listen)
 	// start distribute routers
@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-03 16:04:06
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-30 16:42:00
+ * @LastEditTime: 2026-10-22 14:20:36
  * @Description: 读取配置文件, 初始化路由
  */
 package bootstrap
@@ -26,6 +26,9 @@ type AppServer struct {
 	Ctx     context.Context
 	Config  *Config
 	Cancel  context.CancelFunc
+
+	// 管理接口，监听 AdminServer.Listen
+	AdminHandler *gin.Engine
 }
 
 // Setup 准备.
@@ -41,7 +44,16 @@ func Setup() (*AppServer, error) {
 	env.Default = appServer.Config.Env
 	appServer.Ctx, appServer.Cancel = context.WithCancel(context.Background())
 	InitMust(appServer.Ctx)
+	if appServer.Config.SlogDefault {
+		InitSlog(appServer.Ctx)
+	}
+	if appServer.Config.AutoMigrate && env.RunMode() == env.RunModeDebug {
+		if err := InitMigrate(appServer.Ctx); err != nil {
+			return nil, err
+		}
+	}
 	appServer.Handler = InitHandler(appServer)
+	appServer.AdminHandler = InitAdminHandler(appServer)
 
 	return appServer, nil
 }
@@ -63,19 +75,22 @@ func SetupScript(conf ...string) (*AppServer, error) {
 	env.Default = appServer.Config.Env
 	appServer.Ctx, appServer.Cancel = context.WithCancel(context.Background())
 	InitMust(appServer.Ctx)
+	if appServer.Config.SlogDefault {
+		InitSlog(appServer.Ctx)
+	}
 	return appServer, nil
 }
 
 // Start 启动http服务器.
 func (appServer *AppServer) Start() {
 	defer appServer.Cancel()
-	app := NewApp(appServer.Ctx, appServer.Config, appServer.Handler)
+	app := NewApp(appServer.Ctx, appServer.Config, appServer.Handler, appServer.AdminHandler)
 	log.Fatalln("server exit:", app.Start())
 }
 
 // Start 启动https服务器.
 func (appServer *AppServer) StartTLS() {
 	defer appServer.Cancel()
-	app := NewApp(appServer.Ctx, appServer.Config, appServer.Handler)
+	app := NewApp(appServer.Ctx, appServer.Config, appServer.Handler, appServer.AdminHandler)
 	log.Fatalln("server exit:", app.StartTLS())
 }
@@ -2,16 +2,18 @@
  * @Author: liziwei01
  * @Date: 2022-03-04 22:06:10
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 22:38:55
+ * @LastEditTime: 2026-10-22 14:31:09
  * @Description: file content
  */
 package bootstrap
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/liziwei01/gin-lib/library/logit"
 	"github.com/liziwei01/gin-lib/library/request"
+	"github.com/liziwei01/gin-lib/library/trace"
 	"github.com/liziwei01/gin-lib/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +21,7 @@ import (
 
 func InitMust(ctx context.Context) {
 	InitLog(ctx)
+	InitTrace(ctx)
 	InitMiddleware(ctx)
 }
 
@@ -26,6 +29,22 @@ func InitLog(ctx context.Context) {
 	logit.SetServiceLogger(ctx)
 }
 
+// InitSlog 第三方库通过 log/slog 打印的日志，统一输出到 service 日志
+//
+//	slog.SetDefault 同时会把标准库 log 包的输出转到 service 日志
+func InitSlog(ctx context.Context) {
+	if logit.SvrLogger == nil {
+		return
+	}
+	slog.SetDefault(slog.New(logit.NewSlogHandler(logit.SvrLogger, nil)))
+}
+
+func InitTrace(ctx context.Context) {
+	if err := trace.Init(ctx); err != nil {
+		logit.Svr().Warning(ctx, "init trace failed", logit.Error("err", err))
+	}
+}
+
 func InitMiddleware(ctx context.Context) {
 	middleware.Init(ctx)
 }
@@ -39,8 +58,17 @@ func InitHandler(app *AppServer) *gin.Engine {
 	ginRecovery := gin.Recovery()
 	idGenerator := request.RequestIDMiddleware()
 	libLogger := middleware.LogitMiddleware()
+	tracer := middleware.TraceMiddleware()
 	ginLogger := gin.Logger()
 	handler.Use(ginRecovery, idGenerator)
-	handler.Use(libLogger, ginLogger)
+	handler.Use(libLogger, tracer, ginLogger)
+	return handler
+}
+
+// InitAdminHandler 管理接口的 handler，只在 AdminServer 的端口上提供服务
+func InitAdminHandler(app *AppServer) *gin.Engine {
+	handler := gin.New()
+	handler.ContextWithFallback = true
+	handler.Use(gin.Recovery(), request.RequestIDMiddleware())
 	return handler
 }
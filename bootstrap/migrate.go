@@ -0,0 +1,131 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 11:26:50
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 11:26:50
+ * @Description: mysql 迁移的命令行与启动时自动迁移
+ */
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/liziwei01/gin-lib/library/mysql/migrate"
+)
+
+// Migrate 迁移子命令，./gin-lib migrate [-servicer db_lib] [-dry-run] [-steps 1] up|down|status
+//
+//	未指定 servicer 时处理 conf/migrations 下的所有 servicer
+func Migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	conf := fs.String("conf", appConfPath, "app conf file")
+	servicer := fs.String("servicer", "", "mysql servicer, default all servicers in conf/migrations")
+	dryRun := fs.Bool("dry-run", false, "print sql without executing")
+	steps := fs.Int("steps", 1, "number of versions to roll back for down")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: migrate [flags] up|down|status")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cmd := fs.Arg(0)
+	if fs.NArg() != 1 || (cmd != "up" && cmd != "down" && cmd != "status") {
+		fs.Usage()
+		return errors.New("migrate: expect one of up, down, status")
+	}
+
+	appServer, err := SetupScript(*conf)
+	if err != nil {
+		return err
+	}
+	defer appServer.Cancel()
+	servicers := []string{*servicer}
+	if *servicer == "" {
+		if servicers, err = migrate.Servicers(); err != nil {
+			return err
+		}
+	}
+	var opts []migrate.Option
+	if *dryRun {
+		opts = append(opts, migrate.WithDryRun(os.Stdout))
+	}
+	for _, name := range servicers {
+		m, err := migrate.NewServicer(appServer.Ctx, name, opts...)
+		if err != nil {
+			return err
+		}
+		switch cmd {
+		case "up":
+			done, err := m.Up(appServer.Ctx)
+			if !*dryRun {
+				printMigrations(name, "up", done)
+			}
+			if err != nil {
+				return err
+			}
+		case "down":
+			done, err := m.Down(appServer.Ctx, *steps)
+			if !*dryRun {
+				printMigrations(name, "down", done)
+			}
+			if err != nil {
+				return err
+			}
+		case "status":
+			status, err := m.Status(appServer.Ctx)
+			if err != nil {
+				return err
+			}
+			printStatus(name, status)
+		}
+	}
+	return nil
+}
+
+// InitMigrate 调试模式下启动时执行 conf/migrations 下所有 servicer 的 up
+func InitMigrate(ctx context.Context) error {
+	servicers, err := migrate.Servicers()
+	if err != nil {
+		return err
+	}
+	for _, name := range servicers {
+		m, err := migrate.NewServicer(ctx, name)
+		if err != nil {
+			return err
+		}
+		if _, err := m.Up(ctx); err != nil {
+			return fmt.Errorf("migrate %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func printMigrations(servicer, direction string, done []*migrate.Migration) {
+	for _, m := range done {
+		fmt.Fprintf(DefaultWriter, "[MIGRATE] %s %s\n", servicer, m.File(direction))
+	}
+}
+
+func printStatus(servicer string, status []migrate.Status) {
+	w := tabwriter.NewWriter(DefaultWriter, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "SERVICER\tVERSION\tNAME\tSTATUS\n")
+	for _, s := range status {
+		state := "pending"
+		switch {
+		case s.Dirty:
+			state = "dirty"
+		case s.Missing:
+			state = "applied (file missing)"
+		case s.Applied:
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", servicer, s.Version, s.Name, state)
+	}
+	w.Flush()
+}
@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-03 16:04:46
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 22:05:54
+ * @LastEditTime: 2026-10-22 09:40:18
  * @Description: 路由分发
  */
 
@@ -49,3 +49,14 @@ func InitRouters(handler *gin.Engine) {
 		// panic("safe router panic test")
 	})
 }
+
+// InitAdminRouters 管理接口，监听 conf/app.toml 中 AdminServer 的端口，不对外暴露
+func InitAdminRouters(handler *gin.Engine) {
+	router := handler.Group("/")
+	// 最近日志查询，需在 conf/logit/service.toml 中配置 Ring
+	if ring := logit.RingOf(logit.SvrLogger); ring != nil {
+		debugLogs := router.Group("debug/logs")
+		debugLogs.GET("", gin.WrapH(ring.QueryHandler()))
+		debugLogs.GET("tail", gin.WrapH(ring.TailHandler()))
+	}
+}
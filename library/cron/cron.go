@@ -0,0 +1,525 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 10:20:33
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 13:45:52
+ * @Description: 定时任务调度器，支持 cron 表达式、时区、重叠策略、超时与运行记录
+ */
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/lock"
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// JobFunc 任务，ctx 在超时、调度器强制停止、集群单例任务丢失锁时被取消
+type JobFunc func(ctx context.Context) error
+
+// 运行状态
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
+	StatusPanic   = "panic"
+	StatusSkipped = "skipped"
+
+	// 集群单例任务：其他实例正在运行、运行中丢失了锁
+	StatusLocked   = "locked"
+	StatusLockLost = "lock_lost"
+)
+
+// lockKeyPrefix 集群单例任务的锁名前缀
+const lockKeyPrefix = "cron:"
+
+// FieldCronJob 任务运行时日志中的任务名字段
+const FieldCronJob = "cronJob"
+
+// 等待下一次触发时，最长每隔 maxWait 检查一次，系统时间被修改后也能按新的时间触发
+const maxWait = time.Minute
+
+var (
+	// ErrJobExists 任务名已存在
+	ErrJobExists = errors.New("cron job already exists")
+
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("cron job not found")
+)
+
+// Run 一次运行记录
+type Run struct {
+	Job string
+
+	// 计划触发的时间，手动触发时为触发的时间
+	Scheduled time.Time
+
+	// 实际开始、结束的时间，跳过、未获取到锁的运行为零值
+	Start time.Time
+	End   time.Time
+
+	Status string
+	Err    error
+}
+
+// Duration 运行耗时
+func (r *Run) Duration() time.Duration {
+	if r.Start.IsZero() {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+// Entry 任务的状态
+type Entry struct {
+	Name    string
+	Spec    string
+	Overlap Overlap
+
+	// 上一次、下一次触发的时间，未触发过或不会再触发时为零值
+	Prev time.Time
+	Next time.Time
+
+	// 正在运行的数量、排队的数量
+	Running int
+	Queued  int
+}
+
+// New 创建调度器，需调用 Start 开始调度
+func New(opts ...Option) *Cron {
+	c := &Cron{
+		location:    time.Local,
+		historySize: defaultHistorySize,
+		jobs:        make(map[string]*job),
+	}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+// Cron 定时任务调度器，并发安全
+type Cron struct {
+	location    *time.Location
+	logger      logit.Logger
+	historySize int
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	running bool
+	stopped bool
+
+	// 任务运行的根 ctx，强制停止时取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 调度协程与正在运行的任务
+	loops sync.WaitGroup
+	runs  sync.WaitGroup
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       JobFunc
+
+	overlap Overlap
+	jitter  time.Duration
+	timeout time.Duration
+
+	// 集群单例任务的锁
+	locker  lock.Locker
+	lockTTL time.Duration
+
+	// 停止该任务的调度
+	stop chan struct{}
+
+	mu      sync.Mutex
+	prev    time.Time
+	next    time.Time
+	active  int
+	queued  int
+	history []*Run
+}
+
+// AddJob 添加任务，spec 见 Parse，任务名不可重复
+//
+//	调度器已启动时立即开始调度
+func (c *Cron) AddJob(name, spec string, fn JobFunc, opts ...JobOption) error {
+	s, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	return c.addJob(name, spec, s, fn, opts...)
+}
+
+// AddSchedule 使用自定义的触发规则添加任务
+func (c *Cron) AddSchedule(name string, s Schedule, fn JobFunc, opts ...JobOption) error {
+	spec := "custom"
+	if st, ok := s.(fmt.Stringer); ok {
+		spec = st.String()
+	}
+	return c.addJob(name, spec, s, fn, opts...)
+}
+
+func (c *Cron) addJob(name, spec string, s Schedule, fn JobFunc, opts ...JobOption) error {
+	j := &job{
+		name:     name,
+		spec:     spec,
+		schedule: s,
+		fn:       fn,
+		stop:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(j)
+	}
+	// ttl 为 0 时锁立即过期，redis 等实现会拒绝，每次运行都会失败
+	if j.locker != nil && j.lockTTL <= 0 {
+		return fmt.Errorf("cron: singleton job %s requires a positive lock ttl, got %s", name, j.lockTTL)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, has := c.jobs[name]; has {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+	c.jobs[name] = j
+	if c.running {
+		c.startLoop(j)
+	}
+	return nil
+}
+
+// Remove 移除任务，正在运行的不受影响
+func (c *Cron) Remove(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j, has := c.jobs[name]
+	if !has {
+		return false
+	}
+	delete(c.jobs, name)
+	// 停止时已关闭
+	if !c.stopped {
+		close(j.stop)
+	}
+	return true
+}
+
+// Start 开始调度，重复调用无影响，停止后不可再启动
+func (c *Cron) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running || c.stopped {
+		return
+	}
+	c.running = true
+	for _, j := range c.jobs {
+		c.startLoop(j)
+	}
+}
+
+// Stop 停止调度，并等待正在运行的任务结束
+//
+//	ctx 结束时仍未运行完的任务，其 ctx 将被取消，并返回 ctx.Err()
+func (c *Cron) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.stopped {
+		c.stopped = true
+		c.running = false
+		for _, j := range c.jobs {
+			close(j.stop)
+		}
+	}
+	c.mu.Unlock()
+	c.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		c.runs.Wait()
+		close(done)
+	}()
+	defer c.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run 立即运行一次任务，不影响原有的调度，同样遵循重叠策略
+func (c *Cron) Run(name string) error {
+	// 持有锁触发，避免与 Stop 的等待交错
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j, has := c.jobs[name]
+	if !has {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if c.stopped {
+		return fmt.Errorf("cron stopped")
+	}
+	c.trigger(j, time.Now())
+	return nil
+}
+
+// Entries 全部任务的状态，按任务名排序
+func (c *Cron) Entries() []Entry {
+	c.mu.Lock()
+	jobs := make([]*job, 0, len(c.jobs))
+	for _, j := range c.jobs {
+		jobs = append(jobs, j)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].name < jobs[b].name
+	})
+	entries := make([]Entry, 0, len(jobs))
+	for _, j := range jobs {
+		j.mu.Lock()
+		entries = append(entries, Entry{
+			Name:    j.name,
+			Spec:    j.spec,
+			Overlap: j.overlap,
+			Prev:    j.prev,
+			Next:    j.next,
+			Running: j.active,
+			Queued:  j.queued,
+		})
+		j.mu.Unlock()
+	}
+	return entries
+}
+
+// History 任务最近的运行记录，按时间先后排列
+func (c *Cron) History(name string) []*Run {
+	c.mu.Lock()
+	j, has := c.jobs[name]
+	c.mu.Unlock()
+	if !has {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]*Run(nil), j.history...)
+}
+
+// startLoop 启动任务的调度协程，需持有 c.mu
+func (c *Cron) startLoop(j *job) {
+	c.loops.Add(1)
+	go func() {
+		defer c.loops.Done()
+		c.loop(j)
+	}()
+}
+
+func (c *Cron) loop(j *job) {
+	next := j.schedule.Next(time.Now().In(c.location))
+	for {
+		j.mu.Lock()
+		j.next = next
+		j.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		wait := time.Until(next)
+		if wait > maxWait {
+			wait = maxWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-j.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		if now.Before(next) {
+			continue
+		}
+		j.mu.Lock()
+		j.prev = next
+		j.mu.Unlock()
+		c.trigger(j, next)
+
+		// 跳过已经错过的触发时间，如系统时间被调快
+		next = j.schedule.Next(now.In(c.location))
+	}
+}
+
+// trigger 按重叠策略运行任务
+func (c *Cron) trigger(j *job, scheduled time.Time) {
+	j.mu.Lock()
+	if j.active > 0 {
+		switch j.overlap {
+		case OverlapSkip:
+			j.mu.Unlock()
+			c.finish(j, &Run{Job: j.name, Scheduled: scheduled, Status: StatusSkipped})
+			return
+		case OverlapQueue:
+			j.queued++
+			j.mu.Unlock()
+			return
+		}
+	}
+	j.active++
+	j.mu.Unlock()
+
+	c.runs.Add(1)
+	go func() {
+		defer c.runs.Done()
+		for {
+			c.finish(j, c.execute(j, scheduled))
+
+			j.mu.Lock()
+			if j.overlap == OverlapQueue && j.queued > 0 {
+				j.queued--
+				j.mu.Unlock()
+				scheduled = time.Now()
+				continue
+			}
+			j.active--
+			j.mu.Unlock()
+			return
+		}
+	}()
+}
+
+// execute 运行一次任务，捕获 panic
+func (c *Cron) execute(j *job, scheduled time.Time) (r *Run) {
+	r = &Run{Job: j.name, Scheduled: scheduled}
+
+	if j.jitter > 0 {
+		timer := time.NewTimer(time.Duration(rand.Int63n(int64(j.jitter))))
+		select {
+		case <-timer.C:
+		case <-c.ctx.Done():
+			// 等待期间调度器被强制停止，不再运行
+			timer.Stop()
+			r.Status = StatusSkipped
+			return r
+		}
+	}
+
+	// 每次运行使用独立的日志字段
+	ctx := logit.WithContext(c.ctx)
+	logit.SetRequestID(ctx, logit.NewRequestID())
+	logit.AddMetaFields(ctx, logit.String(FieldCronJob, j.name))
+	if j.locker != nil {
+		var lease *lock.Lease
+		ctx, lease, r.Err = lock.Hold(ctx, j.locker, lockKeyPrefix+j.name, j.lockTTL)
+		if r.Err != nil {
+			r.Status = StatusFailed
+			if errors.Is(r.Err, lock.ErrNotObtained) {
+				r.Status = StatusLocked
+				r.Err = nil
+			}
+			return r
+		}
+		defer c.releaseLock(ctx, j, r, lease)
+	}
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	r.Start = time.Now()
+	defer func() {
+		r.End = time.Now()
+		if p := recover(); p != nil {
+			r.Status = StatusPanic
+			r.Err = fmt.Errorf("panic: %v", p)
+			c.log().Error(ctx, "cron job panic", logit.Reflect("panic", p), logit.Stack())
+			return
+		}
+		switch {
+		case r.Err == nil:
+			r.Status = StatusSuccess
+		case errors.Is(context.Cause(ctx), lock.ErrLockLost):
+			r.Status = StatusLockLost
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			r.Status = StatusTimeout
+		default:
+			r.Status = StatusFailed
+		}
+		c.logRun(ctx, r)
+	}()
+	r.Err = j.fn(ctx)
+	return r
+}
+
+// releaseLock 释放集群单例任务的锁
+//
+//	锁至少持有 lockTTL，运行很快时，时钟略晚的其他实例在同一个触发时间仍获取不到锁
+func (c *Cron) releaseLock(ctx context.Context, j *job, r *Run, lease *lock.Lease) {
+	var err error
+	if hold := j.lockTTL - r.Duration(); hold > 0 {
+		err = lease.ReleaseAfter(context.Background(), hold)
+	} else {
+		err = lease.Release(context.Background())
+	}
+	if err != nil && r.Status != StatusLockLost {
+		c.log().Warning(ctx, "cron job release lock failed", logit.Error("err", err))
+	}
+}
+
+func (c *Cron) logRun(ctx context.Context, r *Run) {
+	fields := []logit.Field{
+		logit.String("status", r.Status),
+		logit.Time("scheduled", r.Scheduled),
+		logit.Duration("cost", r.Duration()),
+	}
+	if r.Err != nil {
+		c.log().Warning(ctx, "cron job failed", append(fields, logit.Error("err", r.Err))...)
+		return
+	}
+	c.log().Notice(ctx, "cron job done", fields...)
+}
+
+// finish 保存运行记录
+func (c *Cron) finish(j *job, r *Run) {
+	observe(r)
+	switch {
+	case r.Status == StatusSkipped && c.ctx.Err() != nil:
+		c.log().Notice(c.ctx, "cron job skipped, scheduler stopped",
+			logit.String(FieldCronJob, j.name), logit.Time("scheduled", r.Scheduled))
+	case r.Status == StatusSkipped:
+		c.log().Notice(c.ctx, "cron job skipped, last run not finished",
+			logit.String(FieldCronJob, j.name), logit.Time("scheduled", r.Scheduled))
+	case r.Status == StatusLocked:
+		c.log().Debug(c.ctx, "cron job skipped, running on another instance",
+			logit.String(FieldCronJob, j.name), logit.Time("scheduled", r.Scheduled))
+	case r.Start.IsZero():
+		c.log().Warning(c.ctx, "cron job acquire lock failed",
+			logit.String(FieldCronJob, j.name), logit.Error("err", r.Err))
+	}
+	if c.historySize <= 0 {
+		return
+	}
+	j.mu.Lock()
+	j.history = append(j.history, r)
+	if len(j.history) > c.historySize {
+		j.history = append(j.history[:0], j.history[len(j.history)-c.historySize:]...)
+	}
+	j.mu.Unlock()
+}
+
+func (c *Cron) log() logit.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return logit.Svr()
+}
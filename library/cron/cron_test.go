@@ -0,0 +1,258 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 10:58:14
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 13:45:52
+ * @Description: 定时任务用例
+ */
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/lock"
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/logit/logittest"
+)
+
+func TestParse(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip(err)
+	}
+	from := time.Date(2026, 10, 20, 10, 30, 15, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 20, 10, 31, 0, 0, time.UTC)},
+		{"*/5 * * * * *", time.Date(2026, 10, 20, 10, 30, 20, 0, time.UTC)},
+		{"15 3 * * *", time.Date(2026, 10, 21, 3, 15, 0, 0, time.UTC)},
+		{"CRON_TZ=Asia/Shanghai 15 3 * * *", time.Date(2026, 10, 21, 3, 15, 0, 0, shanghai)},
+		{"0 9-18/3 * * MON-FRI", time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN ?", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		// 日与周同时指定时满足任意一个
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 20, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"@every 1h30m", from.Add(90 * time.Minute)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, c := range cases {
+		s, err := Parse(c.spec)
+		if err != nil {
+			t.Errorf("%q: %v", c.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(c.want) {
+			t.Errorf("%q: want %v, got %v", c.spec, c.want, got)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every -1s", "@often", "CRON_TZ=Mars/Base * * * * *"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q should be invalid", bad)
+		}
+	}
+}
+
+// msSchedule 毫秒级间隔，仅用于测试
+type msSchedule time.Duration
+
+func (s msSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+func TestOverlap(t *testing.T) {
+	logittest.ReplaceSvrLogger(t)
+	c := New()
+	var skip, queue, allow int32
+	slow := func(n *int32) JobFunc {
+		return func(ctx context.Context) error {
+			atomic.AddInt32(n, 1)
+			time.Sleep(120 * time.Millisecond)
+			return nil
+		}
+	}
+	for name, o := range map[string]Overlap{"skip": OverlapSkip, "queue": OverlapQueue, "allow": OverlapAllow} {
+		n := map[string]*int32{"skip": &skip, "queue": &queue, "allow": &allow}[name]
+		if err := c.AddSchedule(name, msSchedule(50*time.Millisecond), slow(n), WithOverlap(o)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.AddJob("skip", "@daily", slow(&skip)); !errors.Is(err, ErrJobExists) {
+		t.Errorf("duplicate job should fail, got %v", err)
+	}
+	c.Start()
+	time.Sleep(320 * time.Millisecond)
+	for _, name := range []string{"skip", "queue", "allow"} {
+		c.Remove(name)
+	}
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 约6次触发：skip 只能跑到约一半，queue 全部串行跑完，allow 全部并行
+	if skip > 4 || queue < 4 || allow < 5 || queue > allow {
+		t.Errorf("unexpected runs skip=%d queue=%d allow=%d", skip, queue, allow)
+	}
+}
+
+func TestRunHistory(t *testing.T) {
+	o := logittest.ReplaceSvrLogger(t)
+	c := New(WithHistorySize(2))
+	c.AddJob("panic", "@daily", func(ctx context.Context) error { panic("boom") })
+	c.AddJob("timeout", "@daily", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(20*time.Millisecond))
+	c.AddJob("ok", "@daily", func(ctx context.Context) error {
+		if logit.FindMetaField(ctx, FieldCronJob) == nil {
+			return errors.New("missing job field")
+		}
+		return nil
+	}, WithJitter(10*time.Millisecond))
+	c.Start()
+	for _, name := range []string{"panic", "timeout", "ok", "ok", "ok"} {
+		if err := c.Run(name); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+	if err := c.Run("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("want ErrJobNotFound, got %v", err)
+	}
+	if err := c.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, status := range map[string]string{"panic": StatusPanic, "timeout": StatusTimeout, "ok": StatusSuccess} {
+		h := c.History(name)
+		if len(h) == 0 || h[len(h)-1].Status != status {
+			t.Errorf("%s: unexpected history %+v", name, h)
+		}
+	}
+	if len(c.History("ok")) != 2 {
+		t.Errorf("history should be capped at 2")
+	}
+	o.AssertLogged(t, logit.ErrorLevel, "cron job panic", FieldCronJob, "panic", "stack")
+	o.AssertLogged(t, logit.WarningLevel, "cron job failed", FieldCronJob, "err")
+
+	entries := c.Entries()
+	if len(entries) != 3 || entries[0].Name != "ok" || entries[0].Next.IsZero() {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	// 停止超时后任务仍在后台结束，不能使用会被恢复的全局 logger
+	c := New(WithLogger(logittest.NewObserver()))
+	cancelled := make(chan struct{})
+	c.AddJob("slow", "@daily", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	c.Start()
+	c.Run("slow")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := c.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want deadline exceeded, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("job ctx should be cancelled after stop timeout")
+	}
+}
+
+// 等待随机延迟时调度器被强制停止，任务不再运行
+func TestJitterStop(t *testing.T) {
+	c := New(WithLogger(logittest.NewObserver()))
+	var runs int32
+	c.AddJob("jitter", "@daily", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, WithJitter(time.Hour))
+	c.Start()
+	c.Run("jitter")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	c.Stop(ctx)
+	time.Sleep(20 * time.Millisecond)
+	if h := c.History("jitter"); atomic.LoadInt32(&runs) != 0 || len(h) != 1 || h[0].Status != StatusSkipped {
+		t.Errorf("want skipped without running, ran %d times, history %+v", atomic.LoadInt32(&runs), h)
+	}
+}
+
+func TestSingleton(t *testing.T) {
+	logittest.ReplaceSvrLogger(t)
+	locker := lock.NewMemoryLocker()
+	if err := New().AddJob("no_ttl", "@daily", func(ctx context.Context) error { return nil }, WithSingleton(locker, 0)); err == nil {
+		t.Error("singleton job without ttl should fail")
+	}
+
+	// 两个实例同时触发，只有一个运行
+	var runs int32
+	replicas := []*Cron{New(), New()}
+	for _, c := range replicas {
+		c.AddJob("report", "@daily", func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			if lock.FromContext(ctx) == nil {
+				return errors.New("missing lock")
+			}
+			return nil
+		}, WithSingleton(locker, 50*time.Millisecond))
+		c.Start()
+		c.Run("report")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("singleton job ran %d times", n)
+	}
+	statuses := map[string]int{}
+	for _, c := range replicas {
+		for _, r := range c.History("report") {
+			statuses[r.Status]++
+		}
+	}
+	if statuses[StatusSuccess] != 1 || statuses[StatusLocked] != 1 {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	// 运行很快时锁至少持有 ttl，之后可再次运行
+	if !locker.Held(lockKeyPrefix + "report") {
+		t.Error("lock should be held for ttl after a short run")
+	}
+	time.Sleep(60 * time.Millisecond)
+	replicas[1].Run("report")
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("job should run again after ttl, ran %d times", n)
+	}
+
+	// 运行中丢失锁，ctx 被取消
+	c := replicas[0]
+	c.AddJob("lost", "@daily", func(ctx context.Context) error {
+		locker.Revoke(lockKeyPrefix + "lost")
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithSingleton(locker, 30*time.Millisecond))
+	c.Run("lost")
+	for _, c := range replicas {
+		if err := c.Stop(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if h := c.History("lost"); len(h) != 1 || h[0].Status != StatusLockLost {
+		t.Errorf("unexpected history %+v", h)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 10:06:51
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 13:02:41
+ * @Description: 定时任务的 Prometheus metrics
+ */
+package cron
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	jobRuns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cron_job_runs_total",
+			Help: "Number of cron job runs by status.",
+		},
+		[]string{"job", "status"},
+	)
+	jobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cron_job_duration_seconds",
+			Help:    "Duration of cron job runs.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+		},
+		[]string{"job", "status"},
+	)
+	jobLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cron_job_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful cron job run.",
+		},
+		[]string{"job"},
+	)
+	jobLastDuration = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cron_job_last_duration_seconds",
+			Help: "Duration of the last cron job run.",
+		},
+		[]string{"job"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(jobRuns, jobDuration, jobLastSuccess, jobLastDuration)
+}
+
+// observe 记录一次运行，未开始的运行只计数
+func observe(r *Run) {
+	jobRuns.WithLabelValues(r.Job, r.Status).Inc()
+	if r.Start.IsZero() {
+		return
+	}
+	d := r.Duration().Seconds()
+	jobDuration.WithLabelValues(r.Job, r.Status).Observe(d)
+	jobLastDuration.WithLabelValues(r.Job).Set(d)
+	if r.Status == StatusSuccess {
+		jobLastSuccess.WithLabelValues(r.Job).Set(float64(r.End.UnixNano()) / float64(time.Second))
+	}
+}
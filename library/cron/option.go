@@ -0,0 +1,137 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 09:58:02
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 13:45:52
+ * @Description: 调度器与任务的配置选项
+ */
+package cron
+
+import (
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/lock"
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// Overlap 上一次运行还未结束时，新的触发如何处理
+type Overlap int
+
+const (
+	// OverlapSkip 跳过本次触发，默认值
+	OverlapSkip Overlap = iota
+
+	// OverlapQueue 排队，上一次结束后立即运行，同一任务始终串行
+	OverlapQueue
+
+	// OverlapAllow 允许并行运行
+	OverlapAllow
+)
+
+// String 名称
+func (o Overlap) String() string {
+	switch o {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapAllow:
+		return "allow"
+	}
+	return "unknown"
+}
+
+// 默认保留的运行记录条数
+const defaultHistorySize = 20
+
+// Option 调度器的配置选项
+type Option interface {
+	apply(*Cron)
+}
+
+type funcOption struct {
+	f func(*Cron)
+}
+
+func (fo *funcOption) apply(c *Cron) {
+	fo.f(c)
+}
+
+func newFuncOption(f func(*Cron)) *funcOption {
+	return &funcOption{
+		f: f,
+	}
+}
+
+// WithLocation 表达式未指定 CRON_TZ 时使用的时区，默认为 time.Local
+func WithLocation(loc *time.Location) Option {
+	return newFuncOption(func(c *Cron) {
+		c.location = loc
+	})
+}
+
+// WithLogger 任务运行日志的 logger，默认使用 logit.SvrLogger
+func WithLogger(logger logit.Logger) Option {
+	return newFuncOption(func(c *Cron) {
+		c.logger = logger
+	})
+}
+
+// WithHistorySize 每个任务保留的运行记录条数，默认20
+func WithHistorySize(n int) Option {
+	return newFuncOption(func(c *Cron) {
+		c.historySize = n
+	})
+}
+
+// JobOption 任务的配置选项
+type JobOption interface {
+	apply(*job)
+}
+
+type jobFuncOption struct {
+	f func(*job)
+}
+
+func (fo *jobFuncOption) apply(j *job) {
+	fo.f(j)
+}
+
+func newJobFuncOption(f func(*job)) *jobFuncOption {
+	return &jobFuncOption{
+		f: f,
+	}
+}
+
+// WithOverlap 上一次运行还未结束时的处理策略，默认 OverlapSkip
+func WithOverlap(o Overlap) JobOption {
+	return newJobFuncOption(func(j *job) {
+		j.overlap = o
+	})
+}
+
+// WithJitter 每次触发后随机延迟 [0, d) 再运行，避免多个任务或多个实例同时运行
+func WithJitter(d time.Duration) JobOption {
+	return newJobFuncOption(func(j *job) {
+		j.jitter = d
+	})
+}
+
+// WithTimeout 单次运行的超时时间，超时后任务的 ctx 被取消，默认不超时
+func WithTimeout(d time.Duration) JobOption {
+	return newJobFuncOption(func(j *job) {
+		j.timeout = d
+	})
+}
+
+// WithSingleton 集群单例任务，多个实例中同一时间只有获取到锁的一个运行
+//
+//	锁名为 "cron:" + 任务名，运行期间每隔 ttl/3 续期，丢失锁时任务的 ctx 被取消，
+//	可通过 lock.FromContext(ctx).Token() 获取 fencing token。
+//	锁至少持有 ttl，ttl 应大于实例间的时钟偏差、小于触发间隔，不大于 0 时添加任务报错
+func WithSingleton(locker lock.Locker, ttl time.Duration) JobOption {
+	return newJobFuncOption(func(j *job) {
+		j.locker = locker
+		j.lockTTL = ttl
+	})
+}
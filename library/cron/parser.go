@@ -0,0 +1,251 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 09:12:40
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 09:12:40
+ * @Description: cron 表达式解析
+ */
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse 解析 cron 表达式
+//
+//	5个字段：分 时 日 月 周，如 "15 3 * * *" 为每天 03:15
+//	6个字段：秒 分 时 日 月 周，如 "0 */5 * * * *" 为每5分钟
+//	字段支持 * ? , - / 以及月份、星期的英文缩写，星期的 0 与 7 均为周日
+//	日与周同时指定时，满足任意一个即可，与 crontab 一致
+//
+//	描述符：@yearly(@annually) @monthly @weekly @daily(@midnight) @hourly @every <duration>
+//	如 "@every 1h30m"，@every 从上一次的触发时间开始计算，不与整点对齐
+//
+//	时区：以 CRON_TZ= 或 TZ= 开头指定，如 "CRON_TZ=Asia/Shanghai 15 3 * * *"
+//	不指定时使用调度器的时区，见 WithLocation
+func Parse(spec string) (Schedule, error) {
+	raw := spec
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("empty cron spec")
+	}
+
+	var loc *time.Location
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.IndexByte(spec, ' ')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid cron spec %q: missing fields after time zone", raw)
+		}
+		name := spec[strings.IndexByte(spec, '=')+1 : i]
+		var err error
+		if loc, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %w", raw, err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		s, err := parseDescriptor(spec, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %w", raw, err)
+		}
+		return s, nil
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("invalid cron spec %q: expected 5 or 6 fields, got %d", raw, len(fields))
+	}
+
+	s := &specSchedule{loc: loc}
+	var err error
+	for i, p := range []struct {
+		dst *uint64
+		b   bounds
+	}{
+		{&s.second, seconds},
+		{&s.minute, minutes},
+		{&s.hour, hours},
+		{&s.dom, dom},
+		{&s.month, months},
+		{&s.dow, dow},
+	} {
+		if *p.dst, err = parseField(fields[i], p.b); err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %s field: %w", raw, p.b.name, err)
+		}
+	}
+	return s, nil
+}
+
+// MustParse 同 Parse，解析失败时 panic
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func parseDescriptor(spec string, loc *time.Location) (Schedule, error) {
+	all := func(b bounds) uint64 {
+		return bitRange(b.min, b.max, 1) | starBit
+	}
+	switch spec {
+	case "@yearly", "@annually":
+		return &specSchedule{second: 1, minute: 1, hour: 1, dom: 1 << 1, month: 1 << 1, dow: all(dow), loc: loc}, nil
+	case "@monthly":
+		return &specSchedule{second: 1, minute: 1, hour: 1, dom: 1 << 1, month: all(months), dow: all(dow), loc: loc}, nil
+	case "@weekly":
+		return &specSchedule{second: 1, minute: 1, hour: 1, dom: all(dom), month: all(months), dow: 1, loc: loc}, nil
+	case "@daily", "@midnight":
+		return &specSchedule{second: 1, minute: 1, hour: 1, dom: all(dom), month: all(months), dow: all(dow), loc: loc}, nil
+	case "@hourly":
+		return &specSchedule{second: 1, minute: 1, hour: all(hours), dom: all(dom), month: all(months), dow: all(dow), loc: loc}, nil
+	}
+
+	const every = "@every "
+	if strings.HasPrefix(spec, every) {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len(every):]))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("@every duration must be positive")
+		}
+		return Every(d), nil
+	}
+	return nil, fmt.Errorf("unknown descriptor %q", spec)
+}
+
+// bounds 字段的取值范围
+type bounds struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{name: "second", min: 0, max: 59}
+	minutes = bounds{name: "minute", min: 0, max: 59}
+	hours   = bounds{name: "hour", min: 0, max: 23}
+	dom     = bounds{name: "day of month", min: 1, max: 31}
+	months  = bounds{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 也表示周日，解析后统一为 0
+	dow = bounds{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// starBit 标记字段为 * 或 ?，用于日与周的匹配规则
+const starBit = 1 << 63
+
+// parseField 解析一个字段，返回取值的位图
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		v, err := parseRange(expr, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= v
+	}
+	// 周日 7 等同于 0
+	if b.max == 7 && bits&(1<<7) != 0 {
+		bits = bits&^(1<<7) | 1
+	}
+	return bits, nil
+}
+
+// parseRange 解析 * ? n n-m 以及带步长的 x/step
+func parseRange(expr string, b bounds) (uint64, error) {
+	var (
+		start, end, step uint = 0, 0, 1
+		extra            uint64
+		err              error
+	)
+	rangeAndStep := strings.Split(expr, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, fmt.Errorf("invalid expression %q", expr)
+	}
+	lowAndHigh := strings.Split(rangeAndStep[0], "-")
+
+	switch {
+	case lowAndHigh[0] == "*" || lowAndHigh[0] == "?":
+		if len(lowAndHigh) > 1 {
+			return 0, fmt.Errorf("invalid expression %q", expr)
+		}
+		start, end = b.min, b.max
+		// 周的 * 不包含 7，避免与 0 重复
+		if b.max == 7 {
+			end = 6
+		}
+		extra = starBit
+	default:
+		if start, err = parseValue(lowAndHigh[0], b); err != nil {
+			return 0, err
+		}
+		switch len(lowAndHigh) {
+		case 1:
+			end = start
+		case 2:
+			if end, err = parseValue(lowAndHigh[1], b); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("invalid expression %q", expr)
+		}
+	}
+
+	if len(rangeAndStep) == 2 {
+		n, err := strconv.ParseUint(rangeAndStep[1], 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid step in %q", expr)
+		}
+		step = uint(n)
+		// 形如 5/15，表示从5开始到最大值
+		if len(lowAndHigh) == 1 && extra == 0 {
+			end = b.max
+		}
+		// 带步长的 * 不再视为 *
+		if step > 1 {
+			extra = 0
+		}
+	}
+
+	if start < b.min || end > b.max || start > end {
+		return 0, fmt.Errorf("%q out of range [%d, %d]", expr, b.min, b.max)
+	}
+	return bitRange(start, end, step) | extra, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if b.names != nil {
+		if v, ok := b.names[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return uint(n), nil
+}
+
+// bitRange [min, max] 中按步长取值的位图
+func bitRange(min, max, step uint) uint64 {
+	var bits uint64
+	for i := min; i <= max; i += step {
+		bits |= 1 << i
+	}
+	return bits
+}
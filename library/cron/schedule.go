@@ -0,0 +1,143 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 09:35:18
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 09:35:18
+ * @Description: 计算任务的下一次触发时间
+ */
+package cron
+
+import (
+	"time"
+)
+
+// Schedule 任务的触发规则
+type Schedule interface {
+	// Next 返回晚于 t 的下一次触发时间，不会再触发时返回零值
+	Next(t time.Time) time.Time
+}
+
+// Every 固定间隔触发，间隔不足1秒时按1秒
+func Every(d time.Duration) Schedule {
+	if d < time.Second {
+		d = time.Second
+	}
+	return everySchedule(d)
+}
+
+type everySchedule time.Duration
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+func (s everySchedule) String() string {
+	return "@every " + time.Duration(s).String()
+}
+
+// specSchedule 由 cron 表达式解析得到，每个字段是取值的位图
+type specSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// 为空时使用传入时间的时区
+	loc *time.Location
+}
+
+// 最多向后查找的年数，如 2月30日 这样永远不会触发的表达式
+const maxSearchYears = 5
+
+func (s *specSchedule) Next(t time.Time) time.Time {
+	origLoc := t.Location()
+	loc := s.loc
+	if loc == nil {
+		loc = origLoc
+	}
+	t = t.In(loc)
+
+	// 从下一秒开始
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+
+	// 某个字段不匹配时，更低的字段从最小值开始
+	added := false
+	yearLimit := t.Year() + maxSearchYears
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// 夏令时切换的当天可能不存在 00:00，调整回当天的开始
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLoc)
+}
+
+// dayMatches 日与周都指定时满足任意一个即可，否则都需满足（* 总是满足）
+func (s *specSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow > 0
+	if s.dom&starBit > 0 || s.dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
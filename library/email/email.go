@@ -13,7 +13,12 @@ import (
 	"gopkg.in/gomail.v2"
 )
 
-func (c *client) Send(ctx context.Context, to, subject, body string) error {
+func (c *client) Send(ctx context.Context, to, subject, body string) (err error) {
+	ctx, span := c.startSpan(ctx)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 	dialer, err := c.connect(ctx)
 	if err != nil {
 		return err
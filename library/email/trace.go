@@ -0,0 +1,28 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 20:18:02
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 20:18:02
+ * @Description: 邮件发送的链路追踪
+ */
+package email
+
+import (
+	"context"
+
+	"github.com/liziwei01/gin-lib/library/trace"
+)
+
+// startSpan 为一次邮件发送创建 client span
+//
+//	收件人属于敏感信息，不记录
+func (c *client) startSpan(ctx context.Context) (context.Context, *trace.Span) {
+	return trace.Start(ctx, "smtp send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			trace.String("messaging.system", "smtp"),
+			trace.String("net.peer.name", c.conf.Resource.Manual.Host),
+			trace.Int("net.peer.port", c.conf.Resource.Manual.Port),
+		),
+	)
+}
@@ -0,0 +1,315 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 23:02:35
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 10:31:09
+ * @Description: 携带调用栈、错误码与上下文信息的错误，使用 logit.Error 打印时会展开
+ */
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"runtime"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/response"
+)
+
+// 调用栈最大深度
+const maxStackDepth = 32
+
+// Error 携带调用栈、错误码与上下文信息的错误
+//
+//	错误信息与错误链由内部的 err 决定，Error 只是附加信息的包装
+type Error struct {
+	err error
+
+	code    int
+	hasCode bool
+
+	// 上下文信息，key、value 交替
+	kvs []interface{}
+
+	stack []uintptr
+}
+
+// New 创建错误并记录调用栈
+func New(message string) error {
+	return &Error{
+		err:   stderrors.New(message),
+		stack: callers(),
+	}
+}
+
+// Errorf 同 fmt.Errorf，支持 %w，并记录调用栈
+func Errorf(format string, args ...interface{}) error {
+	return &Error{
+		err:   fmt.Errorf(format, args...),
+		stack: callers(),
+	}
+}
+
+// NewCode 创建带错误码的错误，message 为空时使用 response.CodeMsgMap 中的信息
+func NewCode(code int, message string) error {
+	if message == "" {
+		message = codeMsg(code)
+	}
+	return &Error{
+		err:     stderrors.New(message),
+		code:    code,
+		hasCode: true,
+		stack:   callers(),
+	}
+}
+
+// Wrap 为 err 添加说明，形如 message: err，err 为 nil 时返回 nil
+//
+//	err 的错误链中已有调用栈时不再记录
+func Wrap(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		err:   fmt.Errorf("%s: %w", message, err),
+		stack: callersIfAbsent(err),
+	}
+}
+
+// Wrapf 同 Wrap，说明支持格式化
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		err:   fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), err),
+		stack: callersIfAbsent(err),
+	}
+}
+
+// WithCode 为 err 设置错误码，错误信息不变，err 为 nil 时返回 nil
+func WithCode(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		err:     err,
+		code:    code,
+		hasCode: true,
+		stack:   callersIfAbsent(err),
+	}
+}
+
+// With 为 err 附加上下文信息，key、value 交替传入，错误信息不变，err 为 nil 时返回 nil
+//
+//	如 errors.With(err, "uid", uid, "orderID", orderID)
+func With(err error, kvs ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{
+		err:   err,
+		kvs:   kvs,
+		stack: callersIfAbsent(err),
+	}
+}
+
+// Error 错误信息
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回内部的错误
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// ErrorCode 错误链中最外层的错误码，都没有设置时 ok 为 false
+//
+//	response.StdError 据此返回错误码，没有错误码的错误按 response.Failed 返回
+func (e *Error) ErrorCode() (code int, ok bool) {
+	return findCode(e)
+}
+
+// StackTrace 创建时的调用栈，未记录时为空
+func (e *Error) StackTrace() []uintptr {
+	return e.stack
+}
+
+// Format 支持 %+v 输出错误信息与调用栈
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprint(s, e.Error())
+			frames := runtime.CallersFrames(e.stack)
+			for {
+				frame, more := frames.Next()
+				if frame.Function != "" {
+					fmt.Fprintf(s, "\n%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+				}
+				if !more {
+					break
+				}
+			}
+			return
+		}
+		fmt.Fprint(s, e.Error())
+	case 's':
+		fmt.Fprint(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// MarshalLogObject 使用 logit.Error 打印时，输出错误码与整条错误链的上下文信息
+//
+//	外层的上下文信息覆盖内层相同 key 的信息
+func (e *Error) MarshalLogObject(enc logit.FieldEncoder) error {
+	if code, ok := findCode(e); ok {
+		enc.AddInt("code", code)
+	}
+	kvs := Values(e)
+	for _, k := range valueKeys(e) {
+		logit.AutoField(k, kvs[k]).AddTo(enc)
+	}
+	return nil
+}
+
+// Code 错误链中最外层的错误码
+//
+//	err 为 nil 时返回 response.Success，没有错误码时返回 response.Unknown
+func Code(err error) int {
+	if err == nil {
+		return response.Success
+	}
+	if code, ok := findCode(err); ok {
+		return code
+	}
+	return response.Unknown
+}
+
+// Message 错误码对应的信息，见 response.CodeMsgMap
+func Message(err error) string {
+	return codeMsg(Code(err))
+}
+
+// Values 错误链中全部的上下文信息，外层覆盖内层相同 key 的信息
+func Values(err error) map[string]interface{} {
+	res := make(map[string]interface{})
+	chain := errorsInChain(err)
+	for i := len(chain) - 1; i >= 0; i-- {
+		kvs := chain[i].kvs
+		for j := 0; j < len(kvs); j += 2 {
+			res[kvKey(kvs[j])] = kvValue(kvs, j)
+		}
+	}
+	return res
+}
+
+// valueKeys 上下文信息的 key，按首次附加的顺序（由内到外）去重
+func valueKeys(err error) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	chain := errorsInChain(err)
+	for i := len(chain) - 1; i >= 0; i-- {
+		kvs := chain[i].kvs
+		for j := 0; j < len(kvs); j += 2 {
+			k := kvKey(kvs[j])
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// errorsInChain 错误链中所有的 *Error，由外到内
+func errorsInChain(err error) []*Error {
+	var res []*Error
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			res = append(res, e)
+		}
+		err = stderrors.Unwrap(err)
+	}
+	return res
+}
+
+func findCode(err error) (int, bool) {
+	for _, e := range errorsInChain(err) {
+		if e.hasCode {
+			return e.code, true
+		}
+	}
+	return 0, false
+}
+
+func codeMsg(code int) string {
+	if msg, ok := response.CodeMsgMap[code]; ok {
+		return msg
+	}
+	return response.MsgUnknown
+}
+
+func kvKey(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprint(k)
+}
+
+func kvValue(kvs []interface{}, i int) interface{} {
+	if i+1 < len(kvs) {
+		return kvs[i+1]
+	}
+	// 落单的 key
+	return "<missing>"
+}
+
+// callers 记录调用栈，跳过 runtime.Callers、callers 以及创建错误的函数
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// callersIfAbsent 错误链中没有调用栈时才记录，跳过的层数同 callers
+func callersIfAbsent(err error) []uintptr {
+	for e := err; e != nil; e = stderrors.Unwrap(e) {
+		if st, ok := e.(interface{ StackTrace() []uintptr }); ok && len(st.StackTrace()) > 0 {
+			return nil
+		}
+	}
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// 以下同标准库 errors，便于直接替换导入
+
+// Is 同 errors.Is
+func Is(err, target error) bool {
+	return stderrors.Is(err, target)
+}
+
+// As 同 errors.As
+func As(err error, target interface{}) bool {
+	return stderrors.As(err, target)
+}
+
+// Unwrap 同 errors.Unwrap
+func Unwrap(err error) error {
+	return stderrors.Unwrap(err)
+}
+
+// Join 同 errors.Join
+func Join(errs ...error) error {
+	return stderrors.Join(errs...)
+}
+
+var (
+	_ logit.ObjectMarshaler = (*Error)(nil)
+	_ fmt.Formatter         = (*Error)(nil)
+)
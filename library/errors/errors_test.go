@@ -0,0 +1,90 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 23:42:50
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 10:31:09
+ * @Description: errors 用例
+ */
+package errors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/response"
+)
+
+func findUser(uid int) error {
+	return With(Wrap(io.ErrUnexpectedEOF, "read row"), "uid", uid)
+}
+
+func TestWrap(t *testing.T) {
+	err := WithCode(Wrapf(findUser(7), "find user %d", 7), response.InvalidParams)
+	err = With(err, "uid", 8, "op", "login")
+
+	if err.Error() != "find user 7: read row: unexpected EOF" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if !Is(err, io.ErrUnexpectedEOF) {
+		t.Error("should match the cause")
+	}
+	if Code(err) != response.InvalidParams || Message(err) != response.MsgInvalidParams {
+		t.Errorf("unexpected code %d %q", Code(err), Message(err))
+	}
+	if v := Values(err); v["uid"] != 8 || v["op"] != "login" {
+		t.Errorf("outer value should win: %v", v)
+	}
+
+	// 调用栈只在最内层记录一次
+	var stacks []string
+	for _, e := range errorsInChain(err) {
+		if len(e.StackTrace()) > 0 {
+			stacks = append(stacks, fmt.Sprintf("%+v", e))
+		}
+	}
+	if len(stacks) != 1 || !strings.Contains(stacks[0], "errors.findUser") {
+		t.Errorf("stack should be recorded once in findUser: %q", stacks)
+	}
+
+	if Wrap(nil, "x") != nil || WithCode(nil, 1) != nil || With(nil, "k", 1) != nil {
+		t.Error("nil error should stay nil")
+	}
+	if Code(nil) != response.Success || Code(io.EOF) != response.Unknown {
+		t.Error("unexpected default code")
+	}
+	// 没有设置错误码时 ErrorCode 不返回 response.Unknown，StdError 按 response.Failed 返回
+	if _, ok := Wrap(io.EOF, "read").(*Error).ErrorCode(); ok {
+		t.Error("uncoded error should report no code")
+	}
+	if code, ok := Wrap(WithCode(io.EOF, response.TokenCheckFailed), "read").(*Error).ErrorCode(); !ok || code != response.TokenCheckFailed {
+		t.Errorf("unexpected code %d %v", code, ok)
+	}
+	if err := NewCode(response.TokenCheckFailed, ""); err.Error() != response.MsgTokenCheckFailed {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestLogitError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := logit.NewSimple(buf)
+	err := With(WithCode(findUser(7), response.Failed), "op", "login")
+	l.Error(context.Background(), "failed", logit.Error("err", err))
+
+	line := buf.String()
+	for _, want := range []string{
+		"err[read row: unexpected EOF]",
+		"errCauses[unexpected EOF]",
+		"errDetail.code[-1] errDetail.uid[7] errDetail.op[login]",
+		"errStack[",
+		"errors_test.go:",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+}
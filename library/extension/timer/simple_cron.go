@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2023-10-31 20:07:29
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 11:34:17
+ * @LastEditTime: 2026-10-20 13:15:52
  * @Description: 模拟crontab
  */
 package timer
@@ -28,6 +28,9 @@ func NewSimpleCron(duration time.Duration) *SimpleCron {
 }
 
 // SimpleCron 一个简单的定时任务管理器
+//
+//	只支持固定间隔，业务的定时任务请使用 library/cron
+//	每个实例都会运行全部任务，多实例部署时只需运行一次的任务请使用 cron.WithSingleton
 type SimpleCron struct {
 	duration time.Duration
 	jobs     []func()
@@ -0,0 +1,128 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 12:05:33
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 12:05:33
+ * @Description: 持有锁并自动续期，锁丢失时取消 ctx
+ */
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type ctxLockKey struct{}
+
+// FromContext 获取 Hold 返回的 ctx 中持有的锁，不存在时返回 nil
+func FromContext(ctx context.Context) Lock {
+	if l, ok := ctx.Value(ctxLockKey{}).(Lock); ok {
+		return l
+	}
+	return nil
+}
+
+// Lease 自动续期的锁
+type Lease struct {
+	lock   Lock
+	ttl    time.Duration
+	cancel context.CancelCauseFunc
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Hold 获取锁，并每隔 ttl/3 自动续期，直到调用 Release
+//
+//	返回的 ctx 在锁丢失时取消，context.Cause(ctx) 为 ErrLockLost
+//	续期失败（如网络异常）时继续重试，直到距上一次成功续期超过 ttl 才认为锁丢失
+func Hold(ctx context.Context, locker Locker, key string, ttl time.Duration) (context.Context, *Lease, error) {
+	l, err := locker.TryLock(ctx, key, ttl)
+	if err != nil {
+		return ctx, nil, err
+	}
+	ctx, cancel := context.WithCancelCause(context.WithValue(ctx, ctxLockKey{}, l))
+	le := &Lease{
+		lock:   l,
+		ttl:    ttl,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go le.renew(ctx)
+	return ctx, le, nil
+}
+
+// Lock 持有的锁
+func (le *Lease) Lock() Lock {
+	return le.lock
+}
+
+// Release 停止续期并释放锁
+func (le *Lease) Release(ctx context.Context) error {
+	le.stopRenew()
+	err := le.lock.Release(ctx)
+	le.cancel(context.Canceled)
+	return err
+}
+
+// ReleaseAfter 停止续期，锁在 d 之后释放
+//
+//	用于执行很快的任务：其他实例的时钟略晚时，仍能看到锁被占用，不会重复执行
+func (le *Lease) ReleaseAfter(ctx context.Context, d time.Duration) error {
+	le.stopRenew()
+	le.cancel(context.Canceled)
+	if d <= 0 {
+		return le.lock.Release(ctx)
+	}
+	// 有有效期的锁直接缩短有效期，没有有效期的锁（如 mysql）到期后主动释放
+	if err := le.lock.Refresh(ctx, d); err != nil {
+		return err
+	}
+	time.AfterFunc(d, func() {
+		_ = le.lock.Release(context.Background())
+	})
+	return nil
+}
+
+func (le *Lease) stopRenew() {
+	le.stopOnce.Do(func() {
+		close(le.stop)
+	})
+	<-le.done
+}
+
+func (le *Lease) renew(ctx context.Context) {
+	defer close(le.done)
+	interval := le.ttl / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(le.ttl)
+	for {
+		select {
+		case <-le.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := le.lock.Refresh(rctx, le.ttl)
+		cancel()
+		switch {
+		case err == nil:
+			deadline = time.Now().Add(le.ttl)
+		case errors.Is(err, ErrLockLost) || time.Now().After(deadline):
+			le.cancel(ErrLockLost)
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 11:52:08
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 11:52:08
+ * @Description: 分布式锁，提供 redis、mysql 与内存三种实现
+ */
+package lock
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotObtained 锁已被其他持有者占用
+	ErrNotObtained = errors.New("lock not obtained")
+
+	// ErrLockLost 锁已过期或被其他持有者占用
+	ErrLockLost = errors.New("lock lost")
+)
+
+// Locker 分布式锁
+type Locker interface {
+	// TryLock 尝试获取锁，不等待，已被占用时返回 ErrNotObtained
+	//
+	//	ttl 为锁的有效期，持有者异常退出时，锁在 ttl 后自动释放
+	TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock 已获取的锁
+type Lock interface {
+	// Key 锁的名称
+	Key() string
+
+	// Token fencing token，同一个 key 每次获取锁时递增
+	// 写入下游时带上 token，下游拒绝比已见过的更小的 token，可避免锁过期后旧持有者的写入
+	Token() int64
+
+	// Refresh 续期为 ttl，锁已丢失时返回 ErrLockLost
+	Refresh(ctx context.Context, ttl time.Duration) error
+
+	// Release 释放锁，锁已丢失时返回 ErrLockLost
+	Release(ctx context.Context) error
+}
+
+// newOwner 随机的持有者标识，用于判断锁是否仍由自己持有
+func newOwner() string {
+	b := make([]byte, 16)
+	_, _ = crand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,114 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 12:58:36
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 12:58:36
+ * @Description: 分布式锁用例
+ */
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	ml := NewMemoryLocker()
+	l1, err := ml.TryLock(ctx, "job", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ml.TryLock(ctx, "job", time.Second); !errors.Is(err, ErrNotObtained) {
+		t.Fatalf("want ErrNotObtained, got %v", err)
+	}
+	if _, err := ml.TryLock(ctx, "other", time.Second); err != nil {
+		t.Fatalf("other key should be free: %v", err)
+	}
+
+	// 过期后可被其他持有者获取，token 递增，旧持有者续期、释放均失败
+	time.Sleep(60 * time.Millisecond)
+	l2, err := ml.TryLock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l2.Token() <= l1.Token() {
+		t.Errorf("token should increase: %d -> %d", l1.Token(), l2.Token())
+	}
+	if err := l1.Refresh(ctx, time.Second); !errors.Is(err, ErrLockLost) {
+		t.Errorf("stale refresh: want ErrLockLost, got %v", err)
+	}
+	if err := l1.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("stale release: want ErrLockLost, got %v", err)
+	}
+	if !ml.Held("job") {
+		t.Error("stale release should not free the lock")
+	}
+	if err := l2.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ml.Held("job") {
+		t.Error("lock should be free after release")
+	}
+}
+
+func TestHold(t *testing.T) {
+	ml := NewMemoryLocker()
+	ctx, lease, err := Hold(context.Background(), ml, "job", 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FromContext(ctx) != lease.Lock() {
+		t.Error("lock should be stored in ctx")
+	}
+
+	// 持有时间超过 ttl，依靠续期仍未丢失
+	time.Sleep(100 * time.Millisecond)
+	if ctx.Err() != nil || !ml.Held("job") {
+		t.Fatal("lease should keep the lock alive")
+	}
+	if err := lease.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ml.Held("job") {
+		t.Error("lock should be free after release")
+	}
+
+	// 锁被抢走后 ctx 取消
+	ctx, lease, err = Hold(context.Background(), ml, "job", 30*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ml.Revoke("job")
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("ctx should be cancelled after lock lost")
+	}
+	if !errors.Is(context.Cause(ctx), ErrLockLost) {
+		t.Errorf("want cause ErrLockLost, got %v", context.Cause(ctx))
+	}
+	if err := lease.Release(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Errorf("want ErrLockLost, got %v", err)
+	}
+}
+
+func TestReleaseAfter(t *testing.T) {
+	ml := NewMemoryLocker()
+	_, lease, err := Hold(context.Background(), ml, "job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lease.ReleaseAfter(context.Background(), 30*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if !ml.Held("job") {
+		t.Error("lock should be held until d")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if ml.Held("job") {
+		t.Error("lock should be free after d")
+	}
+}
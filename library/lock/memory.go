@@ -0,0 +1,115 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 12:46:22
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 12:46:22
+ * @Description: 进程内的锁，用于单测与单实例部署
+ */
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewMemoryLocker 进程内的锁
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+// MemoryLocker 进程内的锁，并发安全
+type MemoryLocker struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	owner  string
+	expire time.Time
+	token  int64
+}
+
+var _ Locker = (*MemoryLocker)(nil)
+
+func (ml *MemoryLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	e, has := ml.entries[key]
+	if !has {
+		e = &memoryEntry{}
+		ml.entries[key] = e
+	}
+	if e.owner != "" && time.Now().Before(e.expire) {
+		return nil, ErrNotObtained
+	}
+	e.owner = newOwner()
+	e.expire = time.Now().Add(ttl)
+	e.token++
+	return &memoryLock{
+		locker: ml,
+		key:    key,
+		owner:  e.owner,
+		token:  e.token,
+	}, nil
+}
+
+// Held key 是否被持有
+func (ml *MemoryLocker) Held(key string) bool {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	e, has := ml.entries[key]
+	return has && e.owner != "" && time.Now().Before(e.expire)
+}
+
+// Revoke 强制释放 key，用于模拟锁丢失
+func (ml *MemoryLocker) Revoke(key string) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	if e, has := ml.entries[key]; has {
+		e.owner = ""
+	}
+}
+
+type memoryLock struct {
+	locker *MemoryLocker
+	key    string
+	owner  string
+	token  int64
+}
+
+var _ Lock = (*memoryLock)(nil)
+
+func (l *memoryLock) Key() string {
+	return l.key
+}
+
+func (l *memoryLock) Token() int64 {
+	return l.token
+}
+
+func (l *memoryLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	ml := l.locker
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	e := ml.entries[l.key]
+	if e.owner != l.owner || !time.Now().Before(e.expire) {
+		return ErrLockLost
+	}
+	e.expire = time.Now().Add(ttl)
+	return nil
+}
+
+func (l *memoryLock) Release(ctx context.Context) error {
+	ml := l.locker
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+	e := ml.entries[l.key]
+	if e.owner != l.owner || !time.Now().Before(e.expire) {
+		return ErrLockLost
+	}
+	e.owner = ""
+	return nil
+}
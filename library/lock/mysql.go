@@ -0,0 +1,140 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 12:34:10
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 12:34:10
+ * @Description: 基于 mysql GET_LOCK 的分布式锁
+ */
+package lock
+
+import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/mysql"
+)
+
+// mysql 锁名的最大长度，超过时使用哈希值
+const mysqlMaxLockName = 64
+
+// NewMySQLLocker 基于 mysql GET_LOCK 的锁，prefix 为锁名的前缀
+//
+//	锁与连接绑定，每个锁独占一个连接，连接断开时锁自动释放，ttl 仅用于判断续期是否超时
+//	fencing token 为持有锁的连接 ID，同一个 mysql 实例重启前单调递增
+func NewMySQLLocker(client mysql.Client, prefix string) Locker {
+	return &mysqlLocker{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+type mysqlLocker struct {
+	client mysql.Client
+	prefix string
+}
+
+var _ Locker = (*mysqlLocker)(nil)
+
+func (ml *mysqlLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	name := ml.prefix + key
+	if len(name) > mysqlMaxLockName {
+		sum := sha1.Sum([]byte(name))
+		name = hex.EncodeToString(sum[:])
+	}
+	conn, err := ml.client.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		got   sql.NullInt64
+		token int64
+	)
+	// 超时时间为 0，不等待
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0), CONNECTION_ID()", name).Scan(&got, &token)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if got.Int64 != 1 {
+		conn.Close()
+		return nil, ErrNotObtained
+	}
+	return &mysqlLock{
+		conn:  conn,
+		key:   key,
+		name:  name,
+		token: token,
+	}, nil
+}
+
+type mysqlLock struct {
+	key   string
+	name  string
+	token int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+var _ Lock = (*mysqlLock)(nil)
+
+func (l *mysqlLock) Key() string {
+	return l.key
+}
+
+func (l *mysqlLock) Token() int64 {
+	return l.token
+}
+
+// Refresh 检查锁是否仍由当前连接持有，mysql 的锁没有有效期，不使用 ttl
+func (l *mysqlLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return ErrLockLost
+	}
+	var owned sql.NullBool
+	err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", l.name).Scan(&owned)
+	if err != nil {
+		return l.connErr(err)
+	}
+	if !owned.Bool {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release 释放锁并将连接归还连接池
+func (l *mysqlLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conn == nil {
+		return ErrLockLost
+	}
+	var released sql.NullInt64
+	err := l.conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", l.name).Scan(&released)
+	l.conn.Close()
+	l.conn = nil
+	if err != nil {
+		return l.connErr(err)
+	}
+	if released.Int64 != 1 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// connErr 连接断开时会话随之结束，锁已被释放
+func (l *mysqlLock) connErr(err error) error {
+	if errors.Is(err, sql.ErrConnDone) || errors.Is(err, driver.ErrBadConn) {
+		return ErrLockLost
+	}
+	return err
+}
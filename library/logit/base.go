@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-04 15:40:52
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-31 14:43:00
+ * @LastEditTime: 2026-10-22 14:31:09
  * @Description: 提供默认Service Logger
  */
 package logit
@@ -47,6 +47,14 @@ func SetServiceLogger(ctx context.Context) error {
 	return nil
 }
 
+// Svr service logger，未初始化时为 DefaultLogger，供各组件打印自身的日志
+func Svr() Logger {
+	if SvrLogger != nil {
+		return SvrLogger
+	}
+	return DefaultLogger
+}
+
 // GetLogger 获取 logger
 func GetLogger(ctx context.Context, logName string) (Logger, error) {
 	// 先尝试获取
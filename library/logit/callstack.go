@@ -41,18 +41,23 @@ func Stack() Field {
 
 // StackWithSkip 返回调用栈的Field
 func StackWithSkip(skip int) Field {
-	buf := &bytes.Buffer{}
 	stack := pcsPool.Get().(*stackPtr)
 	defer pcsPool.Put(stack)
 	callStackSize := runtime.Callers(skip, stack.pcs)
-	frames := runtime.CallersFrames(stack.pcs[:callStackSize])
+	return String(stackKey, formatStack(stack.pcs[:callStackSize]))
+}
+
+// formatStack 将 runtime.Callers 得到的调用栈格式化为 file:line;file:line; 的形式
+func formatStack(pcs []uintptr) string {
+	buf := &bytes.Buffer{}
+	frames := runtime.CallersFrames(pcs)
 	for frame, more := frames.Next(); more; frame, more = frames.Next() {
 		buf.WriteString(frame.File)
 		buf.WriteByte(':')
 		buf.WriteString(strconv.Itoa(frame.Line))
 		buf.WriteByte(';')
 	}
-	return String(stackKey, buf.String())
+	return buf.String()
 }
 
 // CallerField 默认的获取调用栈的Field
@@ -112,6 +112,15 @@ type Config struct {
 
 	encoderPool EncoderPool
 
+	// 敏感字段脱敏规则，为空时不脱敏
+	Redact []*RedactRule
+
+	redactor *Redactor
+
+	// 最近日志的内存缓冲，为nil时不开启
+	// 开启后可通过 RingOf 获取，并注册查询接口
+	Ring *ConfigRing
+
 	// 是否已经解析过
 	parsed bool
 
@@ -123,6 +132,15 @@ type Config struct {
 	err error
 }
 
+// ConfigRing 最近日志内存缓冲的配置部分
+type ConfigRing struct {
+	// 最多保留的日志行数，默认 DefaultRingMaxLines
+	MaxLines int
+
+	// 最多占用的字节数，默认 DefaultRingMaxBytes
+	MaxBytes int
+}
+
 // DefaultMaxFileNum 默认文件保留数
 var DefaultMaxFileNum = 48
 
@@ -210,6 +228,14 @@ func (cfg *Config) parser() error {
 		}
 	}
 
+	if len(cfg.Redact) > 0 {
+		r, err := NewRedactor(cfg.Redact)
+		if err != nil {
+			return err
+		}
+		cfg.redactor = r
+	}
+
 	if cfg.BeforeOutputFunc == nil && cfg.BeforeOutput != "" {
 		fn := GetBeforeOutputFunc(cfg.BeforeOutput)
 		if fn == nil {
@@ -19,6 +19,7 @@ type logContextKey int
 const (
 	ctxLogFieldsKey logContextKey = 100 + iota
 	ctxMetaFieldsKey
+	ctxTailBufferKey
 )
 
 // WithContext 在当前ctx中预埋日志字段
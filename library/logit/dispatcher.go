@@ -48,6 +48,11 @@ func (d *dispatcher) Fatal(ctx context.Context, message string, fields ...Field)
 }
 
 func (d *dispatcher) Output(ctx context.Context, level Level, callDepth int, message string, fields ...Field) {
+	// ctx 挂载了日志缓冲时，先暂存，caller 需要在此刻记录
+	// 跳过 callerWithSkip、Output 本身
+	if tb := FindTailBuffer(ctx); tb != nil && tb.accept(level) && tb.add(level, callerWithSkip(callDepth+2), message, fields) {
+		return
+	}
 	d.dispatchFunc(level).Output(ctx, level, callDepth+1, message, fields...)
 }
 
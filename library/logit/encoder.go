@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2023-10-30 11:28:48
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 11:37:51
+ * @LastEditTime: 2026-10-23 10:38:17
  * @Description: 日志输出器
  */
 package logit
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -49,9 +50,22 @@ type FieldEncoder interface {
 	// slow and allocation-heavy.
 	AddReflected(key string, value interface{}) error
 
+	// AddObject、AddArray 编码嵌套的对象和数组，不使用反射
+	AddObject(key string, value ObjectMarshaler) error
+	AddArray(key string, value ArrayMarshaler) error
+
+	// OpenNamespace 开启一个命名空间，之后添加的字段都将放入该命名空间
+	// 命名空间在本行日志结束(输出message前)时关闭
+	OpenNamespace(key string)
+
 	Reset()
 }
 
+// namespaceCloser 在输出 message 字段前关闭已开启的命名空间，避免 message 被放入命名空间
+type namespaceCloser interface {
+	closeNamespaces()
+}
+
 // TexEncoderOption 文本encoder的配置
 type TexEncoderOption struct {
 	KeyPrefix   []byte
@@ -93,6 +107,9 @@ func NewTextEncoder(opt TexEncoderOption) *TextEncoder {
 type TextEncoder struct {
 	opt TexEncoderOption
 	buf bytes.Buffer
+
+	// 嵌套对象与命名空间的key前缀，形如 "a.b."
+	prefix string
 }
 
 // 实现io.WriterTo接口，将buf写入到另一个io.Writer中
@@ -122,21 +139,20 @@ func (e *TextEncoder) AddByteString(key string, value []byte) {
 
 // AddBool bool类型
 func (e *TextEncoder) AddBool(key string, value bool) {
-	if value {
-		e.write(key, []byte("true"))
-	} else {
-		e.write(key, []byte("false"))
-	}
+	e.writeString(key, strconv.FormatBool(value))
 }
 
 // AddDuration 时间间隔
 func (e *TextEncoder) AddDuration(key string, value time.Duration) {
+	e.writeString(key, textDuration(value))
+}
+
+// textDuration 时间间隔以毫秒输出，保留3位小数
+func textDuration(value time.Duration) string {
 	if value < time.Microsecond {
-		e.write(key, []byte("0"))
-		return
+		return "0"
 	}
-	dur := strconv.FormatFloat(float64(value.Nanoseconds())/float64(time.Millisecond), 'f', 3, 64)
-	e.write(key, []byte(dur))
+	return strconv.FormatFloat(float64(value.Nanoseconds())/float64(time.Millisecond), 'f', 3, 64)
 }
 
 // AddFloat64 float64
@@ -181,11 +197,15 @@ func (e *TextEncoder) AddString(key string, value string) {
 
 // AddTime 时间类型
 func (e *TextEncoder) AddTime(key string, value time.Time) {
+	e.writeString(key, textTime(value))
+}
+
+// textTime 时间以毫秒时间戳输出
+func textTime(value time.Time) string {
 	if value.IsZero() {
-		e.writeString(key, "0")
-	} else {
-		e.writeString(key, strconv.FormatInt(value.UnixNano()/int64(time.Millisecond), 10))
+		return "0"
 	}
+	return strconv.FormatInt(value.UnixNano()/int64(time.Millisecond), 10)
 }
 
 // AddUint Uint
@@ -237,6 +257,37 @@ func (e *TextEncoder) AddReflected(key string, value interface{}) error {
 	return nil
 }
 
+// AddObject 对象展开为 key.subKey[value] 的形式
+func (e *TextEncoder) AddObject(key string, value ObjectMarshaler) error {
+	old := e.prefix
+	e.prefix = old + key + "."
+	err := value.MarshalLogObject(e)
+	e.prefix = old
+	return err
+}
+
+// AddArray 数组中的基础类型元素输出为 key[v1,v2]，嵌套的对象和数组按下标展开，如 key.0.subKey[value]
+func (e *TextEncoder) AddArray(key string, value ArrayMarshaler) error {
+	arr := &textArrayEncoder{
+		enc: e,
+		key: key,
+	}
+	err := value.MarshalLogArray(arr)
+	if arr.elems != nil {
+		e.write(key, arr.elems)
+	}
+	return err
+}
+
+// OpenNamespace 之后的字段输出为 key.subKey[value] 的形式
+func (e *TextEncoder) OpenNamespace(key string) {
+	e.prefix += key + "."
+}
+
+func (e *TextEncoder) closeNamespaces() {
+	e.prefix = ""
+}
+
 // 写入field的终极实现，将field写成key[value]的形式
 // 其中，key的前后缀，value的前后缀，多个kv之间的分隔符，都可以自定义，默认为key[value]，分隔符为一个空格
 // 例如：key1[value1] key2[value2]
@@ -244,6 +295,7 @@ func (e *TextEncoder) write(key string, val []byte) {
 	if len(e.opt.KeyPrefix) > 0 {
 		_, _ = e.buf.Write(e.opt.KeyPrefix)
 	}
+	_, _ = e.buf.WriteString(e.prefix)
 	_, _ = e.buf.WriteString(key)
 
 	if len(e.opt.KeySuffix) > 0 {
@@ -268,22 +320,112 @@ func (e *TextEncoder) writeString(key string, val string) {
 // Reset 重置
 func (e *TextEncoder) Reset() {
 	e.buf.Reset()
+	e.prefix = ""
 }
 
 var _ FieldEncoder = (*TextEncoder)(nil)
 
+// textArrayEscaper 转义字符串元素中的分隔符与换行，避免元素被拆开或破坏单行日志
+var textArrayEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "]", `\]`, "\n", `\n`, "\r", `\r`)
+
+// textArrayEncoder TextEncoder 的数组编码器
+type textArrayEncoder struct {
+	enc *TextEncoder
+	key string
+
+	// 已编码的元素数量，用于嵌套元素的下标
+	idx int
+
+	// 基础类型元素，以逗号分隔
+	elems []byte
+}
+
+func (a *textArrayEncoder) append(value string) {
+	if a.elems != nil {
+		a.elems = append(a.elems, ',')
+	} else {
+		a.elems = make([]byte, 0, 32)
+	}
+	a.elems = append(a.elems, value...)
+	a.idx++
+}
+
+func (a *textArrayEncoder) AppendBool(value bool) {
+	a.append(strconv.FormatBool(value))
+}
+
+func (a *textArrayEncoder) AppendByteString(value []byte) {
+	a.append(textArrayEscaper.Replace(string(value)))
+}
+
+func (a *textArrayEncoder) AppendDuration(value time.Duration) {
+	a.append(textDuration(value))
+}
+
+func (a *textArrayEncoder) AppendFloat64(value float64) {
+	a.append(strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (a *textArrayEncoder) AppendInt(value int) {
+	a.append(strconv.Itoa(value))
+}
+
+func (a *textArrayEncoder) AppendInt64(value int64) {
+	a.append(strconv.FormatInt(value, 10))
+}
+
+func (a *textArrayEncoder) AppendString(value string) {
+	a.append(textArrayEscaper.Replace(value))
+}
+
+func (a *textArrayEncoder) AppendTime(value time.Time) {
+	a.append(textTime(value))
+}
+
+func (a *textArrayEncoder) AppendUint64(value uint64) {
+	a.append(strconv.FormatUint(value, 10))
+}
+
+func (a *textArrayEncoder) AppendObject(value ObjectMarshaler) error {
+	err := a.enc.AddObject(a.key+"."+strconv.Itoa(a.idx), value)
+	a.idx++
+	return err
+}
+
+func (a *textArrayEncoder) AppendArray(value ArrayMarshaler) error {
+	err := a.enc.AddArray(a.key+"."+strconv.Itoa(a.idx), value)
+	a.idx++
+	return err
+}
+
+func (a *textArrayEncoder) AppendReflected(value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	a.append(textArrayEscaper.Replace(string(b)))
+	return nil
+}
+
+var _ ArrayEncoder = (*textArrayEncoder)(nil)
+
 // JSONEncoder 以 {key: value} 格式输出 JSON 格式的Encoder
 type JSONEncoder struct {
 	kv map[string]interface{}
 
+	// 当前写入的对象，开启命名空间后指向命名空间对应的对象
+	cur map[string]interface{}
+
 	LineBreak []byte // 换行符
 }
 
 // NewJSONEncoder 打包输出为json格式
 func NewJSONEncoder() FieldEncoder {
+	// 32:手百日志大概30个字段
+	kv := make(map[string]interface{}, 32)
 	return &JSONEncoder{
-		// 32:手百日志大概30个字段
-		kv: make(map[string]interface{}, 32),
+		kv:  kv,
+		cur: kv,
 
 		LineBreak: []byte("\n"),
 	}
@@ -304,121 +446,227 @@ func (e *JSONEncoder) WriteTo(w io.Writer) (int64, error) {
 
 // AddBinary  Binary
 func (e *JSONEncoder) AddBinary(key string, value []byte) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddBool  Bool
 func (e *JSONEncoder) AddBool(key string, value bool) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddByteString  ByteString
 func (e *JSONEncoder) AddByteString(key string, value []byte) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddDuration duration
 func (e *JSONEncoder) AddDuration(key string, value time.Duration) {
-	e.kv[key] = value.String()
+	e.cur[key] = value.String()
 }
 
 // AddFloat64 Float64
 func (e *JSONEncoder) AddFloat64(key string, value float64) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddFloat32 Float32
 func (e *JSONEncoder) AddFloat32(key string, value float32) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddInt Int
 func (e *JSONEncoder) AddInt(key string, value int) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddInt64 Int64
 func (e *JSONEncoder) AddInt64(key string, value int64) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddInt32 Int32
 func (e *JSONEncoder) AddInt32(key string, value int32) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddInt16 Int16
 func (e *JSONEncoder) AddInt16(key string, value int16) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddInt8 Int8
 func (e *JSONEncoder) AddInt8(key string, value int8) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddString String
 func (e *JSONEncoder) AddString(key string, value string) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddTime Time
 func (e *JSONEncoder) AddTime(key string, value time.Time) {
-	e.kv[key] = value.Format(time.RFC3339Nano)
+	e.cur[key] = value.Format(time.RFC3339Nano)
 }
 
 // AddUint Uint
 func (e *JSONEncoder) AddUint(key string, value uint) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddUint64 Uint64
 func (e *JSONEncoder) AddUint64(key string, value uint64) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddUint32 Uint32
 func (e *JSONEncoder) AddUint32(key string, value uint32) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddUint16 Uint16
 func (e *JSONEncoder) AddUint16(key string, value uint16) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddUint8 Uint8
 func (e *JSONEncoder) AddUint8(key string, value uint8) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddUintptr Uintptr
 func (e *JSONEncoder) AddUintptr(key string, value uintptr) {
-	e.kv[key] = value
+	e.cur[key] = value
 }
 
 // AddReflected Reflected
 func (e *JSONEncoder) AddReflected(key string, value interface{}) error {
-	e.kv[key] = value
+	e.cur[key] = value
 	return nil
 }
 
 // AddError  Error
 func (e *JSONEncoder) AddError(key string, value error) {
 	if value != nil {
-		e.kv[key] = value.Error()
+		e.cur[key] = value.Error()
 		return
 	}
-	e.kv[key] = nil
+	e.cur[key] = nil
+}
+
+// AddObject 编码为嵌套的json对象
+func (e *JSONEncoder) AddObject(key string, value ObjectMarshaler) error {
+	obj, err := jsonObject(value)
+	e.cur[key] = obj
+	return err
+}
+
+// AddArray 编码为json数组
+func (e *JSONEncoder) AddArray(key string, value ArrayMarshaler) error {
+	arr, err := jsonArray(value)
+	e.cur[key] = arr
+	return err
+}
+
+// OpenNamespace 之后的字段都写入 key 对应的嵌套对象
+func (e *JSONEncoder) OpenNamespace(key string) {
+	ns := make(map[string]interface{})
+	e.cur[key] = ns
+	e.cur = ns
+}
+
+func (e *JSONEncoder) closeNamespaces() {
+	e.cur = e.kv
 }
 
 // Reset 重置
 func (e *JSONEncoder) Reset() {
 	e.kv = make(map[string]interface{}, len(e.kv))
+	e.cur = e.kv
 }
 
 var _ FieldEncoder = (*JSONEncoder)(nil)
 
+// jsonObject 将 ObjectMarshaler 编码为 map
+func jsonObject(value ObjectMarshaler) (map[string]interface{}, error) {
+	kv := make(map[string]interface{})
+	nested := &JSONEncoder{
+		kv:  kv,
+		cur: kv,
+	}
+	err := value.MarshalLogObject(nested)
+	return kv, err
+}
+
+// jsonArray 将 ArrayMarshaler 编码为 slice
+func jsonArray(value ArrayMarshaler) ([]interface{}, error) {
+	arr := &jsonArrayEncoder{
+		elems: make([]interface{}, 0, 8),
+	}
+	err := value.MarshalLogArray(arr)
+	return arr.elems, err
+}
+
+// jsonArrayEncoder JSONEncoder 的数组编码器
+type jsonArrayEncoder struct {
+	elems []interface{}
+}
+
+func (a *jsonArrayEncoder) AppendBool(value bool) {
+	a.elems = append(a.elems, value)
+}
+
+func (a *jsonArrayEncoder) AppendByteString(value []byte) {
+	a.elems = append(a.elems, string(value))
+}
+
+func (a *jsonArrayEncoder) AppendDuration(value time.Duration) {
+	a.elems = append(a.elems, value.String())
+}
+
+func (a *jsonArrayEncoder) AppendFloat64(value float64) {
+	a.elems = append(a.elems, value)
+}
+
+func (a *jsonArrayEncoder) AppendInt(value int) {
+	a.elems = append(a.elems, value)
+}
+
+func (a *jsonArrayEncoder) AppendInt64(value int64) {
+	a.elems = append(a.elems, value)
+}
+
+func (a *jsonArrayEncoder) AppendString(value string) {
+	a.elems = append(a.elems, value)
+}
+
+func (a *jsonArrayEncoder) AppendTime(value time.Time) {
+	a.elems = append(a.elems, value.Format(time.RFC3339Nano))
+}
+
+func (a *jsonArrayEncoder) AppendUint64(value uint64) {
+	a.elems = append(a.elems, value)
+}
+
+func (a *jsonArrayEncoder) AppendObject(value ObjectMarshaler) error {
+	obj, err := jsonObject(value)
+	a.elems = append(a.elems, obj)
+	return err
+}
+
+func (a *jsonArrayEncoder) AppendArray(value ArrayMarshaler) error {
+	arr, err := jsonArray(value)
+	a.elems = append(a.elems, arr)
+	return err
+}
+
+func (a *jsonArrayEncoder) AppendReflected(value interface{}) error {
+	a.elems = append(a.elems, value)
+	return nil
+}
+
+var _ ArrayEncoder = (*jsonArrayEncoder)(nil)
+
 // NewEncoderPool 创建一个encoder 对象池
 func NewEncoderPool(newFn func() FieldEncoder) EncoderPool {
 	return &encoderPool{
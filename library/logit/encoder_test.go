@@ -0,0 +1,94 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 11:42:18
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 10:38:17
+ * @Description: 对象、数组字段编码用例
+ */
+package logit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	ID   int
+	Name string
+	Tags []string
+}
+
+func (u *testUser) MarshalLogObject(enc FieldEncoder) error {
+	enc.AddInt("id", u.ID)
+	enc.AddString("name", u.Name)
+	return enc.AddArray("tags", stringArray(u.Tags))
+}
+
+func TestTextEncoderObject(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewSimple(buf)
+	users := ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+		return enc.AppendObject(&testUser{ID: 2, Name: "b"})
+	})
+	l.Notice(context.Background(), "dto",
+		Object("user", &testUser{ID: 1, Name: "a", Tags: []string{"x", "y"}}),
+		Ints("ids", []int{1, 2, 3}),
+		Array("users", users),
+		Namespace("req"),
+		String("path", "/a"),
+	)
+
+	line := buf.String()
+	for _, want := range []string{
+		"user.id[1] user.name[a] user.tags[x,y]",
+		"ids[1,2,3]",
+		"users.0.id[2] users.0.name[b]",
+		"req.path[/a]",
+		" message[dto]",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+
+	// 字符串元素中的逗号、] 与换行被转义，不会拆开元素或破坏单行日志
+	buf.Reset()
+	l.Notice(context.Background(), "escape", Strings("tags", []string{"a,b", "c]d", "e\nf", `g\h`}))
+	if want := `tags[a\,b,c\]d,e\nf,g\\h]`; !strings.Contains(buf.String(), want) {
+		t.Errorf("want %q in %q", want, buf.String())
+	}
+	buf.Reset()
+	reflected := ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+		return enc.AppendReflected(map[string]interface{}{"a": 1, "b": []int{2, 3}})
+	})
+	l.Notice(context.Background(), "escape", Array("objs", reflected))
+	if want := `objs[{"a":1\,"b":[2\,3\]}]`; !strings.Contains(buf.String(), want) {
+		t.Errorf("want %q in %q", want, buf.String())
+	}
+}
+
+func TestJSONEncoderObject(t *testing.T) {
+	enc := NewJSONEncoder()
+	Object("user", &testUser{ID: 1, Name: "a", Tags: []string{"x"}}).AddTo(enc)
+	Strings("roles", []string{"admin"}).AddTo(enc)
+	Namespace("req").AddTo(enc)
+	String("path", "/a").AddTo(enc)
+	enc.(namespaceCloser).closeNamespaces()
+	enc.AddString("message", "dto")
+
+	buf := &bytes.Buffer{}
+	if _, err := enc.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"message":"dto","req":{"path":"/a"},"roles":["admin"],"user":{"id":1,"name":"a","tags":["x"]}}`
+	if b, _ := json.Marshal(got); string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 22:47:16
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 14:47:25
+ * @Description: 错误字段的展开：错误链、附加信息与调用栈
+ */
+package logit
+
+import (
+	"fmt"
+	"reflect"
+)
+
+const (
+	// ErrorCausesSuffix 错误链中各个根因（没有再包装其他错误的错误）的信息，如 errCauses[a,b]
+	ErrorCausesSuffix = "Causes"
+
+	// ErrorDetailSuffix 错误链中第一个实现了 ObjectMarshaler 的错误的附加信息，如 errDetail.code[-3]
+	ErrorDetailSuffix = "Detail"
+
+	// ErrorStackSuffix 错误创建时的调用栈，取错误链中最内层的，如 errStack[xxx.go:12;xxx.go:30;]
+	ErrorStackSuffix = "Stack"
+
+	// 错误链最多展开的错误数量，避免异常的 Unwrap 实现
+	maxErrorChain = 32
+)
+
+// addErrorDetail 将错误链、附加信息与调用栈作为额外的字段写入编码器
+//
+//	错误本身的信息由 AddError 写入，这里只写入额外的字段，普通错误不会产生额外字段
+func addErrorDetail(enc FieldEncoder, key string, err error) {
+	if err == nil {
+		return
+	}
+	chain := errorChain(err)
+
+	// 只输出根因，中间的包装（如 fmt.Errorf 的 %w、errors.Join）已包含在错误信息中
+	// 与错误本身信息相同的根因只是被包装了一层，不重复输出，如 WithCode 之类的包装
+	var causes []string
+	last := err.Error()
+	for _, e := range chain[1:] {
+		if !isRootCause(e) {
+			continue
+		}
+		msg := e.Error()
+		if msg == last {
+			continue
+		}
+		causes = append(causes, msg)
+		last = msg
+	}
+	if len(causes) > 0 {
+		Strings(key+ErrorCausesSuffix, causes).AddTo(enc)
+	}
+
+	for _, e := range chain {
+		if m, ok := e.(ObjectMarshaler); ok {
+			// 没有任何附加信息时不输出，避免 json 中出现空对象
+			probe := &TextEncoder{}
+			if err := m.MarshalLogObject(probe); err != nil || probe.buf.Len() > 0 {
+				_ = enc.AddObject(key+ErrorDetailSuffix, m)
+			}
+			break
+		}
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		if stack := errorStack(chain[i]); stack != "" {
+			enc.AddString(key+ErrorStackSuffix, stack)
+			break
+		}
+	}
+}
+
+// errorChain 按深度优先展开错误链，第一个是 err 本身
+//
+//	支持 Unwrap() error（如 fmt.Errorf 的 %w）与 Unwrap() []error（如 errors.Join）
+func errorChain(err error) []error {
+	chain := make([]error, 0, 4)
+	var walk func(e error)
+	walk = func(e error) {
+		if e == nil || len(chain) >= maxErrorChain {
+			return
+		}
+		chain = append(chain, e)
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, c := range u.Unwrap() {
+				walk(c)
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// isRootCause 错误没有再包装其他错误
+func isRootCause(err error) bool {
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return u.Unwrap() == nil
+	case interface{ Unwrap() []error }:
+		return len(u.Unwrap()) == 0
+	}
+	return true
+}
+
+// errorStack 获取错误的调用栈，错误需实现 StackTrace 方法
+//
+//	返回值为 uintptr 的切片时（如 library/errors、github.com/pkg/errors）格式与 Stack 相同，
+//	否则使用返回值的 String 方法
+func errorStack(err error) string {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return ""
+	}
+	v := m.Call(nil)[0]
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uintptr {
+		pcs := make([]uintptr, v.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(v.Index(i).Uint())
+		}
+		return formatStack(pcs)
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
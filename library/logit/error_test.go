@@ -0,0 +1,117 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 23:31:09
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 13:40:11
+ * @Description: 错误字段展开用例
+ */
+package logit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// stackErr 记录了调用栈、带附加信息的错误
+type stackErr struct {
+	msg   string
+	code  int
+	stack []uintptr
+}
+
+func newStackErr(msg string, code int) error {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(2, pcs)
+	return &stackErr{msg: msg, code: code, stack: pcs[:n]}
+}
+
+func (e *stackErr) Error() string         { return e.msg }
+func (e *stackErr) StackTrace() []uintptr { return e.stack }
+func (e *stackErr) MarshalLogObject(enc FieldEncoder) error {
+	if e.code != 0 {
+		enc.AddInt("code", e.code)
+	}
+	return nil
+}
+
+func TestErrorFieldText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewSimple(buf)
+	origin := newStackErr("connection refused", -3)
+	err := fmt.Errorf("query user: %w", errors.Join(origin, errors.New("retry exhausted")))
+	l.Error(context.Background(), "failed", Error("err", err))
+
+	line := buf.String()
+	for _, want := range []string{
+		"errCauses[connection refused,retry exhausted]",
+		"errDetail.code[-3]",
+		"errStack[",
+		"error_test.go:",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+
+	// 根因中的换行被转义
+	buf.Reset()
+	l.Error(context.Background(), "failed", Error("err", fmt.Errorf("exec: %w", errors.New("line1\nline2"))))
+	if line := buf.String(); !strings.Contains(line, `errCauses[line1\nline2]`) {
+		t.Errorf("newline in causes should be escaped: %q", line)
+	}
+
+	// 普通的错误不产生额外字段
+	buf.Reset()
+	l.Error(context.Background(), "failed", Error("err", errors.New("plain")))
+	if strings.Contains(buf.String(), "errCauses") || strings.Contains(buf.String(), "errStack") {
+		t.Errorf("plain error should not be expanded: %q", buf.String())
+	}
+}
+
+func TestErrorFieldJSON(t *testing.T) {
+	enc := NewJSONEncoder()
+	Error("err", fmt.Errorf("wrap: %w", newStackErr("boom", 0))).AddTo(enc)
+	buf := &bytes.Buffer{}
+	if _, err := enc.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["err"] != "wrap: boom" || fmt.Sprint(got["errCauses"]) != "[boom]" {
+		t.Errorf("unexpected %v", got)
+	}
+	if _, has := got["errDetail"]; has {
+		t.Errorf("empty detail should be omitted: %v", got)
+	}
+	if s, _ := got["errStack"].(string); !strings.Contains(s, "error_test.go:") {
+		t.Errorf("unexpected stack %v", got["errStack"])
+	}
+}
+
+func TestErrorFieldRedact(t *testing.T) {
+	r, err := NewRedactor([]*RedactRule{{Value: "phone"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	l := &SimpleLogger{Writer: buf, EncoderPool: DefaultTextEncoderPool, Redactor: r}
+	l.Error(context.Background(), "failed", Error("err", fmt.Errorf("login: %w", newStackErr("denied", 0))))
+	if line := buf.String(); !strings.Contains(line, "errCauses[denied]") || !strings.Contains(line, "errStack[") {
+		t.Errorf("error detail should be kept when not redacted: %q", line)
+	}
+
+	// 错误信息被脱敏时，错误链中的原文不能输出
+	buf.Reset()
+	l.Error(context.Background(), "failed", Error("err", fmt.Errorf("login: %w", newStackErr("phone 13800138000 denied", 0))))
+	if line := buf.String(); strings.Contains(line, "13800138000") || !strings.Contains(line, "err[login: phone") {
+		t.Errorf("phone should be redacted: %q", line)
+	}
+}
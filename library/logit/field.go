@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2023-10-30 11:26:22
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 11:38:01
+ * @LastEditTime: 2026-10-23 10:38:17
  * @Description: 日志字段
  */
 package logit
@@ -90,6 +90,15 @@ const (
 
 	// DeferType 延迟获取值的类型
 	DeferType
+
+	// ObjectMarshalerType 自编码对象的类型
+	ObjectMarshalerType
+
+	// ArrayMarshalerType 自编码数组的类型
+	ArrayMarshalerType
+
+	// NamespaceType 命名空间，之后的字段都在该命名空间下
+	NamespaceType
 )
 
 // Field 一个日志字段
@@ -207,12 +216,20 @@ func FieldAddToEncoder(f Field, enc FieldEncoder) {
 	case ErrorType:
 		if value, ok := f.Value().(error); ok {
 			enc.AddError(f.Key(), value)
+			// 错误链、附加信息与调用栈作为额外的字段
+			addErrorDetail(enc, f.Key(), value)
 		} else {
 			enc.AddError(f.Key(), nil)
 		}
 	case DeferType:
 		fn := f.Value().(func() interface{})
 		AutoField(f.Key(), fn()).AddTo(enc)
+	case ObjectMarshalerType:
+		err = enc.AddObject(f.Key(), f.Value().(ObjectMarshaler))
+	case ArrayMarshalerType:
+		err = enc.AddArray(f.Key(), f.Value().(ArrayMarshaler))
+	case NamespaceType:
+		enc.OpenNamespace(f.Key())
 	default:
 		panic(fmt.Sprintf("unknown field type: %v", f))
 	}
@@ -250,7 +267,7 @@ func FieldEqual(a, b Field) bool {
 	switch a.Type() {
 	case BinaryType, ByteStringType:
 		return bytes.Equal(a.Value().([]byte), b.Value().([]byte))
-	case ErrorType, ReflectType:
+	case ErrorType, ReflectType, ObjectMarshalerType, ArrayMarshalerType:
 		return reflect.DeepEqual(a, b)
 	default:
 		return a.Value() == b.Value()
@@ -457,6 +474,48 @@ func Defer(key string, value func() interface{}) Field {
 	}
 }
 
+// Object 对象字段，由 value 自行编码，避免反射
+//
+//	json 编码为嵌套的对象，text 编码展开为 key.subKey[value] 的形式
+func Object(key string, value ObjectMarshaler) Field {
+	return &field{
+		fieldType: ObjectMarshalerType,
+		key:       key,
+		value:     value,
+	}
+}
+
+// Array 数组字段，由 value 自行编码，避免反射
+//
+//	json 编码为数组，text 编码为 key[v1,v2] 的形式
+func Array(key string, value ArrayMarshaler) Field {
+	return &field{
+		fieldType: ArrayMarshalerType,
+		key:       key,
+		value:     value,
+	}
+}
+
+// Strings 字符串数组字段
+func Strings(key string, value []string) Field {
+	return Array(key, stringArray(value))
+}
+
+// Ints 整数数组字段
+func Ints(key string, value []int) Field {
+	return Array(key, intArray(value))
+}
+
+// Namespace 开启一个命名空间，同一次日志调用中之后传入的字段都将放入该命名空间
+//
+//	如 Namespace("req"), String("id", "1") 输出为 req.id[1] 或 {"req":{"id":"1"}}
+func Namespace(key string) Field {
+	return &field{
+		fieldType: NamespaceType,
+		key:       key,
+	}
+}
+
 // AutoField field creator
 func AutoField(key string, value interface{}) Field {
 	switch val := value.(type) {
@@ -500,6 +559,14 @@ func AutoField(key string, value interface{}) Field {
 		return Error(key, val)
 	case func() interface{}:
 		return Defer(key, val)
+	case []string:
+		return Strings(key, val)
+	case []int:
+		return Ints(key, val)
+	case ObjectMarshaler:
+		return Object(key, val)
+	case ArrayMarshaler:
+		return Array(key, val)
 	}
 	return Reflect(key, value)
 }
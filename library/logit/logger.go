@@ -102,6 +102,7 @@ func NewLogger(ctx context.Context, opts ...Option) (l Logger, errResult error)
 			EncoderPool:      cfg.encoderPool,
 			BeforeOutputFunc: cfg.BeforeOutputFunc,
 			Writer:           awc,
+			Redactor:         cfg.redactor,
 		}
 		closeFns = append(closeFns, awc.Close)
 
@@ -126,6 +127,17 @@ func NewLogger(ctx context.Context, opts ...Option) (l Logger, errResult error)
 
 	dl.closeFunc = closeWritersFunc
 
+	// 最近日志的内存缓冲记录所有等级的日志，与文件日志使用相同的编码与脱敏规则
+	if cfg.Ring != nil {
+		ring := NewRingLogger(&RingOptions{
+			MaxLines:    cfg.Ring.MaxLines,
+			MaxBytes:    cfg.Ring.MaxBytes,
+			EncoderPool: cfg.encoderPool,
+			Redactor:    cfg.redactor,
+		})
+		return MultiLogger(dl, ring), nil
+	}
+
 	// 比如，调用Warning，某两个文件后缀都需要写入Warning级别的日志，那么会通过MultiLogger调用两次Output写入文件
 	return dl, nil
 }
@@ -43,6 +43,10 @@ type SimpleLogger struct {
 
 	// 最小日志等级，低于此等级的日志信息将不打印
 	MinLevel Level
+
+	// 敏感字段脱敏，为nil时不脱敏
+	// 对ctx里存储的字段、meta fields、传入的字段以及message均生效
+	Redactor *Redactor
 }
 
 // Debug debug
@@ -93,6 +97,12 @@ func (sl *SimpleLogger) Output(ctx context.Context, level Level, callDepth int,
 		sl.BeforeOutputFunc(ctx, enc, level, callDepth+2)
 	}
 
+	// 字段在写入编码器前进行脱敏
+	var fenc FieldEncoder = enc
+	if sl.Redactor != nil {
+		fenc = &redactEncoder{FieldEncoder: enc, r: sl.Redactor}
+	}
+
 	// 字段的顺序
 	// 1：ctx里存储的字段  2：全局meta fields  3：传入的临时补充字段
 
@@ -119,11 +129,11 @@ func (sl *SimpleLogger) Output(ctx context.Context, level Level, callDepth int,
 		if f.Level().Is(level) {
 			// 若字段之前在ctx，后面又在fields 里出现，则使用后面传入的
 			if fn, has := fkv[f.Key()]; has {
-				fn.AddTo(enc)
+				fn.AddTo(fenc)
 				// 不需要再打印第二次：如requestID在meta&log字段中都有，只需要打印一次
 				delete(fkv, f.Key())
 			} else {
-				f.AddTo(enc)
+				f.AddTo(fenc)
 			}
 		}
 		return nil
@@ -134,7 +144,7 @@ func (sl *SimpleLogger) Output(ctx context.Context, level Level, callDepth int,
 		if len(metaFields) > 0 {
 			for _, f := range metaFields {
 				if lastField, has := fkv[f.Key()]; has {
-					lastField.AddTo(enc)
+					lastField.AddTo(fenc)
 					delete(fkv, f.Key())
 				}
 			}
@@ -143,13 +153,21 @@ func (sl *SimpleLogger) Output(ctx context.Context, level Level, callDepth int,
 		// 补充字段 优先级第三
 		for _, f := range fields {
 			if lastField, has := fkv[f.Key()]; has {
-				lastField.AddTo(enc)
+				lastField.AddTo(fenc)
 			}
 		}
 	}
 
+	// 字段中开启的命名空间只对本次传入的字段生效
+	if nc, ok := enc.(namespaceCloser); ok {
+		nc.closeNamespaces()
+	}
+
 	// 最后添加message字段，形如 message[xxx]
 	// 相当于 Field: String("message", message).AddTo(enc)
+	if sl.Redactor != nil {
+		message = sl.Redactor.RedactString(message)
+	}
 	enc.AddString("message", message)
 
 	// 复用bytes.Buffer，减少内存分配
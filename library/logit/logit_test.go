@@ -2,37 +2,52 @@
  * @Author: liziwei01
  * @Date: 2023-10-31 21:57:23
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 07:39:07
+ * @LastEditTime: 2026-10-19 22:26:03
  * @Description: 用例
  */
-package logit
+package logit_test
 
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"strings"
 	"testing"
-	"time"
-)
 
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/logit/logittest"
+)
 
-// TRACE: 2023-11-01 07:38:36 /Users/liziwei01/Desktop/OpenSource/github.com/gin-lib/library/logit/logit_test.go:29 keyT[valueT] message[test trace]
-// NOTICE: 2023-11-01 07:38:36 /Users/liziwei01/Desktop/OpenSource/github.com/gin-lib/library/logit/logit_test.go:30 keyN[valueN] message[test notice]
+func TestWarning(t *testing.T) {
+	o := logittest.ReplaceSvrLogger(t)
 
-// WARNING: 2023-11-01 07:38:36 /Users/liziwei01/Desktop/OpenSource/github.com/gin-lib/library/logit/logit_test.go:31 keyW[valueW] message[test warning]
-// ERROR: 2023-11-01 07:38:36 /Users/liziwei01/Desktop/OpenSource/github.com/gin-lib/library/logit/logit_test.go:32 keyE[valueE] message[test error]
+	ctx := logit.WithContext(context.Background())
+	logit.SetRequestID(ctx, "123456")
+	logit.AddWarning(ctx, logit.String("uid", "7"))
 
+	logit.SvrLogger.Trace(ctx, "test trace", logit.Error("keyT", fmt.Errorf("valueT")))
+	logit.SvrLogger.Notice(ctx, "test notice", logit.Error("keyN", fmt.Errorf("valueN")))
+	logit.SvrLogger.Warning(ctx, "test warning", logit.Error("keyW", fmt.Errorf("valueW")))
+	logit.SvrLogger.Error(ctx, "test error", logit.Error("keyE", fmt.Errorf("valueE")))
 
-func TestWarning(t *testing.T) {
-	ctx := context.Background()
-	logName := "service"
-	l, err := NewLogger(ctx, OptConfigFile(filepath.Join(logPath, logName+suffix)))
-	if err != nil {
-		t.Fatal(err)
+	if o.Len() != 4 {
+		t.Fatalf("want 4 entries, got %d", o.Len())
 	}
-	l.Trace(ctx, "test trace", Error("keyT", fmt.Errorf("valueT")))
-	l.Notice(ctx, "test notice", Error("keyN", fmt.Errorf("valueN")))
-	l.Warning(ctx, "test warning", Error("keyW", fmt.Errorf("valueW")))
-	l.Error(ctx, "test error", Error("keyE", fmt.Errorf("valueE")))
-	time.Sleep(3 * time.Second)
+	e := logittest.AssertLogged(t, logit.WarningLevel, "test warning", "requestID", "uid", "keyW")
+	if e != nil {
+		if e.Value("requestID") != "123456" || !strings.Contains(e.Caller, "logit_test.go:") {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+	// uid 只在 Warning 等级可见
+	logittest.AssertLogged(t, logit.ErrorLevel, "test error", "requestID", "keyE")
+	if e := o.FilterLevel(logit.ErrorLevel)[0]; e.Has("uid") {
+		t.Errorf("uid should not be visible at error level")
+	}
+	logittest.AssertNotLogged(t, logit.FatalLevel, "")
+	if got := o.FilterField(logit.String("requestID", "123456")); len(got) != 4 {
+		t.Errorf("want 4 entries with requestID, got %d", len(got))
+	}
+
+	o.Reset()
+	logittest.AssertNotLogged(t, logit.WarningLevel, "test warning")
 }
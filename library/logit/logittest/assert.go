@@ -0,0 +1,106 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 22:18:40
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 22:18:40
+ * @Description: 替换全局 logger 以及日志断言
+ */
+package logittest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// ReplaceSvrLogger 将 logit.SvrLogger 替换为新的 Observer，测试结束后自动恢复
+//
+//	替换的是全局变量，使用了该方法的测试不应调用 t.Parallel
+func ReplaceSvrLogger(t testing.TB) *Observer {
+	t.Helper()
+	return ReplaceLogger(t, &logit.SvrLogger)
+}
+
+// ReplaceLogger 将 target 指向的 logger 替换为新的 Observer，测试结束后自动恢复
+//
+//	如 ReplaceLogger(t, &logit.DefaultLogger)
+func ReplaceLogger(t testing.TB, target *logit.Logger) *Observer {
+	t.Helper()
+	o := NewObserver()
+	old := *target
+	*target = o
+	t.Cleanup(func() {
+		*target = old
+	})
+	return o
+}
+
+// AssertLogged 断言 logit.SvrLogger 打印过满足条件的日志，需先调用 ReplaceSvrLogger
+//
+//	message 需完全匹配，为空时不比对；keys 为日志中必须包含的字段，传入的字段与 ctx 字段均可
+//	如 AssertLogged(t, logit.WarningLevel, "msg", "requestID")
+//	返回第一条匹配的日志，不存在时标记测试失败并返回 nil
+func AssertLogged(t testing.TB, level logit.Level, message string, keys ...string) *Entry {
+	t.Helper()
+	return svrObserver(t).AssertLogged(t, level, message, keys...)
+}
+
+// AssertNotLogged 断言 logit.SvrLogger 没有打印过指定等级与 message 的日志，需先调用 ReplaceSvrLogger
+func AssertNotLogged(t testing.TB, level logit.Level, message string) {
+	t.Helper()
+	svrObserver(t).AssertNotLogged(t, level, message)
+}
+
+// AssertLogged 断言打印过满足条件的日志，规则同 AssertLogged
+func (o *Observer) AssertLogged(t testing.TB, level logit.Level, message string, keys ...string) *Entry {
+	t.Helper()
+	entries := o.Entries()
+	for _, e := range entries {
+		if e.Level == level && (message == "" || e.Message == message) && e.Has(keys...) {
+			return e
+		}
+	}
+	t.Errorf("no %s log with message %q and fields %v, got:\n%s", level, message, keys, dump(entries))
+	return nil
+}
+
+// AssertNotLogged 断言没有打印过指定等级与 message 的日志，message 为空时表示该等级的任意日志
+func (o *Observer) AssertNotLogged(t testing.TB, level logit.Level, message string) {
+	t.Helper()
+	for _, e := range o.Entries() {
+		if e.Level == level && (message == "" || e.Message == message) {
+			t.Errorf("unexpected %s log at %s: %q", level, e.Caller, e.Message)
+			return
+		}
+	}
+}
+
+func svrObserver(t testing.TB) *Observer {
+	t.Helper()
+	o, ok := logit.SvrLogger.(*Observer)
+	if !ok {
+		t.Fatalf("logit.SvrLogger is %T, call ReplaceSvrLogger first", logit.SvrLogger)
+	}
+	return o
+}
+
+// dump 日志的简要内容，用于断言失败时输出
+func dump(entries []*Entry) string {
+	if len(entries) == 0 {
+		return "\t(none)"
+	}
+	var b strings.Builder
+	for _, e := range entries {
+		keys := make([]string, 0, len(e.Fields)+len(e.ContextFields))
+		for _, f := range e.ContextFields {
+			keys = append(keys, f.Key())
+		}
+		for _, f := range e.Fields {
+			keys = append(keys, f.Key())
+		}
+		fmt.Fprintf(&b, "\t%s %s %q %v\n", e.Level, e.Caller, e.Message, keys)
+	}
+	return b.String()
+}
@@ -0,0 +1,221 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 22:05:12
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 22:05:12
+ * @Description: 单测用的 logger，将日志记录在内存中，便于断言
+ */
+package logittest
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// Entry 一条被记录的日志
+type Entry struct {
+	Time    time.Time
+	Level   logit.Level
+	Message string
+
+	// 调用日志方法的位置，如 xxx/xxx.go:80
+	Caller string
+
+	// 调用时传入的字段
+	Fields []logit.Field
+
+	// 当前等级可见的 ctx 字段以及 meta fields，如 requestID
+	ContextFields []logit.Field
+}
+
+// Field 按 key 查找字段，传入的字段优先于 ctx 字段，不存在时返回 nil
+func (e *Entry) Field(key string) logit.Field {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key() == key {
+			return e.Fields[i]
+		}
+	}
+	for i := len(e.ContextFields) - 1; i >= 0; i-- {
+		if e.ContextFields[i].Key() == key {
+			return e.ContextFields[i]
+		}
+	}
+	return nil
+}
+
+// Has 是否包含全部指定的字段
+func (e *Entry) Has(keys ...string) bool {
+	for _, key := range keys {
+		if e.Field(key) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Value 按 key 查找字段的值，不存在时返回 nil
+func (e *Entry) Value(key string) interface{} {
+	if f := e.Field(key); f != nil {
+		return f.Value()
+	}
+	return nil
+}
+
+// Observer 将日志记录在内存中的 logger，并发安全
+type Observer struct {
+	// 最小日志等级，低于此等级的日志不记录，默认全部记录
+	MinLevel logit.Level
+
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+// NewObserver 创建一个 Observer
+func NewObserver() *Observer {
+	return &Observer{}
+}
+
+// Debug debug
+func (o *Observer) Debug(ctx context.Context, message string, fields ...logit.Field) {
+	o.Output(ctx, logit.DebugLevel, 1, message, fields...)
+}
+
+// Trace Trace
+func (o *Observer) Trace(ctx context.Context, message string, fields ...logit.Field) {
+	o.Output(ctx, logit.TraceLevel, 1, message, fields...)
+}
+
+// Notice Notice
+func (o *Observer) Notice(ctx context.Context, message string, fields ...logit.Field) {
+	o.Output(ctx, logit.NoticeLevel, 1, message, fields...)
+}
+
+// Warning Warning
+func (o *Observer) Warning(ctx context.Context, message string, fields ...logit.Field) {
+	o.Output(ctx, logit.WarningLevel, 1, message, fields...)
+}
+
+// Error Error
+func (o *Observer) Error(ctx context.Context, message string, fields ...logit.Field) {
+	o.Output(ctx, logit.ErrorLevel, 1, message, fields...)
+}
+
+// Fatal Fatal
+func (o *Observer) Fatal(ctx context.Context, message string, fields ...logit.Field) {
+	o.Output(ctx, logit.FatalLevel, 1, message, fields...)
+}
+
+// Output 记录日志，字段会被复制，调用方之后修改不影响已记录的内容
+func (o *Observer) Output(ctx context.Context, level logit.Level, callDepth int, message string, fields ...logit.Field) {
+	if level == logit.UnknownLevel || level >= logit.AllLevels || o.MinLevel > level {
+		return
+	}
+
+	e := &Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: message,
+		Caller:  caller(callDepth + 1),
+		Fields:  append([]logit.Field(nil), fields...),
+	}
+	if ctx != nil {
+		// 与 SimpleLogger 一致：ctx 里的字段只有在当前等级下才可见
+		logit.Range(ctx, func(f logit.Field) error {
+			if f.Level().Is(level) {
+				e.ContextFields = append(e.ContextFields, f)
+			}
+			return nil
+		})
+		// requestID 等字段可能同时存在于 ctx 与 meta fields 中，只记录一次
+		logit.RangeMetaFields(ctx, func(f logit.Field) error {
+			for _, cf := range e.ContextFields {
+				if cf.Key() == f.Key() {
+					return nil
+				}
+			}
+			e.ContextFields = append(e.ContextFields, f)
+			return nil
+		})
+	}
+
+	o.mu.Lock()
+	o.entries = append(o.entries, e)
+	o.mu.Unlock()
+}
+
+// Entries 返回已记录的全部日志
+func (o *Observer) Entries() []*Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*Entry(nil), o.entries...)
+}
+
+// Len 已记录的日志条数
+func (o *Observer) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// TakeAll 返回并清空已记录的日志
+func (o *Observer) TakeAll() []*Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := o.entries
+	o.entries = nil
+	return entries
+}
+
+// Reset 清空已记录的日志
+func (o *Observer) Reset() {
+	o.TakeAll()
+}
+
+// Filter 返回满足条件的日志
+func (o *Observer) Filter(match func(e *Entry) bool) []*Entry {
+	var res []*Entry
+	for _, e := range o.Entries() {
+		if match(e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// FilterLevel 返回指定等级的日志
+func (o *Observer) FilterLevel(level logit.Level) []*Entry {
+	return o.Filter(func(e *Entry) bool {
+		return e.Level == level
+	})
+}
+
+// FilterMessage 返回 message 完全匹配的日志
+func (o *Observer) FilterMessage(message string) []*Entry {
+	return o.Filter(func(e *Entry) bool {
+		return e.Message == message
+	})
+}
+
+// FilterField 返回包含指定字段且值相等的日志
+func (o *Observer) FilterField(f logit.Field) []*Entry {
+	return o.Filter(func(e *Entry) bool {
+		got := e.Field(f.Key())
+		return got != nil && logit.FieldEqual(got, f)
+	})
+}
+
+func caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	return strings.Join([]string{logit.CallerPathClean(file), strconv.Itoa(line)}, ":")
+}
+
+var _ logit.Logger = (*Observer)(nil)
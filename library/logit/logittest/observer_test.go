@@ -0,0 +1,66 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 22:31:27
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 22:31:27
+ * @Description: logittest 用例
+ */
+package logittest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// fakeTB 记录断言失败信息，不让外层测试失败
+type fakeTB struct {
+	testing.TB
+	errs []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func TestReplaceSvrLogger(t *testing.T) {
+	old := logit.SvrLogger
+	t.Run("replace", func(t *testing.T) {
+		o := ReplaceSvrLogger(t)
+		if logit.SvrLogger != o {
+			t.Fatal("SvrLogger should be replaced")
+		}
+	})
+	if logit.SvrLogger != old {
+		t.Error("SvrLogger should be restored after test")
+	}
+}
+
+func TestObserverAssert(t *testing.T) {
+	o := NewObserver()
+	o.MinLevel = logit.NoticeLevel
+	ctx := logit.WithContext(context.Background())
+	logit.SetRequestID(ctx, "42")
+
+	o.Debug(ctx, "dropped")
+	o.Warning(ctx, "slow", logit.Int("cost", 3))
+
+	tb := &fakeTB{TB: t}
+	if e := o.AssertLogged(tb, logit.WarningLevel, "slow", "requestID", "cost"); e == nil || e.Value("cost") != 3 {
+		t.Errorf("unexpected entry %+v, errs %v", e, tb.errs)
+	}
+	if len(tb.errs) != 0 || o.Len() != 1 {
+		t.Fatalf("unexpected failures %v", tb.errs)
+	}
+
+	o.AssertLogged(tb, logit.WarningLevel, "slow", "uid")
+	o.AssertNotLogged(tb, logit.WarningLevel, "")
+	if len(tb.errs) != 2 || !strings.Contains(tb.errs[0], `"slow" [requestID cost]`) {
+		t.Errorf("unexpected failures %q", tb.errs)
+	}
+}
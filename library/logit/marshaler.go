@@ -0,0 +1,78 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 11:05:44
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 10:38:17
+ * @Description: 对象、数组字段的自编码接口，避免使用反射
+ */
+package logit
+
+import (
+	"time"
+)
+
+// ObjectMarshaler 可以将自身编码为日志对象的类型
+//
+//	如请求的 DTO 实现该接口后，可以使用 Object 字段直接打印，不需要经过 json.Marshal
+type ObjectMarshaler interface {
+	MarshalLogObject(enc FieldEncoder) error
+}
+
+// ObjectMarshalerFunc 将一个函数转换为 ObjectMarshaler
+type ObjectMarshalerFunc func(enc FieldEncoder) error
+
+// MarshalLogObject 调用函数本身
+func (f ObjectMarshalerFunc) MarshalLogObject(enc FieldEncoder) error {
+	return f(enc)
+}
+
+// ArrayMarshaler 可以将自身编码为日志数组的类型
+type ArrayMarshaler interface {
+	MarshalLogArray(enc ArrayEncoder) error
+}
+
+// ArrayMarshalerFunc 将一个函数转换为 ArrayMarshaler
+type ArrayMarshalerFunc func(enc ArrayEncoder) error
+
+// MarshalLogArray 调用函数本身
+func (f ArrayMarshalerFunc) MarshalLogArray(enc ArrayEncoder) error {
+	return f(enc)
+}
+
+// ArrayEncoder 数组元素的编码器，与 FieldEncoder 类似，但是元素没有 key
+type ArrayEncoder interface {
+	AppendBool(value bool)
+	AppendByteString(value []byte) // UTF-8 编码的字节
+	AppendDuration(value time.Duration)
+	AppendFloat64(value float64)
+	AppendInt(value int)
+	AppendInt64(value int64)
+	AppendString(value string)
+	AppendTime(value time.Time)
+	AppendUint64(value uint64)
+
+	// 嵌套的对象与数组
+	AppendObject(value ObjectMarshaler) error
+	AppendArray(value ArrayMarshaler) error
+
+	// 任意类型的元素，通过反射编码，较慢且分配较多
+	AppendReflected(value interface{}) error
+}
+
+type stringArray []string
+
+func (ss stringArray) MarshalLogArray(enc ArrayEncoder) error {
+	for i := range ss {
+		enc.AppendString(ss[i])
+	}
+	return nil
+}
+
+type intArray []int
+
+func (is intArray) MarshalLogArray(enc ArrayEncoder) error {
+	for i := range is {
+		enc.AppendInt(is[i])
+	}
+	return nil
+}
@@ -154,6 +154,13 @@ func OptPrefixName(name string) Option {
 	})
 }
 
+// OptRedact 配置选项-追加敏感字段脱敏规则
+func OptRedact(rules ...*RedactRule) Option {
+	return newFuncOption(func(config *Config) {
+		config.Redact = append(config.Redact, rules...)
+	})
+}
+
 // loggerCfg 通过加载 Option 初始化生成一个 Config
 func loggerCfg(opts ...Option) (*Config, error) {
 	cfg := &Config{}
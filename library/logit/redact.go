@@ -0,0 +1,430 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 13:20:05
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 11:44:20
+ * @Description: 敏感字段脱敏，在编码前对字段名、字段值进行打码、哈希或丢弃
+ */
+package logit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/liziwei01/gin-lib/library/utils"
+)
+
+const (
+	// RedactMask 打码，保留首尾，中间替换为 *
+	RedactMask = "mask"
+
+	// RedactHash 替换为 sha256 摘要的前16位，相同的值结果相同，便于关联排查
+	RedactHash = "hash"
+
+	// RedactDrop 丢弃，字段不输出；对于字段值规则，匹配到的内容将被删除
+	RedactDrop = "drop"
+)
+
+// RedactRule 脱敏规则
+type RedactRule struct {
+	// 字段名正则，忽略大小写，如 password|token|authorization
+	// 匹配到的字段，整个字段值按 Strategy 处理
+	Key string
+
+	// 字段值正则，也可以使用内置的 email、phone、idcard
+	// 只对字符串类的字段值生效，匹配到的部分按 Strategy 处理
+	// 若 Key 也不为空，则只处理 Key 匹配到的字段
+	Value string
+
+	// 脱敏策略：mask、hash、drop，默认为 mask
+	Strategy string
+}
+
+// 内置的字段值规则
+var redactValuePatterns = map[string]*redactPattern{
+	"email": {
+		re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	"phone": {
+		re: regexp.MustCompile(`\b(?:86[\- ]?)?1[3-9]\d{9}\b`),
+	},
+	"idcard": {
+		re:    regexp.MustCompile(`\b[1-9]\d{16}[\dXx]\b`),
+		valid: idCardValid,
+	},
+}
+
+type redactPattern struct {
+	re *regexp.Regexp
+
+	// 对正则匹配到的内容再做一次校验，如身份证的校验位，减少误伤
+	valid func(s string) bool
+}
+
+type redactRule struct {
+	key      *regexp.Regexp
+	value    *redactPattern
+	strategy string
+}
+
+// Redactor 字段脱敏器，并发安全
+type Redactor struct {
+	rules []*redactRule
+}
+
+// NewRedactor 根据规则创建脱敏器
+func NewRedactor(rules []*RedactRule) (*Redactor, error) {
+	r := &Redactor{}
+	for idx, rule := range rules {
+		if rule.Key == "" && rule.Value == "" {
+			return nil, fmt.Errorf("redact rule %d: Key and Value are both empty", idx)
+		}
+		rr := &redactRule{
+			strategy: strings.ToLower(rule.Strategy),
+		}
+		switch rr.strategy {
+		case "":
+			rr.strategy = RedactMask
+		case RedactMask, RedactHash, RedactDrop:
+		default:
+			return nil, fmt.Errorf("redact rule %d: unknown Strategy %q", idx, rule.Strategy)
+		}
+		if rule.Key != "" {
+			re, err := regexp.Compile("(?i)" + rule.Key)
+			if err != nil {
+				return nil, fmt.Errorf("redact rule %d: Key %w", idx, err)
+			}
+			rr.key = re
+		}
+		if rule.Value != "" {
+			if p, has := redactValuePatterns[strings.ToLower(rule.Value)]; has {
+				rr.value = p
+			} else {
+				re, err := regexp.Compile(rule.Value)
+				if err != nil {
+					return nil, fmt.Errorf("redact rule %d: Value %w", idx, err)
+				}
+				rr.value = &redactPattern{re: re}
+			}
+		}
+		r.rules = append(r.rules, rr)
+	}
+	return r, nil
+}
+
+// Redact 对字段进行脱敏，返回脱敏后的字段
+// 若返回 false，表示该字段应被丢弃
+func (r *Redactor) Redact(f Field) (Field, bool) {
+	if r == nil || len(r.rules) == 0 || f.Type() == NamespaceType {
+		return f, true
+	}
+	// Defer 字段先取值，字段值规则才能匹配到实际的值，编码时也不会再次取值
+	if f.Type() == DeferType {
+		f = resolveDefer(f)
+	}
+	for _, rule := range r.rules {
+		if rule.key == nil || !rule.key.MatchString(f.Key()) {
+			continue
+		}
+		// 只有字段名规则：处理整个字段
+		if rule.value == nil {
+			if rule.strategy == RedactDrop {
+				return nil, false
+			}
+			return redactField(f, redactValue(rule.strategy, fieldString(f))), true
+		}
+	}
+
+	// 字段值规则处理字符串类与数字类的字段，如以整数记录的手机号
+	// 没有适用的字段值规则时不取值，避免 Reflect 字段多做一次 json 编码
+	if !r.hasValueRule(f.Key()) {
+		return f, true
+	}
+	s, ok := matchValue(f)
+	if !ok {
+		return f, true
+	}
+	out := s
+	for _, rule := range r.rules {
+		if rule.value == nil {
+			continue
+		}
+		if rule.key != nil && !rule.key.MatchString(f.Key()) {
+			continue
+		}
+		out = rule.replace(out)
+	}
+	if out == s {
+		if f.Type() == ReflectType {
+			// 保留 json 编码的结果，编码器不再通过反射编码
+			nf := Reflect(f.Key(), json.RawMessage(s))
+			nf.SetLevel(f.Level())
+			return nf, true
+		}
+		return f, true
+	}
+	return redactField(f, out), true
+}
+
+// hasValueRule 是否有适用于字段 key 的字段值规则
+func (r *Redactor) hasValueRule(key string) bool {
+	for _, rule := range r.rules {
+		if rule.value != nil && (rule.key == nil || rule.key.MatchString(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString 使用字段值规则对字符串脱敏，如日志的 message
+func (r *Redactor) RedactString(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.rules {
+		if rule.value != nil && rule.key == nil {
+			s = rule.replace(s)
+		}
+	}
+	return s
+}
+
+func (rule *redactRule) replace(s string) string {
+	return rule.value.re.ReplaceAllStringFunc(s, func(m string) string {
+		if rule.value.valid != nil && !rule.value.valid(m) {
+			return m
+		}
+		if rule.strategy == RedactDrop {
+			return ""
+		}
+		return redactValue(rule.strategy, m)
+	})
+}
+
+// redactField 使用脱敏后的值创建一个新的字段，保留原字段的日志等级
+func redactField(f Field, value string) Field {
+	nf := String(f.Key(), value)
+	nf.SetLevel(f.Level())
+	return nf
+}
+
+func redactValue(strategy string, s string) string {
+	if strategy == RedactHash {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])[:16]
+	}
+	return mask(s)
+}
+
+// mask 保留首尾各 1/4 的字符，其余替换为 *
+func mask(s string) string {
+	n := utf8.RuneCountInString(s)
+	if n == 0 {
+		return s
+	}
+	keep := n / 4
+	var b strings.Builder
+	b.Grow(len(s))
+	i := 0
+	for _, c := range s {
+		if i < keep || i >= n-keep {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('*')
+		}
+		i++
+	}
+	return b.String()
+}
+
+// stringValue 字符串类字段的值
+func stringValue(f Field) (string, bool) {
+	switch f.Type() {
+	case StringType:
+		return f.Value().(string), true
+	case ByteStringType, BinaryType:
+		return string(f.Value().([]byte)), true
+	case ErrorType:
+		if err, ok := f.Value().(error); ok && err != nil {
+			return err.Error(), true
+		}
+	case ReflectType:
+		b, err := json.Marshal(f.Value())
+		if err == nil {
+			return string(b), true
+		}
+	}
+	return "", false
+}
+
+// matchValue 字段值规则匹配的文本：字符串类字段的值，数字格式化为十进制
+func matchValue(f Field) (string, bool) {
+	if s, ok := stringValue(f); ok {
+		return s, true
+	}
+	switch f.Type() {
+	case IntType, Int64Type, Int32Type, Int16Type, Int8Type, UintType, Uint64Type, Uint32Type, Uint16Type, Uint8Type:
+		return fmt.Sprint(f.Value()), true
+	case Float64Type:
+		return strconv.FormatFloat(f.Value().(float64), 'f', -1, 64), true
+	case Float32Type:
+		return strconv.FormatFloat(float64(f.Value().(float32)), 'f', -1, 32), true
+	}
+	return "", false
+}
+
+// resolveDefer 取出 Defer 字段的值，保留原字段的日志等级
+func resolveDefer(f Field) Field {
+	fn := f.Value().(func() interface{})
+	nf := AutoField(f.Key(), fn())
+	nf.SetLevel(f.Level())
+	return nf
+}
+
+// fieldString 将任意字段的值转换为字符串
+func fieldString(f Field) string {
+	if s, ok := matchValue(f); ok {
+		return s
+	}
+	switch f.Type() {
+	case DeferType:
+		return fieldString(resolveDefer(f))
+	case ObjectMarshalerType:
+		obj, _ := jsonObject(f.Value().(ObjectMarshaler))
+		b, _ := json.Marshal(obj)
+		return string(b)
+	case ArrayMarshalerType:
+		arr, _ := jsonArray(f.Value().(ArrayMarshaler))
+		b, _ := json.Marshal(arr)
+		return string(b)
+	case ErrorType:
+		return "nil"
+	}
+	return fmt.Sprint(f.Value())
+}
+
+// idCardValid 校验18位身份证号的校验位
+func idCardValid(id string) bool {
+	if len(id) != 18 {
+		return false
+	}
+	for i := 0; i < 17; i++ {
+		if id[i] < '0' || id[i] > '9' {
+			return false
+		}
+	}
+	last := id[17]
+	if last == 'x' {
+		last = 'X'
+	}
+	return utils.CreditChecksum(id) == last
+}
+
+// redactEncoder 在字段写入编码器前进行脱敏
+// 嵌套的对象、数组同样会经过脱敏
+type redactEncoder struct {
+	FieldEncoder
+	r *Redactor
+}
+
+func (e *redactEncoder) add(f Field) {
+	f, ok := e.r.Redact(f)
+	if !ok {
+		return
+	}
+	switch f.Type() {
+	case ObjectMarshalerType:
+		m := f.Value().(ObjectMarshaler)
+		_ = e.FieldEncoder.AddObject(f.Key(), ObjectMarshalerFunc(func(enc FieldEncoder) error {
+			return m.MarshalLogObject(&redactEncoder{FieldEncoder: enc, r: e.r})
+		}))
+	case ArrayMarshalerType:
+		m := f.Value().(ArrayMarshaler)
+		_ = e.FieldEncoder.AddArray(f.Key(), ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+			return m.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: e.r})
+		}))
+	case ErrorType:
+		// 错误信息未被脱敏时才展开，额外的字段同样经过脱敏
+		// 被脱敏的错误已转换为字符串，不再输出错误链与调用栈
+		value, _ := f.Value().(error)
+		e.FieldEncoder.AddError(f.Key(), value)
+		addErrorDetail(e, f.Key(), value)
+	default:
+		FieldAddToEncoder(f, e.FieldEncoder)
+	}
+}
+
+func (e *redactEncoder) AddBinary(key string, value []byte)     { e.add(Binary(key, value)) }
+func (e *redactEncoder) AddBool(key string, value bool)         { e.add(Bool(key, value)) }
+func (e *redactEncoder) AddByteString(key string, value []byte) { e.add(ByteString(key, value)) }
+func (e *redactEncoder) AddDuration(key string, value time.Duration) {
+	e.add(Duration(key, value))
+}
+func (e *redactEncoder) AddFloat64(key string, value float64) { e.add(Float64(key, value)) }
+func (e *redactEncoder) AddFloat32(key string, value float32) { e.add(Float32(key, value)) }
+func (e *redactEncoder) AddInt(key string, value int)         { e.add(Int(key, value)) }
+func (e *redactEncoder) AddInt64(key string, value int64)     { e.add(Int64(key, value)) }
+func (e *redactEncoder) AddInt32(key string, value int32)     { e.add(Int32(key, value)) }
+func (e *redactEncoder) AddInt16(key string, value int16)     { e.add(Int16(key, value)) }
+func (e *redactEncoder) AddInt8(key string, value int8)       { e.add(Int8(key, value)) }
+func (e *redactEncoder) AddString(key, value string)          { e.add(String(key, value)) }
+func (e *redactEncoder) AddTime(key string, value time.Time)  { e.add(Time(key, value)) }
+func (e *redactEncoder) AddUint(key string, value uint)       { e.add(Uint(key, value)) }
+func (e *redactEncoder) AddUint64(key string, value uint64)   { e.add(Uint64(key, value)) }
+func (e *redactEncoder) AddUint32(key string, value uint32)   { e.add(Uint32(key, value)) }
+func (e *redactEncoder) AddUint16(key string, value uint16)   { e.add(Uint16(key, value)) }
+func (e *redactEncoder) AddUint8(key string, value uint8)     { e.add(Uint8(key, value)) }
+func (e *redactEncoder) AddUintptr(key string, value uintptr) { e.add(Uintptr(key, value)) }
+func (e *redactEncoder) AddError(key string, value error)     { e.add(Error(key, value)) }
+
+func (e *redactEncoder) AddReflected(key string, value interface{}) error {
+	e.add(Reflect(key, value))
+	return nil
+}
+
+func (e *redactEncoder) AddObject(key string, value ObjectMarshaler) error {
+	e.add(Object(key, value))
+	return nil
+}
+
+func (e *redactEncoder) AddArray(key string, value ArrayMarshaler) error {
+	e.add(Array(key, value))
+	return nil
+}
+
+var _ FieldEncoder = (*redactEncoder)(nil)
+
+// redactArrayEncoder 数组元素没有字段名，只应用字段值规则
+type redactArrayEncoder struct {
+	ArrayEncoder
+	r *Redactor
+}
+
+func (a *redactArrayEncoder) AppendString(value string) {
+	a.ArrayEncoder.AppendString(a.r.RedactString(value))
+}
+
+func (a *redactArrayEncoder) AppendByteString(value []byte) {
+	a.ArrayEncoder.AppendString(a.r.RedactString(string(value)))
+}
+
+func (a *redactArrayEncoder) AppendObject(value ObjectMarshaler) error {
+	return a.ArrayEncoder.AppendObject(ObjectMarshalerFunc(func(enc FieldEncoder) error {
+		return value.MarshalLogObject(&redactEncoder{FieldEncoder: enc, r: a.r})
+	}))
+}
+
+func (a *redactArrayEncoder) AppendArray(value ArrayMarshaler) error {
+	return a.ArrayEncoder.AppendArray(ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+		return value.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: a.r})
+	}))
+}
+
+var _ ArrayEncoder = (*redactArrayEncoder)(nil)
@@ -0,0 +1,103 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 14:02:51
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 11:31:54
+ * @Description: 敏感字段脱敏用例
+ */
+package logit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	r, err := NewRedactor([]*RedactRule{
+		{Key: "password|token", Strategy: RedactDrop},
+		{Key: "^secret$", Strategy: RedactHash},
+		{Value: "email"},
+		{Value: "idcard", Strategy: RedactHash},
+		{Value: "phone"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	l := &SimpleLogger{
+		Writer:      buf,
+		EncoderPool: DefaultTextEncoderPool,
+		Redactor:    r,
+	}
+
+	ctx := WithContext(context.Background())
+	AddNotice(ctx, String("Authorization-Token", "abc"))
+	AddMetaFields(ctx, String("contact", "mail zhangsan@163.com please"))
+	l.Notice(ctx, "call 13812345678",
+		String("password", "123456"),
+		Int("secret", 42),
+		String("idcard", "11010519491231002X"),
+		String("notIDCard", "110105194912310021"),
+		Int64("mobile", 13912345678),
+		Defer("mail", func() interface{} { return "lisi@163.com" }),
+		Object("user", ObjectMarshalerFunc(func(enc FieldEncoder) error {
+			enc.AddString("token", "abc")
+			enc.AddString("phone", "+86 13812345678")
+			return nil
+		})),
+	)
+
+	line := buf.String()
+	for _, bad := range []string{"Authorization-Token", "password", "zhangsan@163.com", "13812345678", "11010519491231002X", "secret[42]", "user.token", "13912345678", "lisi@163.com"} {
+		if strings.Contains(line, bad) {
+			t.Errorf("%q should be redacted: %q", bad, line)
+		}
+	}
+	for _, want := range []string{"contact[mail zhan********.com please]", "notIDCard[110105194912310021]", "message[call 13*******78]", "user.phone[", "mobile[13*******78]", "mail[lis******com]"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+}
+
+type countMarshaler struct {
+	n *int
+}
+
+func (c countMarshaler) MarshalJSON() ([]byte, error) {
+	*c.n++
+	return []byte(`{"phone":"13812345678"}`), nil
+}
+
+// Reflect 字段只在有字段值规则时 json 编码一次，编码结果由编码器复用
+func TestRedactReflect(t *testing.T) {
+	n := 0
+	v := countMarshaler{n: &n}
+	keyOnly, _ := NewRedactor([]*RedactRule{{Key: "password", Strategy: RedactDrop}})
+	if f, _ := keyOnly.Redact(Reflect("user", v)); n != 0 || f.Value() != v {
+		t.Errorf("key rules should not marshal, marshaled %d times", n)
+	}
+
+	r, _ := NewRedactor([]*RedactRule{{Key: "^order$", Value: "phone"}})
+	f, _ := r.Redact(Reflect("user", v))
+	enc := NewTextEncoder(TexEncoderOption{})
+	f.AddTo(enc)
+	if n != 1 || f.Type() != ReflectType || !strings.Contains(enc.buf.String(), "13812345678") {
+		t.Errorf("unexpected %d marshals %q", n, enc.buf.String())
+	}
+	f, _ = r.Redact(Reflect("order", v))
+	if n != 2 || f.Value() != `{"phone":"13*******78"}` {
+		t.Errorf("unexpected %d marshals %v", n, f.Value())
+	}
+}
+
+func TestRedactRuleInvalid(t *testing.T) {
+	if _, err := NewRedactor([]*RedactRule{{Strategy: RedactMask}}); err == nil {
+		t.Error("empty rule should fail")
+	}
+	if _, err := NewRedactor([]*RedactRule{{Key: "a", Strategy: "unknown"}}); err == nil {
+		t.Error("unknown strategy should fail")
+	}
+}
@@ -0,0 +1,378 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 21:03:27
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 12:03:26
+ * @Description: 全局唯一的 requestID 生成器：UUIDv7、ULID、Snowflake，可在配置中选择
+ */
+package logit
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/env"
+)
+
+const (
+	// RequestIDUint32 默认的生成器，见 NewRequestIDUint32，跨进程可能重复
+	RequestIDUint32 = "uint32"
+
+	// RequestIDUUIDv7 RFC 9562 UUIDv7，如 01890a5d-ac96-774b-bcce-b302099a8057
+	RequestIDUUIDv7 = "uuidv7"
+
+	// RequestIDULID 26位 Crockford base32 编码的 ULID，如 01ARZ3NDEKTSV4RRFFQ69G5FAV
+	RequestIDULID = "ulid"
+
+	// RequestIDSnowflake 十进制的 Snowflake ID：41位毫秒时间戳 + 10位机器ID + 12位序列号
+	RequestIDSnowflake = "snowflake"
+)
+
+// requestIDGenerators 已注册的生成器
+var requestIDGenerators = map[string]func() string{
+	RequestIDUint32: func() string {
+		return strconv.FormatUint(uint64(NewRequestIDUint32()), 10)
+	},
+	RequestIDUUIDv7:    NewUUIDv7,
+	RequestIDULID:      NewULID,
+	RequestIDSnowflake: NewSnowflakeID,
+}
+
+var requestIDMux sync.RWMutex
+
+// RegisterRequestIDGenerator 注册自定义的 requestID 生成器，name 不区分大小写
+func RegisterRequestIDGenerator(name string, fn func() string) error {
+	name = strings.ToLower(name)
+	requestIDMux.Lock()
+	defer requestIDMux.Unlock()
+	if _, has := requestIDGenerators[name]; has {
+		return fmt.Errorf("request id generator %q already exists", name)
+	}
+	requestIDGenerators[name] = fn
+	return nil
+}
+
+// SetRequestIDGenerator 使用指定名称的生成器替换 NewRequestID，name 为空时不做修改
+//
+//	需要在程序启动时调用，之后不应再修改
+func SetRequestIDGenerator(name string) error {
+	if name == "" {
+		return nil
+	}
+	requestIDMux.RLock()
+	fn, has := requestIDGenerators[strings.ToLower(name)]
+	requestIDMux.RUnlock()
+	if !has {
+		return fmt.Errorf("request id generator %q not found", name)
+	}
+	// 启动时检查环境变量中的机器ID，配置错误时不再等到第一个请求
+	if strings.ToLower(name) == RequestIDSnowflake {
+		if err := initSnowflakeMachineID(); err != nil {
+			return err
+		}
+	}
+	NewRequestID = fn
+	return nil
+}
+
+// monotonicClock 进程内单调的毫秒时钟
+//
+//	同一毫秒内由调用方递增序列号；序列号用尽或系统时间回拨时，逻辑时间在上一次的基础上 +1ms
+type monotonicClock struct {
+	mu     sync.Mutex
+	lastMs int64
+}
+
+// next 返回本次使用的毫秒时间戳，以及是否与上一次处于同一毫秒
+func (c *monotonicClock) next() (int64, bool) {
+	ms := now().UnixMilli()
+	if ms <= c.lastMs {
+		return c.lastMs, true
+	}
+	c.lastMs = ms
+	return ms, false
+}
+
+// bump 序列号用尽，逻辑时间前进 1ms
+func (c *monotonicClock) bump() int64 {
+	c.lastMs++
+	return c.lastMs
+}
+
+func randomBytes(b []byte) {
+	if _, err := crand.Read(b); err != nil {
+		// 极端情况下随机数不可用，退化为时间
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(time.Now().UnixNano()))
+	}
+}
+
+var uuidv7State struct {
+	monotonicClock
+	// rand_a 的12位作为序列号
+	seq uint16
+}
+
+// NewUUIDv7 生成 UUIDv7，同一进程内严格递增
+//
+//	48位毫秒时间戳 + 4位版本 + 12位序列号 + 2位变体 + 62位随机数
+func NewUUIDv7() string {
+	var u [16]byte
+	randomBytes(u[6:])
+
+	s := &uuidv7State
+	s.mu.Lock()
+	ms, same := s.next()
+	if same {
+		s.seq++
+		if s.seq > 0xFFF {
+			ms = s.bump()
+			s.seq = 0
+		}
+	} else {
+		// 新的毫秒从较小的随机值开始，保留递增空间
+		s.seq = uint16(u[6]&0x07)<<8 | uint16(u[7])
+	}
+	seq := s.seq
+	s.mu.Unlock()
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = 0x70 | byte(seq>>8)
+	u[7] = byte(seq)
+	u[8] = u[8]&0x3F | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// ParseUUIDv7Time 解析 UUIDv7 中的时间
+func ParseUUIDv7Time(id string) (time.Time, error) {
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return time.Time{}, fmt.Errorf("invalid uuid %q", id)
+	}
+	b, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid uuid %q: %w", id, err)
+	}
+	if b[6]>>4 != 7 {
+		return time.Time{}, fmt.Errorf("uuid %q is not version 7", id)
+	}
+	var ms int64
+	for _, v := range b[:6] {
+		ms = ms<<8 | int64(v)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// crockford Crockford base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidState struct {
+	monotonicClock
+	// 上一次的80位随机数
+	entropy [10]byte
+}
+
+// NewULID 生成 ULID，同一进程内严格递增
+//
+//	48位毫秒时间戳 + 80位随机数，同一毫秒内随机数部分 +1
+func NewULID() string {
+	var u [16]byte
+
+	s := &ulidState
+	s.mu.Lock()
+	ms, same := s.next()
+	if same && !incEntropy(&s.entropy) {
+		ms = s.bump()
+		same = false
+	}
+	if !same {
+		randomBytes(s.entropy[:])
+		// 最高位清零，保留递增空间
+		s.entropy[0] &= 0x7F
+	}
+	copy(u[6:], s.entropy[:])
+	s.mu.Unlock()
+
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+
+	// 128位按5位一组编码为26个字符，首字符只有3位
+	var buf [26]byte
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// incEntropy 随机数部分 +1，溢出时返回 false
+func incEntropy(e *[10]byte) bool {
+	for i := len(e) - 1; i >= 0; i-- {
+		e[i]++
+		if e[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseULIDTime 解析 ULID 中的时间，大小写不敏感
+func ParseULIDTime(id string) (time.Time, error) {
+	if len(id) != 26 {
+		return time.Time{}, fmt.Errorf("invalid ulid %q", id)
+	}
+	id = strings.ToUpper(id)
+	if id[0] > '7' {
+		return time.Time{}, fmt.Errorf("invalid ulid %q: overflow", id)
+	}
+	// 前10个字符为50位，其中高2位恒为0，即48位时间戳
+	var ms int64
+	for i := 0; i < 10; i++ {
+		v := strings.IndexByte(crockford, id[i])
+		if v < 0 {
+			return time.Time{}, fmt.Errorf("invalid ulid %q", id)
+		}
+		ms = ms<<5 | int64(v)
+	}
+	for i := 10; i < 26; i++ {
+		if strings.IndexByte(crockford, id[i]) < 0 {
+			return time.Time{}, fmt.Errorf("invalid ulid %q", id)
+		}
+	}
+	return time.UnixMilli(ms), nil
+}
+
+const (
+	snowflakeMachineBits = 10
+	snowflakeSeqBits     = 12
+	snowflakeMaxSeq      = 1<<snowflakeSeqBits - 1
+	snowflakeMaxMachine  = 1<<snowflakeMachineBits - 1
+
+	// SnowflakeMachineIDEnv 指定机器ID的环境变量，取值 0~1023
+	SnowflakeMachineIDEnv = "SNOWFLAKE_MACHINE_ID"
+)
+
+// SnowflakeEpoch Snowflake 时间戳的起点，2020-01-01 00:00:00 UTC，可使用约69年
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var snowflakeState struct {
+	monotonicClock
+	seq       int64
+	machineID int64
+	err       error
+	once      sync.Once
+}
+
+// SnowflakeMachineID 当前进程的机器ID，取值 0~1023
+//
+//	优先使用环境变量 SNOWFLAKE_MACHINE_ID，未设置时由主机名与本机IP哈希得到，
+//	哈希只有10位，几十台机器就可能重复，多实例部署时应为每个实例指定不同的值
+func SnowflakeMachineID() int64 {
+	initSnowflakeMachineID()
+	return snowflakeState.machineID
+}
+
+// initSnowflakeMachineID 初始化机器ID，环境变量的值无效时返回错误并使用哈希值
+func initSnowflakeMachineID() error {
+	s := &snowflakeState
+	s.once.Do(func() {
+		if v := os.Getenv(SnowflakeMachineIDEnv); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err == nil && id >= 0 && id <= snowflakeMaxMachine {
+				s.machineID = id
+				return
+			}
+			s.err = fmt.Errorf("invalid %s %q, must be 0~%d", SnowflakeMachineIDEnv, v, snowflakeMaxMachine)
+		}
+		hostname, _ := os.Hostname()
+		h := fnv.New32a()
+		h.Write([]byte(hostname))
+		h.Write([]byte{'|'})
+		h.Write([]byte(env.LocalIP()))
+		s.machineID = int64(h.Sum32() & snowflakeMaxMachine)
+	})
+	return s.err
+}
+
+// NewSnowflakeID 生成十进制的 Snowflake ID，同一进程内严格递增
+func NewSnowflakeID() string {
+	machineID := SnowflakeMachineID()
+	epoch := SnowflakeEpoch.UnixMilli()
+
+	s := &snowflakeState
+	s.mu.Lock()
+	ms, same := s.next()
+	if same {
+		s.seq++
+		if s.seq > snowflakeMaxSeq {
+			ms = s.bump()
+			s.seq = 0
+		}
+	} else {
+		s.seq = 0
+	}
+	seq := s.seq
+	s.mu.Unlock()
+
+	id := (ms-epoch)<<(snowflakeMachineBits+snowflakeSeqBits) | machineID<<snowflakeSeqBits | seq
+	return strconv.FormatInt(id, 10)
+}
+
+// ParseSnowflakeID 解析 Snowflake ID 中的时间、机器ID与序列号
+func ParseSnowflakeID(id string) (t time.Time, machineID int64, seq int64, err error) {
+	v, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || v < 0 {
+		return time.Time{}, 0, 0, fmt.Errorf("invalid snowflake id %q", id)
+	}
+	seq = v & snowflakeMaxSeq
+	machineID = v >> snowflakeSeqBits & snowflakeMaxMachine
+	ms := v>>(snowflakeMachineBits+snowflakeSeqBits) + SnowflakeEpoch.UnixMilli()
+	return time.UnixMilli(ms), machineID, seq, nil
+}
+
+// RequestIDTime 根据格式自动识别 requestID 的类型并解析其中的时间
+//
+//	支持 UUIDv7、ULID 与 Snowflake，默认的 uint32 格式不包含完整时间，会返回错误
+func RequestIDTime(id string) (time.Time, error) {
+	switch len(id) {
+	case 36:
+		return ParseUUIDv7Time(id)
+	case 26:
+		return ParseULIDTime(id)
+	}
+	// uint32 最多10位，Snowflake 至少在 2020 年后的数秒内就超过10位
+	if len(id) > 10 {
+		t, _, _, err := ParseSnowflakeID(id)
+		return t, err
+	}
+	return time.Time{}, fmt.Errorf("request id %q has no timestamp", id)
+}
@@ -0,0 +1,143 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 21:48:05
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 12:03:26
+ * @Description: requestID 生成器用例
+ */
+package logit
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRequestIDGenerators(t *testing.T) {
+	uuidRe := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidRe := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+	cases := []struct {
+		name   string
+		gen    func() string
+		format *regexp.Regexp
+		less   func(a, b string) bool
+	}{
+		{RequestIDUUIDv7, NewUUIDv7, uuidRe, func(a, b string) bool { return a < b }},
+		{RequestIDULID, NewULID, ulidRe, func(a, b string) bool { return a < b }},
+		{RequestIDSnowflake, NewSnowflakeID, regexp.MustCompile(`^\d+$`), func(a, b string) bool {
+			return len(a) < len(b) || (len(a) == len(b) && a < b)
+		}},
+	}
+	for _, c := range cases {
+		before := time.Now().Add(-time.Millisecond)
+		// 同一毫秒内大量生成，覆盖序列号用尽的情况
+		ids := make([]string, 10000)
+		for i := range ids {
+			ids[i] = c.gen()
+		}
+		for i, id := range ids {
+			if !c.format.MatchString(id) {
+				t.Fatalf("%s: bad format %q", c.name, id)
+			}
+			if i > 0 && !c.less(ids[i-1], id) {
+				t.Fatalf("%s: not monotonic %q >= %q", c.name, ids[i-1], id)
+			}
+		}
+		ts, err := RequestIDTime(ids[0])
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if ts.Before(before) || ts.After(time.Now().Add(time.Second)) {
+			t.Errorf("%s: unexpected time %v", c.name, ts)
+		}
+	}
+
+	_, machineID, _, err := ParseSnowflakeID(NewSnowflakeID())
+	if err != nil || machineID != SnowflakeMachineID() {
+		t.Errorf("machine id mismatch: %d %d %v", machineID, SnowflakeMachineID(), err)
+	}
+	if _, err := RequestIDTime("123456"); err == nil {
+		t.Error("uint32 request id has no timestamp")
+	}
+	if _, err := ParseULIDTime("8ZZZZZZZZZZZZZZZZZZZZZZZZZ"); err == nil {
+		t.Error("overflowed ulid should fail")
+	}
+}
+
+func TestRequestIDConcurrent(t *testing.T) {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[string]bool)
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]string, 0, 3000)
+			for j := 0; j < 1000; j++ {
+				local = append(local, NewUUIDv7(), NewULID(), NewSnowflakeID())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range local {
+				if seen[id] {
+					t.Errorf("duplicate id %q", id)
+				}
+				seen[id] = true
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSnowflakeMachineIDEnv(t *testing.T) {
+	reset := func() {
+		snowflakeState.once = sync.Once{}
+		snowflakeState.err = nil
+	}
+	reset()
+	t.Cleanup(reset)
+
+	t.Setenv(SnowflakeMachineIDEnv, "1023")
+	if id := SnowflakeMachineID(); id != 1023 {
+		t.Errorf("want machine id from env, got %d", id)
+	}
+	_, machineID, _, err := ParseSnowflakeID(NewSnowflakeID())
+	if err != nil || machineID != 1023 {
+		t.Errorf("unexpected machine id %d %v", machineID, err)
+	}
+
+	reset()
+	t.Setenv(SnowflakeMachineIDEnv, "1024")
+	if err := SetRequestIDGenerator(RequestIDSnowflake); err == nil {
+		t.Error("out of range machine id should fail")
+	}
+}
+
+func TestSetRequestIDGenerator(t *testing.T) {
+	old := NewRequestID
+	defer func() { NewRequestID = old }()
+
+	if err := SetRequestIDGenerator("ULID"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseULIDTime(NewRequestID()); err != nil {
+		t.Error(err)
+	}
+	if err := SetRequestIDGenerator("unknown"); err == nil {
+		t.Error("unknown generator should fail")
+	}
+	if err := RegisterRequestIDGenerator(RequestIDUUIDv7, NewUUIDv7); err == nil {
+		t.Error("duplicate generator should fail")
+	}
+	// 自定义生成器的名称不区分大小写
+	if err := RegisterRequestIDGenerator("TraceID", func() string { return "trace" }); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRequestIDGenerator("TraceID"); err != nil || NewRequestID() != "trace" {
+		t.Errorf("custom generator not selected: %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 16:31:02
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 09:12:36
+ * @Description: 最近日志的内存环形缓冲，可通过 MultiLogger 挂载，配合 ring_http.go 在线查询
+ */
+package logit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRingMaxLines 默认最多保留的日志行数
+	DefaultRingMaxLines = 10000
+
+	// DefaultRingMaxBytes 默认最多占用的内存，按日志内容的字节数计算
+	DefaultRingMaxBytes = 8 << 20
+
+	// ringSubscriberBuffer 每个订阅者的队列长度，消费不及时的日志将被丢弃
+	ringSubscriberBuffer = 256
+)
+
+// RingOptions NewRingLogger 的配置
+type RingOptions struct {
+	// 最多保留的日志行数，<=0 时使用 DefaultRingMaxLines
+	MaxLines int
+
+	// 最多占用的字节数，<=0 时使用 DefaultRingMaxBytes
+	MaxBytes int
+
+	// 编码器池，为nil时使用 DefaultTextEncoderPool
+	EncoderPool EncoderPool
+
+	// 敏感字段脱敏，查询接口会将日志暴露出去，建议与文件日志使用相同的规则
+	Redactor *Redactor
+}
+
+// RingEntry 一条缓冲的日志
+type RingEntry struct {
+	// 自增序号，可用于 SSE 的断点续传
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Level     Level     `json:"-"`
+	LevelName string    `json:"level"`
+	RequestID string    `json:"requestID,omitempty"`
+	Line      string    `json:"line"`
+}
+
+// RingLogger 将日志保存在内存中，超出行数或字节数限制时丢弃最早的日志
+//
+//	一般通过 MultiLogger(SvrLogger, ring) 挂载到已有的 logger 上
+type RingLogger struct {
+	formatter *SimpleLogger
+	maxLines  int
+	maxBytes  int
+
+	mu sync.RWMutex
+	// entries[start:] 为有效的日志
+	entries []*RingEntry
+	start   int
+	bytes   int
+	seq     uint64
+	subs    map[*ringSubscriber]struct{}
+}
+
+type ringSubscriber struct {
+	filter *RingFilter
+	ch     chan *RingEntry
+}
+
+// NewRingLogger 创建一个内存环形缓冲 logger，opts 可以为nil
+func NewRingLogger(opts *RingOptions) *RingLogger {
+	if opts == nil {
+		opts = &RingOptions{}
+	}
+	r := &RingLogger{
+		maxLines: opts.MaxLines,
+		maxBytes: opts.MaxBytes,
+		subs:     make(map[*ringSubscriber]struct{}),
+	}
+	if r.maxLines <= 0 {
+		r.maxLines = DefaultRingMaxLines
+	}
+	if r.maxBytes <= 0 {
+		r.maxBytes = DefaultRingMaxBytes
+	}
+	r.formatter = &SimpleLogger{
+		PrefixFunc:  DefaultPrefixFunc,
+		EncoderPool: opts.EncoderPool,
+		Redactor:    opts.Redactor,
+	}
+	if r.formatter.EncoderPool == nil {
+		r.formatter.EncoderPool = DefaultTextEncoderPool
+	}
+	return r
+}
+
+// lineWriter 接收 formatter 编码好的一行日志，每次 Output 使用一个
+type lineWriter struct {
+	line []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.line = p
+	return len(p), nil
+}
+
+func (r *RingLogger) Debug(ctx context.Context, message string, fields ...Field) {
+	r.Output(ctx, DebugLevel, 1, message, fields...)
+}
+
+func (r *RingLogger) Trace(ctx context.Context, message string, fields ...Field) {
+	r.Output(ctx, TraceLevel, 1, message, fields...)
+}
+
+func (r *RingLogger) Notice(ctx context.Context, message string, fields ...Field) {
+	r.Output(ctx, NoticeLevel, 1, message, fields...)
+}
+
+func (r *RingLogger) Warning(ctx context.Context, message string, fields ...Field) {
+	r.Output(ctx, WarningLevel, 1, message, fields...)
+}
+
+func (r *RingLogger) Error(ctx context.Context, message string, fields ...Field) {
+	r.Output(ctx, ErrorLevel, 1, message, fields...)
+}
+
+func (r *RingLogger) Fatal(ctx context.Context, message string, fields ...Field) {
+	r.Output(ctx, FatalLevel, 1, message, fields...)
+}
+
+func (r *RingLogger) Output(ctx context.Context, level Level, callDepth int, message string, fields ...Field) {
+	if level == UnknownLevel || level >= AllLevels {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// 还在请求级别缓冲中的日志不记录，Flush 时会再次写入
+	if tb := FindTailBuffer(ctx); tb != nil && tb.buffering(level) {
+		return
+	}
+
+	// 在锁外编码，Defer 字段的函数中打印日志时不会死锁
+	w := &lineWriter{}
+	formatter := *r.formatter
+	formatter.Writer = w
+	formatter.Output(ctx, level, callDepth+1, message, fields...)
+	if len(w.line) == 0 {
+		return
+	}
+
+	e := &RingEntry{
+		Time:      now(),
+		Level:     level,
+		LevelName: level.String(),
+		Line:      strings.TrimRight(string(w.line), "\n"),
+	}
+	if f := FindRequestIDField(ctx); f != nil {
+		if id, ok := f.Value().(string); ok {
+			e.RequestID = id
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	e.Seq = r.seq
+	r.append(e)
+
+	for s := range r.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+		}
+	}
+}
+
+// append 写入一条日志并淘汰超出限制的旧日志，调用方需持有写锁
+func (r *RingLogger) append(e *RingEntry) {
+	r.entries = append(r.entries, e)
+	r.bytes += len(e.Line)
+	for len(r.entries)-r.start > 1 && (len(r.entries)-r.start > r.maxLines || r.bytes > r.maxBytes) {
+		r.bytes -= len(r.entries[r.start].Line)
+		r.entries[r.start] = nil
+		r.start++
+	}
+	// 已淘汰的部分超过一半时整理一次，避免底层数组无限增长
+	if r.start > len(r.entries)/2 {
+		n := copy(r.entries, r.entries[r.start:])
+		for i := n; i < len(r.entries); i++ {
+			r.entries[i] = nil
+		}
+		r.entries = r.entries[:n]
+		r.start = 0
+	}
+}
+
+// Len 当前保留的日志行数
+func (r *RingLogger) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.entries) - r.start
+}
+
+// Query 查询符合条件的日志，按时间正序返回最新的 limit 条
+//
+//	filter 为nil时不过滤，limit<=0 时返回全部
+func (r *RingLogger) Query(filter *RingFilter, limit int) []*RingEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*RingEntry
+	for i := len(r.entries) - 1; i >= r.start; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		if filter.Match(r.entries[i]) {
+			result = append(result, r.entries[i])
+		}
+	}
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// Subscribe 订阅之后写入的、符合条件的日志
+//
+//	消费不及时时日志会被丢弃；调用 cancel 后 channel 将被关闭
+func (r *RingLogger) Subscribe(filter *RingFilter) (<-chan *RingEntry, func()) {
+	s := &ringSubscriber{
+		filter: filter,
+		ch:     make(chan *RingEntry, ringSubscriberBuffer),
+	}
+	r.mu.Lock()
+	r.subs[s] = struct{}{}
+	r.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.subs, s)
+			r.mu.Unlock()
+			close(s.ch)
+		})
+	}
+	return s.ch, cancel
+}
+
+var _ Logger = (*RingLogger)(nil)
+
+// RingOf 获取 logger 上挂载的 RingLogger，没有时返回nil
+//
+//	如配置了 Ring 的 SvrLogger：logit.RingOf(logit.SvrLogger)
+func RingOf(l Logger) *RingLogger {
+	switch v := l.(type) {
+	case *RingLogger:
+		return v
+	case *multiLogger:
+		for _, item := range v.loggers {
+			if r := RingOf(item); r != nil {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// RingFilter 日志过滤条件，零值的条件不生效
+type RingFilter struct {
+	// 日志等级，可以使用 | 组合
+	Levels Level
+
+	RequestID string
+
+	// 时间范围，[Start, End]
+	Start time.Time
+	End   time.Time
+
+	// 日志内容包含的子串
+	Contains string
+
+	// 只返回序号大于该值的日志
+	AfterSeq uint64
+}
+
+// Match 日志是否符合过滤条件，nil 匹配所有日志
+func (f *RingFilter) Match(e *RingEntry) bool {
+	if f == nil {
+		return true
+	}
+	if f.Levels != 0 && f.Levels&e.Level == 0 {
+		return false
+	}
+	if f.RequestID != "" && f.RequestID != e.RequestID {
+		return false
+	}
+	if !f.Start.IsZero() && e.Time.Before(f.Start) {
+		return false
+	}
+	if !f.End.IsZero() && e.Time.After(f.End) {
+		return false
+	}
+	if f.AfterSeq != 0 && e.Seq <= f.AfterSeq {
+		return false
+	}
+	return f.Contains == "" || strings.Contains(e.Line, f.Contains)
+}
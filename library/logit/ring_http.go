@@ -0,0 +1,180 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 16:58:47
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 14:26:41
+ * @Description: RingLogger 的查询接口与 SSE 实时日志
+ */
+package logit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// 查询接口默认返回的行数
+	defaultRingQueryLimit = 100
+
+	// SSE 心跳间隔，避免连接被代理断开
+	ringTailHeartbeat = 15 * time.Second
+)
+
+// ParseRingFilter 从 url 参数中解析过滤条件
+//
+//	level：日志等级，多个以逗号分隔，如 WARNING,ERROR
+//	requestID：请求ID
+//	start、end：时间范围，支持 RFC3339、2006-01-02 15:04:05(本地时间) 以及 unix 秒
+//	q：日志内容包含的子串
+func ParseRingFilter(query url.Values) (*RingFilter, error) {
+	f := &RingFilter{
+		RequestID: query.Get("requestID"),
+		Contains:  query.Get("q"),
+	}
+	if levels := query.Get("level"); levels != "" {
+		for _, name := range strings.Split(levels, ",") {
+			l, err := ParseLevel(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			f.Levels |= l
+		}
+	}
+	var err error
+	if f.Start, err = parseRingTime(query.Get("start")); err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	if f.End, err = parseRingTime(query.Get("end")); err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	return f, nil
+}
+
+func parseRingTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+}
+
+// QueryHandler 查询最近的日志，返回 json
+//
+//	除 ParseRingFilter 支持的参数外，limit 指定返回的最大行数，默认100
+//	日志可能包含敏感信息，应只在内网或经过鉴权的路由上注册
+func (r *RingLogger) QueryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		filter, err := ParseRingFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit := defaultRingQueryLimit
+		if s := query.Get("limit"); s != "" {
+			if limit, err = strconv.Atoi(s); err != nil {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries := r.Query(filter, limit)
+		if entries == nil {
+			entries = []*RingEntry{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"total":   len(entries),
+			"entries": entries,
+		})
+	})
+}
+
+// TailHandler 以 SSE(text/event-stream) 的形式实时推送日志
+//
+//	过滤参数与 QueryHandler 相同，每条日志的 id 为其序号
+//	重连时携带 Last-Event-ID，会先补发缓冲中序号更大的日志
+//	长连接不受 http.Server 的 WriteTimeout 限制
+func (r *RingLogger) TailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		filter, err := ParseRingFilter(req.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		// 清除 WriteTimeout 设置的写超时，否则连接会在超时后被断开
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// 先订阅再补发，避免两者之间的日志丢失
+		ch, cancel := r.Subscribe(filter)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		var lastSeq uint64
+		if id := req.Header.Get("Last-Event-ID"); id != "" {
+			if seq, err := strconv.ParseUint(id, 10, 64); err == nil {
+				replay := *filter
+				replay.AfterSeq = seq
+				for _, e := range r.Query(&replay, 0) {
+					writeRingEvent(w, e)
+					lastSeq = e.Seq
+				}
+			}
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(ringTailHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			case e, ok := <-ch:
+				if !ok {
+					return
+				}
+				if e.Seq <= lastSeq {
+					continue
+				}
+				writeRingEvent(w, e)
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+// writeRingEvent 写入一条 SSE 事件，日志内容中的换行拆分为多个 data 行
+func writeRingEvent(w http.ResponseWriter, e *RingEntry) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\n", e.Seq, e.LevelName)
+	for _, line := range strings.Split(e.Line, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,173 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 17:24:15
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 14:26:41
+ * @Description: 最近日志内存缓冲用例
+ */
+package logit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingLogger(t *testing.T) {
+	ring := NewRingLogger(&RingOptions{MaxLines: 3})
+	ctx := WithContext(context.Background())
+	SetRequestID(ctx, "rid-1")
+
+	ring.Notice(ctx, "n1")
+	ring.Warning(ctx, "w1")
+	ring.Error(context.Background(), "e1")
+	ring.Notice(ctx, "n2")
+	if ring.Len() != 3 {
+		t.Fatalf("want 3 lines, got %d", ring.Len())
+	}
+
+	all := ring.Query(nil, 0)
+	if !strings.Contains(all[0].Line, "message[w1]") || !strings.Contains(all[2].Line, "message[n2]") {
+		t.Errorf("oldest line should be evicted, got %q ... %q", all[0].Line, all[2].Line)
+	}
+	if !strings.Contains(all[0].Line, "ring_test.go:") {
+		t.Errorf("want caller in %q", all[0].Line)
+	}
+	if got := ring.Query(&RingFilter{RequestID: "rid-1"}, 0); len(got) != 2 {
+		t.Errorf("want 2 lines of rid-1, got %d", len(got))
+	}
+	if got := ring.Query(&RingFilter{Levels: WarningLevel | ErrorLevel}, 1); len(got) != 1 || got[0].LevelName != "ERROR" {
+		t.Errorf("want latest error line, got %+v", got)
+	}
+	if got := ring.Query(&RingFilter{Contains: "n2", End: time.Now().Add(-time.Hour)}, 0); len(got) != 0 {
+		t.Errorf("time range should filter all, got %d", len(got))
+	}
+
+	// 按字节数淘汰
+	ring = NewRingLogger(&RingOptions{MaxBytes: 200})
+	for i := 0; i < 10; i++ {
+		ring.Notice(ctx, strings.Repeat("x", 50))
+	}
+	if n := ring.Len(); n == 0 || n >= 10 {
+		t.Errorf("want lines bounded by bytes, got %d", n)
+	}
+
+	// 请求级别缓冲中的日志在 Flush 时才记录
+	ring = NewRingLogger(nil)
+	l := MultiLogger(newDispatcher(func(level Level) Logger { return &nopLogger{} }), ring)
+	tctx, tb := WithTailBuffer(ctx, 0, 0)
+	l.Debug(tctx, "d1")
+	if ring.Len() != 0 {
+		t.Fatalf("buffered line should not be recorded")
+	}
+	tb.Flush(tctx, l)
+	if got := ring.Query(nil, 0); len(got) != 1 || !strings.Contains(got[0].Line, "message[d1]") {
+		t.Errorf("flushed line should be recorded, got %+v", got)
+	}
+	if RingOf(l) != ring {
+		t.Error("RingOf should find the ring")
+	}
+
+	// Defer 字段的函数中打印日志不会死锁
+	ring = NewRingLogger(nil)
+	done := make(chan struct{})
+	go func() {
+		ring.Notice(ctx, "outer", Defer("inner", func() interface{} {
+			ring.Notice(ctx, "inner")
+			return 1
+		}))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("logging in a Defer field deadlocked")
+	}
+	if ring.Len() != 2 {
+		t.Errorf("want 2 lines, got %d", ring.Len())
+	}
+}
+
+func TestRingHandler(t *testing.T) {
+	ring := NewRingLogger(nil)
+	ctx := WithContext(context.Background())
+	ring.Notice(ctx, "hello")
+	ring.Error(ctx, "boom")
+
+	rec := httptest.NewRecorder()
+	ring.QueryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?level=error&q=boom", nil))
+	var resp struct {
+		Total   int
+		Entries []*RingEntry
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 1 || resp.Entries[0].LevelName != "ERROR" {
+		t.Errorf("unexpected response %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ring.QueryHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?level=unknown_level", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("want 400, got %d", rec.Code)
+	}
+
+	srv := httptest.NewServer(ring.TailHandler())
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"?level=WARNING,ERROR", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	ring.Notice(ctx, "skipped")
+	ring.Warning(ctx, "tail")
+
+	var data []string
+	sc := bufio.NewScanner(res.Body)
+	for len(data) < 2 && sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "data: ") {
+			data = append(data, sc.Text())
+		}
+	}
+	// 先补发 Last-Event-ID 之后的日志，再推送新日志
+	if len(data) != 2 || !strings.Contains(data[0], "message[boom]") || !strings.Contains(data[1], "message[tail]") {
+		t.Errorf("want replayed error and new warning, got %q", data)
+	}
+}
+
+func TestRingTailWriteTimeout(t *testing.T) {
+	ring := NewRingLogger(&RingOptions{MaxLines: 3})
+	ctx := WithContext(context.Background())
+	srv := httptest.NewUnstartedServer(ring.TailHandler())
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	// 超过 WriteTimeout 后连接仍可推送日志
+	time.Sleep(100 * time.Millisecond)
+	ring.Warning(ctx, "late")
+	sc := bufio.NewScanner(res.Body)
+	for sc.Scan() {
+		if strings.HasPrefix(sc.Text(), "data: ") {
+			if !strings.Contains(sc.Text(), "message[late]") {
+				t.Errorf("unexpected event %q", sc.Text())
+			}
+			return
+		}
+	}
+	t.Errorf("stream closed: %v", sc.Err())
+}
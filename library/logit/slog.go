@@ -0,0 +1,323 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 10:12:31
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 10:12:31
+ * @Description: 与标准库 log/slog 互通：slog.Handler 写入 logit，logit.Logger 写入 slog.Handler
+ */
+package logit
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SlogLevelTrace slog 中对应 logit TraceLevel 的等级，介于 Debug 与 Info 之间
+	SlogLevelTrace slog.Level = -2
+
+	// SlogLevelFatal slog 中对应 logit FatalLevel 的等级，高于 Error
+	SlogLevelFatal slog.Level = 12
+)
+
+// SlogLevel 将 logit 的日志等级转换为 slog 的日志等级
+func SlogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case TraceLevel:
+		return SlogLevelTrace
+	case NoticeLevel:
+		return slog.LevelInfo
+	case WarningLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case FatalLevel:
+		return SlogLevelFatal
+	}
+	return slog.LevelInfo
+}
+
+// LevelFromSlog 将 slog 的日志等级转换为 logit 的日志等级
+//
+//	(,-2) DEBUG；[-2,0) TRACE；[0,4) NOTICE；[4,8) WARNING；[8,12) ERROR；[12,) FATAL
+func LevelFromSlog(level slog.Level) Level {
+	switch {
+	case level < SlogLevelTrace:
+		return DebugLevel
+	case level < slog.LevelInfo:
+		return TraceLevel
+	case level < slog.LevelWarn:
+		return NoticeLevel
+	case level < slog.LevelError:
+		return WarningLevel
+	case level < SlogLevelFatal:
+		return ErrorLevel
+	}
+	return FatalLevel
+}
+
+// SlogHandlerOptions NewSlogHandler 的配置
+type SlogHandlerOptions struct {
+	// 最小日志等级，低于此等级的日志不输出
+	// 若为nil，则输出所有等级
+	Level slog.Leveler
+}
+
+// NewSlogHandler 创建一个 slog.Handler，日志最终由传入的 logger 输出
+//
+//	slog 的 group 会被展开为以 . 连接的 key，如 req.id
+//	ctx 里通过 WithContext、AddFields 等预埋的字段(如requestID)会由 logger 照常输出
+func NewSlogHandler(logger Logger, opts *SlogHandlerOptions) slog.Handler {
+	h := &slogHandler{
+		logger: logger,
+	}
+	if opts != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// slogHandler 实现 slog.Handler，转交给 logit.Logger
+type slogHandler struct {
+	logger Logger
+	level  slog.Leveler
+
+	// WithAttrs 预先转换好的字段
+	fields []Field
+
+	// WithGroup 累积的key前缀，形如 "a.b."
+	prefix string
+}
+
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.level == nil {
+		return true
+	}
+	return level >= h.level.Level()
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, a)
+		return true
+	})
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// slog 的调用位置已经记录在 r.PC 中，调用深度无法推算，所以通过 caller 字段告知日志前缀
+	// 未设置 Level 的字段不会被当作日志内容输出
+	if r.PC != 0 {
+		ctx = ForkContext(ctx)
+		AddFields(ctx, String(callerKey, slogCaller(r.PC)))
+	}
+	h.logger.Output(ctx, LevelFromSlog(r.Level), 1, r.Message, fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	nh := *h
+	nh.fields = make([]Field, 0, len(h.fields)+len(attrs))
+	nh.fields = append(nh.fields, h.fields...)
+	for _, a := range attrs {
+		nh.fields = appendSlogAttr(nh.fields, h.prefix, a)
+	}
+	return &nh
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	nh := *h
+	nh.prefix = h.prefix + name + "."
+	return &nh
+}
+
+// appendSlogAttr 将 slog.Attr 转换为 Field，group 将递归展开
+func appendSlogAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	v := a.Value
+	if v.Kind() == slog.KindGroup {
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		// key 为空的 group 直接内联
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, ga := range attrs {
+			fields = appendSlogAttr(fields, groupPrefix, ga)
+		}
+		return fields
+	}
+
+	key := prefix + a.Key
+	switch v.Kind() {
+	case slog.KindBool:
+		return append(fields, Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, Duration(key, v.Duration()))
+	case slog.KindFloat64:
+		return append(fields, Float64(key, v.Float64()))
+	case slog.KindInt64:
+		return append(fields, Int64(key, v.Int64()))
+	case slog.KindString:
+		return append(fields, String(key, v.String()))
+	case slog.KindTime:
+		return append(fields, Time(key, v.Time()))
+	case slog.KindUint64:
+		return append(fields, Uint64(key, v.Uint64()))
+	}
+	return append(fields, AutoField(key, v.Any()))
+}
+
+func slogCaller(pc uintptr) string {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	frame, _ := frames.Next()
+	if frame.File == "" {
+		return "unknown"
+	}
+	return strings.Join([]string{
+		CallerPathClean(frame.File),
+		strconv.Itoa(frame.Line),
+	}, ":")
+}
+
+var _ slog.Handler = (*slogHandler)(nil)
+
+// NewSlogLogger 创建一个 logger，日志最终由传入的 slog.Handler 输出
+//
+//	ctx 里预埋的字段与 meta fields 会一并作为 slog.Attr 输出，与 SimpleLogger 的字段顺序保持一致
+func NewSlogLogger(handler slog.Handler) Logger {
+	return &slogLogger{
+		handler: handler,
+	}
+}
+
+// slogLogger 实现 Logger，转交给 slog.Handler
+type slogLogger struct {
+	handler slog.Handler
+}
+
+func (sl *slogLogger) Debug(ctx context.Context, message string, fields ...Field) {
+	sl.Output(ctx, DebugLevel, 1, message, fields...)
+}
+
+func (sl *slogLogger) Trace(ctx context.Context, message string, fields ...Field) {
+	sl.Output(ctx, TraceLevel, 1, message, fields...)
+}
+
+func (sl *slogLogger) Notice(ctx context.Context, message string, fields ...Field) {
+	sl.Output(ctx, NoticeLevel, 1, message, fields...)
+}
+
+func (sl *slogLogger) Warning(ctx context.Context, message string, fields ...Field) {
+	sl.Output(ctx, WarningLevel, 1, message, fields...)
+}
+
+func (sl *slogLogger) Error(ctx context.Context, message string, fields ...Field) {
+	sl.Output(ctx, ErrorLevel, 1, message, fields...)
+}
+
+func (sl *slogLogger) Fatal(ctx context.Context, message string, fields ...Field) {
+	sl.Output(ctx, FatalLevel, 1, message, fields...)
+}
+
+func (sl *slogLogger) Output(ctx context.Context, level Level, callDepth int, message string, fields ...Field) {
+	if level == UnknownLevel || level >= AllLevels {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sLevel := SlogLevel(level)
+	if !sl.handler.Enabled(ctx, sLevel) {
+		return
+	}
+
+	// 跳过 runtime.Callers、Output 本身
+	var pcs [1]uintptr
+	runtime.Callers(callDepth+2, pcs[:])
+	r := slog.NewRecord(time.Now(), sLevel, message, pcs[0])
+
+	// 字段的顺序与 SimpleLogger 一致
+	// 1：ctx里存储的字段  2：全局meta fields  3：传入的临时补充字段
+	// 若有重复的key，使用后面传入的值，但保持第一次出现的位置
+	attrs := make([]slog.Attr, 0, len(fields)+10)
+	index := make(map[string]int, len(fields)+10)
+	add := func(a slog.Attr) {
+		if idx, has := index[a.Key]; has {
+			attrs[idx] = a
+			return
+		}
+		index[a.Key] = len(attrs)
+		attrs = append(attrs, a)
+	}
+	Range(ctx, func(f Field) error {
+		if f.Level().Is(level) {
+			add(slogAttr(f))
+		}
+		return nil
+	})
+	RangeMetaFields(ctx, func(f Field) error {
+		add(slogAttr(f))
+		return nil
+	})
+	// 命名空间只对之后传入的字段生效，以 . 连接作为key前缀
+	var ns string
+	for _, f := range fields {
+		if f.Type() == NamespaceType {
+			ns += f.Key() + "."
+			continue
+		}
+		a := slogAttr(f)
+		a.Key = ns + a.Key
+		add(a)
+	}
+	r.AddAttrs(attrs...)
+
+	_ = sl.handler.Handle(ctx, r)
+}
+
+// slogAttr 将 Field 转换为 slog.Attr
+func slogAttr(f Field) slog.Attr {
+	switch f.Type() {
+	case ErrorType:
+		if err, ok := f.Value().(error); ok && err != nil {
+			return slog.String(f.Key(), err.Error())
+		}
+		return slog.String(f.Key(), "nil")
+	case DeferType:
+		fn := f.Value().(func() interface{})
+		return slogAttr(AutoField(f.Key(), fn()))
+	case BinaryType, ByteStringType:
+		return slog.String(f.Key(), string(f.Value().([]byte)))
+	case ObjectMarshalerType:
+		obj, _ := jsonObject(f.Value().(ObjectMarshaler))
+		return slog.Any(f.Key(), obj)
+	case ArrayMarshalerType:
+		arr, _ := jsonArray(f.Value().(ArrayMarshaler))
+		return slog.Any(f.Key(), arr)
+	}
+	return slog.Any(f.Key(), f.Value())
+}
+
+var _ Logger = (*slogLogger)(nil)
@@ -0,0 +1,67 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 10:40:07
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 10:40:07
+ * @Description: slog 互通用例
+ */
+package logit
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewSimple(buf)
+	ctx := WithContext(context.Background())
+	SetRequestID(ctx, "123456")
+
+	sl := slog.New(NewSlogHandler(l, nil)).With("app", "demo").WithGroup("req")
+	sl.WarnContext(ctx, "slow request", "path", "/a", slog.Group("user", "id", 7))
+
+	line := buf.String()
+	for _, want := range []string{
+		"WARNING: ",
+		"slog_test.go:",
+		"requestID[123456]",
+		"app[demo]",
+		"req.path[/a]",
+		"req.user.id[7]",
+		"message[slow request]",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+}
+
+func TestSlogLevel(t *testing.T) {
+	for _, level := range []Level{DebugLevel, TraceLevel, NoticeLevel, WarningLevel, ErrorLevel, FatalLevel} {
+		if got := LevelFromSlog(SlogLevel(level)); got != level {
+			t.Errorf("level %s round trip got %s", level, got)
+		}
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewSlogLogger(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ctx := WithContext(context.Background())
+	SetRequestID(ctx, "654321")
+
+	l.Notice(ctx, "hello", Int("cost", 3), String("requestID", "override"))
+	line := buf.String()
+	for _, want := range []string{"level=INFO", "msg=hello", "requestID=override", "cost=3"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+	if strings.Count(line, "requestID=") != 1 {
+		t.Errorf("requestID should appear once: %q", line)
+	}
+}
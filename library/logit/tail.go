@@ -0,0 +1,165 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 15:20:36
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 15:20:36
+ * @Description: 请求级别的日志缓冲，低等级日志先暂存，请求出错时才输出
+ */
+package logit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTailMaxLines 默认每个请求最多缓冲的日志行数
+const DefaultTailMaxLines = 500
+
+// DefaultTailLevels 默认缓冲的日志等级
+var DefaultTailLevels = DebugLevel | TraceLevel
+
+// TailBuffer 请求级别的日志缓冲
+//
+//	挂载到 ctx 后，经由 NewLogger 创建的 logger 输出的指定等级日志会被暂存，而不是直接写出
+//	请求结束时由调用方决定 Flush(输出) 或 Discard(丢弃)，之后再写入的日志不再缓冲
+//	超过 maxLines 时丢弃最早的日志
+type TailBuffer struct {
+	mu       sync.Mutex
+	levels   Level
+	maxLines int
+	entries  []*tailEntry
+	// 环形缓冲中最早一条日志的下标
+	head    int
+	dropped int
+	closed  bool
+}
+
+type tailEntry struct {
+	level   Level
+	time    time.Time
+	caller  string
+	message string
+	fields  []Field
+}
+
+// WithTailBuffer 在 ctx 中挂载一个日志缓冲
+//
+//	levels 为需要缓冲的日志等级，可以使用 | 组合，为 0 时使用 DefaultTailLevels
+//	maxLines <= 0 时使用 DefaultTailMaxLines
+func WithTailBuffer(ctx context.Context, levels Level, maxLines int) (context.Context, *TailBuffer) {
+	if levels == 0 {
+		levels = DefaultTailLevels
+	}
+	if maxLines <= 0 {
+		maxLines = DefaultTailMaxLines
+	}
+	tb := &TailBuffer{
+		levels:   levels,
+		maxLines: maxLines,
+	}
+	return context.WithValue(ctx, ctxTailBufferKey, tb), tb
+}
+
+// FindTailBuffer 获取 ctx 中挂载的日志缓冲，不存在时返回 nil
+func FindTailBuffer(ctx context.Context) *TailBuffer {
+	if ctx == nil {
+		return nil
+	}
+	tb, _ := ctx.Value(ctxTailBufferKey).(*TailBuffer)
+	return tb
+}
+
+// accept 该等级的日志是否需要缓冲
+func (tb *TailBuffer) accept(level Level) bool {
+	return tb.levels&level != 0
+}
+
+// buffering 该等级的日志当前是否会被缓冲
+func (tb *TailBuffer) buffering(level Level) bool {
+	if !tb.accept(level) {
+		return false
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return !tb.closed
+}
+
+// add 缓冲一条日志，返回 false 表示缓冲已关闭，应直接输出
+func (tb *TailBuffer) add(level Level, caller string, message string, fields []Field) bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if tb.closed {
+		return false
+	}
+
+	e := &tailEntry{
+		level:   level,
+		time:    now(),
+		caller:  caller,
+		message: message,
+	}
+	// 调用方可能会复用 fields，需要拷贝
+	if len(fields) > 0 {
+		e.fields = make([]Field, len(fields), len(fields)+1)
+		copy(e.fields, fields)
+	}
+
+	if len(tb.entries) < tb.maxLines {
+		tb.entries = append(tb.entries, e)
+		return true
+	}
+	tb.entries[tb.head] = e
+	tb.head = (tb.head + 1) % tb.maxLines
+	tb.dropped++
+	return true
+}
+
+// Len 当前缓冲的日志行数
+func (tb *TailBuffer) Len() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return len(tb.entries)
+}
+
+// Dropped 因超出 maxLines 被丢弃的日志行数
+func (tb *TailBuffer) Dropped() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.dropped
+}
+
+// Flush 按写入顺序将缓冲的日志交给 logger 输出，并关闭缓冲
+//
+//	每条日志保留原始的 caller，原始的写入时间以 bufferedAt 字段输出
+func (tb *TailBuffer) Flush(ctx context.Context, logger Logger) {
+	entries, dropped := tb.close()
+	if logger == nil {
+		return
+	}
+	if dropped > 0 {
+		logger.Output(ctx, NoticeLevel, 1, "tail log buffer overflow", Int("tailDropped", dropped))
+	}
+	for _, e := range entries {
+		c := ForkContext(ctx)
+		AddFields(c, String(callerKey, e.caller))
+		logger.Output(c, e.level, 1, e.message, append(e.fields, Time("bufferedAt", e.time))...)
+	}
+}
+
+// Discard 丢弃缓冲的日志，并关闭缓冲
+func (tb *TailBuffer) Discard() {
+	tb.close()
+}
+
+func (tb *TailBuffer) close() ([]*tailEntry, int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.closed = true
+	entries := make([]*tailEntry, 0, len(tb.entries))
+	entries = append(entries, tb.entries[tb.head:]...)
+	entries = append(entries, tb.entries[:tb.head]...)
+	dropped := tb.dropped
+	tb.entries, tb.head, tb.dropped = nil, 0, 0
+	return entries, dropped
+}
@@ -0,0 +1,68 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 15:48:10
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 15:48:10
+ * @Description: 请求级别日志缓冲用例
+ */
+package logit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTailBuffer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	simple := NewSimple(buf)
+	l := newDispatcher(func(level Level) Logger {
+		return simple
+	})
+
+	ctx, tb := WithTailBuffer(WithContext(context.Background()), 0, 2)
+	l.Debug(ctx, "d1")
+	l.Trace(ctx, "t1", String("k", "v"))
+	l.Debug(ctx, "d2")
+	l.Notice(ctx, "n1")
+
+	if got := buf.String(); strings.Contains(got, "d1") || strings.Contains(got, "d2") || !strings.Contains(got, "n1") {
+		t.Fatalf("only notice should be written before flush, got %q", got)
+	}
+	if tb.Len() != 2 || tb.Dropped() != 1 {
+		t.Fatalf("len=%d dropped=%d, want 2 1", tb.Len(), tb.Dropped())
+	}
+
+	buf.Reset()
+	tb.Flush(ctx, l)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want 3 lines, got %q", lines)
+	}
+	if !strings.Contains(lines[0], "tailDropped[1]") {
+		t.Errorf("want overflow notice, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "k[v]") || !strings.Contains(lines[1], "message[t1]") || !strings.Contains(lines[1], "tail_test.go:") {
+		t.Errorf("want t1 with caller, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "message[d2]") || !strings.Contains(lines[2], "bufferedAt[") {
+		t.Errorf("want d2, got %q", lines[2])
+	}
+
+	// 关闭后不再缓冲
+	buf.Reset()
+	l.Debug(ctx, "d3")
+	if !strings.Contains(buf.String(), "d3") {
+		t.Errorf("debug after flush should be written, got %q", buf.String())
+	}
+
+	buf.Reset()
+	ctx, tb = WithTailBuffer(context.Background(), DebugLevel, 0)
+	l.Debug(ctx, "d4")
+	l.Trace(ctx, "t4")
+	tb.Discard()
+	if got := buf.String(); strings.Contains(got, "d4") || !strings.Contains(got, "t4") {
+		t.Errorf("want only t4, got %q", got)
+	}
+}
@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-04 15:43:21
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 11:22:02
+ * @LastEditTime: 2026-10-23 10:51:29
  * @Description: file content
  */
 package mysql
@@ -31,6 +31,8 @@ var (
 	clients map[string]Client
 	// 初始化互斥锁
 	initMux sync.Mutex
+	// clients 的读写锁
+	clientsMux sync.RWMutex
 )
 
 /**
@@ -41,10 +43,8 @@ var (
  */
 func GetClient(ctx context.Context, serviceName string) (Client, error) {
 	// try to get from single instance map
-	if client, hasSet := clients[serviceName]; hasSet {
-		if client != nil {
-			return client, nil
-		}
+	if client := cachedClient(serviceName); client != nil {
+		return client, nil
 	}
 	// set a new instance
 	client, err := setClient(serviceName)
@@ -63,19 +63,32 @@ func setClient(serviceName string) (Client, error) {
 	// 互斥锁
 	initMux.Lock()
 	defer initMux.Unlock()
+	// 等锁期间其他协程可能已初始化完成，复用其实例，避免重复创建连接池与健康检查
+	if client := cachedClient(serviceName); client != nil {
+		return client, nil
+	}
 	// 初始化
 	client, err := initClient(serviceName)
 	if err == nil {
+		clientsMux.Lock()
 		if clients == nil {
 			clients = make(map[string]Client)
 		}
 		// 添加
 		clients[serviceName] = client
+		clientsMux.Unlock()
 		return client, nil
 	}
 	return nil, err
 }
 
+// cachedClient 已初始化的 client，不存在时为 nil
+func cachedClient(serviceName string) Client {
+	clientsMux.RLock()
+	defer clientsMux.RUnlock()
+	return clients[serviceName]
+}
+
 /**
  * @description: according to conf service, read conf from conf file to init mysql client
  * @param {string} serviceName
@@ -89,6 +102,14 @@ func initClient(serviceName string) (Client, error) {
 	}
 	if _, err := os.Stat(fileAbs); !os.IsNotExist(err) {
 		conf.Default.Parse(fileAbs, &config)
+		// 配置了分片规则时按规则路由到各分库
+		if len(config.Sharding.Tables) > 0 {
+			sc, err := NewShardClient(config)
+			if err != nil {
+				return nil, err
+			}
+			return sc, nil
+		}
 		client := New(config)
 		return client, nil
 	}
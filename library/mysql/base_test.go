@@ -0,0 +1,59 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-23 10:51:29
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 10:51:29
+ * @Description: client 单例的初始化用例
+ */
+package mysql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestGetClientConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, mysqlPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	toml := "Name = \"db_concurrent\"\n[MySQL]\nDBDriver = \"sqlite\"\nDBName = \":memory:\"\n[Pool]\nHealthCheckInterval = -1\n"
+	if err := os.WriteFile(filepath.Join(dir, mysqlPath, "db_concurrent"+prefix), []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := configPath
+	configPath = dir
+	t.Cleanup(func() {
+		configPath = old
+		clientsMux.Lock()
+		if c := clients["db_concurrent"]; c != nil {
+			c.Close()
+			delete(clients, "db_concurrent")
+		}
+		clientsMux.Unlock()
+	})
+
+	// 并发首次获取只初始化一个实例
+	got := make([]Client, 8)
+	var wg sync.WaitGroup
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := GetClient(context.Background(), "db_concurrent")
+			if err != nil {
+				t.Error(err)
+			}
+			got[i] = c
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range got {
+		if c == nil || c != got[0] {
+			t.Fatalf("want one shared client, got %v", got)
+		}
+	}
+}
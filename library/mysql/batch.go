@@ -0,0 +1,357 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 21:05:37
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 11:14:52
+ * @Description: 批量写入，按行数与大小分批，汇总每批的结果
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultChunkRows 每批的默认行数
+	DefaultChunkRows = 1000
+	// DefaultChunkBytes 每批预估 sql 大小的默认上限，需小于 max_allowed_packet（默认4MB）
+	DefaultChunkBytes = 1 << 20
+)
+
+// BatchOption 批量写入的配置选项
+type BatchOption interface {
+	apply(*batchOptions)
+}
+
+type batchOptions struct {
+	rows        int
+	bytes       int
+	typ         int
+	update      map[string]interface{}
+	inTx        bool
+	txOpts      []TxOption
+	concurrency int
+}
+
+type batchFuncOption struct {
+	f func(*batchOptions)
+}
+
+func (fo *batchFuncOption) apply(o *batchOptions) {
+	fo.f(o)
+}
+
+func newBatchFuncOption(f func(*batchOptions)) *batchFuncOption {
+	return &batchFuncOption{
+		f: f,
+	}
+}
+
+// WithChunkRows 每批的最大行数，默认 DefaultChunkRows
+func WithChunkRows(n int) BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.rows = n
+	})
+}
+
+// WithChunkBytes 每批预估 sql 大小的上限，默认 DefaultChunkBytes
+//
+//	单行超过上限时单独成一批
+func WithChunkBytes(n int) BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.bytes = n
+	})
+}
+
+// WithBatchIgnore 使用 INSERT IGNORE 写入
+func WithBatchIgnore() BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.typ = insertIgnore
+	})
+}
+
+// WithBatchReplace 使用 REPLACE 写入
+func WithBatchReplace() BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.typ = insertReplace
+	})
+}
+
+// WithBatchOnDuplicate 使用 INSERT ... ON DUPLICATE KEY UPDATE 写入
+func WithBatchOnDuplicate(update map[string]interface{}) BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.typ = insertOnDuplicate
+		o.update = update
+	})
+}
+
+// WithBatchTx 所有批次在一个事务中执行，任一批失败时全部回滚，不再执行后续批次
+//
+//	在 Tx 中使用时以嵌套事务（SAVEPOINT）执行，opts 不生效
+func WithBatchTx(opts ...TxOption) BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.inTx = true
+		o.txOpts = opts
+	})
+}
+
+// WithConcurrency 并行执行的批次数，默认1，即依次执行
+//
+//	在事务中执行时只能依次执行，该选项不生效
+func WithConcurrency(n int) BatchOption {
+	return newBatchFuncOption(func(o *batchOptions) {
+		o.concurrency = n
+	})
+}
+
+// BatchResult 批量写入的汇总结果
+type BatchResult struct {
+	// 分批数
+	Chunks int
+	// 成功的批次影响的行数之和，事务回滚时为0
+	RowsAffected int64
+	// 失败的批次下标，从小到大排列
+	Failed []int
+	// 失败批次的错误，key 为批次下标
+	Errors map[int]error
+
+	// 每批的行区间与写入结果
+	ranges  [][2]int
+	results []sql.Result
+	mu      sync.Mutex
+}
+
+// Err 所有失败批次的错误，全部成功时为 nil
+func (r *BatchResult) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	errs := make([]error, len(r.Failed))
+	for i, idx := range r.Failed {
+		errs[i] = fmt.Errorf("chunk %d: %w", idx, r.Errors[idx])
+	}
+	return errors.Join(errs...)
+}
+
+// Rows 第 i 批在输入中的行区间 [start, end)
+func (r *BatchResult) Rows(i int) (start, end int) {
+	return r.ranges[i][0], r.ranges[i][1]
+}
+
+func (r *BatchResult) fail(i int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failed = append(r.Failed, i)
+	r.Errors[i] = err
+}
+
+// BatchInsert 分批写入多行，避免单条 sql 超过 max_allowed_packet
+//
+//	默认依次执行所有批次，某批失败不影响其他批次，失败的批次记录在结果中；
+//	ctx 取消后未执行的批次记为失败。有失败的批次时 error 为 BatchResult.Err()
+func BatchInsert(ctx context.Context, s Session, tableName string, data []map[string]interface{}, opts ...BatchOption) (*BatchResult, error) {
+	o := &batchOptions{
+		rows:        DefaultChunkRows,
+		bytes:       DefaultChunkBytes,
+		typ:         insertCommon,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	res := &BatchResult{
+		Errors: map[int]error{},
+		ranges: chunkRows(data, o.rows, o.bytes),
+	}
+	res.Chunks = len(res.ranges)
+	res.results = make([]sql.Result, res.Chunks)
+	if res.Chunks == 0 {
+		return res, nil
+	}
+
+	if !o.inTx {
+		runChunks(ctx, s, tableName, data, o, res)
+		return res, res.Err()
+	}
+	run := func(tx Tx) error {
+		for i := range res.ranges {
+			if err := execChunk(ctx, tx, tableName, data, o, res, i); err != nil {
+				res.fail(i, err)
+				return err
+			}
+		}
+		return nil
+	}
+	var err error
+	switch sess := s.(type) {
+	case Client:
+		err = sess.Transaction(ctx, run, o.txOpts...)
+	case Tx:
+		err = sess.Transaction(ctx, run)
+	default:
+		return nil, fmt.Errorf("mysql: session %T does not support transaction", s)
+	}
+	if err != nil {
+		// 回滚后所有批次均未写入
+		res.RowsAffected = 0
+		for i := range res.results {
+			res.results[i] = nil
+		}
+		if len(res.Failed) == 0 {
+			// 提交失败
+			return res, err
+		}
+	}
+	return res, res.Err()
+}
+
+// runChunks 不在事务中执行，Tx 不可并发使用，只能依次执行
+func runChunks(ctx context.Context, s Session, tableName string, data []map[string]interface{}, o *batchOptions, res *BatchResult) {
+	concurrency := o.concurrency
+	if _, ok := s.(Tx); ok || concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	for i := range res.ranges {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			res.fail(i, err)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := execChunk(ctx, s, tableName, data, o, res, i); err != nil {
+				res.fail(i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	sort.Ints(res.Failed)
+}
+
+// execChunk 写入第 i 批，并发执行时每批只写自己的 results[i]
+func execChunk(ctx context.Context, s Session, tableName string, data []map[string]interface{}, o *batchOptions, res *BatchResult, i int) error {
+	start, end := res.Rows(i)
+	chunk := data[start:end]
+	var (
+		r   sql.Result
+		err error
+	)
+	switch o.typ {
+	case insertIgnore:
+		r, err = s.InsertIgnore(ctx, tableName, chunk)
+	case insertReplace:
+		r, err = s.InsertReplace(ctx, tableName, chunk)
+	case insertOnDuplicate:
+		r, err = s.InsertOnDuplicate(ctx, tableName, chunk, o.update)
+	default:
+		r, err = s.Insert(ctx, tableName, chunk)
+	}
+	if err != nil {
+		return err
+	}
+	res.results[i] = r
+	if n, err := r.RowsAffected(); err == nil {
+		res.mu.Lock()
+		res.RowsAffected += n
+		res.mu.Unlock()
+	}
+	return nil
+}
+
+// chunkRows 按行数与预估大小分批，返回每批的行区间
+func chunkRows(data []map[string]interface{}, maxRows, maxBytes int) [][2]int {
+	if maxRows <= 0 {
+		maxRows = DefaultChunkRows
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultChunkBytes
+	}
+	var (
+		ranges [][2]int
+		start  int
+		size   int
+	)
+	for i, row := range data {
+		n := rowSize(row)
+		if i > start && (i-start >= maxRows || size+n > maxBytes) {
+			ranges = append(ranges, [2]int{start, i})
+			start, size = i, 0
+		}
+		size += n
+	}
+	if start < len(data) {
+		ranges = append(ranges, [2]int{start, len(data)})
+	}
+	return ranges
+}
+
+// rowSize 预估一行在 VALUES 中的大小
+func rowSize(row map[string]interface{}) int {
+	// 括号
+	n := 3
+	for _, v := range row {
+		// 分隔符
+		n += 2
+		switch val := v.(type) {
+		case nil:
+			n += 4
+		case string:
+			n += len(val) + 2
+		case []byte:
+			n += len(val) + 2
+		case time.Time:
+			n += 28
+		case bool:
+			n++
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			n += 20
+		default:
+			n += len(sqlLiteral(val))
+		}
+	}
+	return n
+}
+
+// BatchInsertStructs 分批写入结构体，字段规则与 InsertStructs 一致
+//
+//	普通写入模式下，mysql 成功批次的自增主键按该批的 LastInsertId 依次回填
+func BatchInsertStructs[T any](ctx context.Context, s Session, tableName string, rows []*T, opts ...BatchOption) (*BatchResult, error) {
+	info, values, fields, data, err := structsInsertData(rows)
+	if err != nil {
+		return nil, err
+	}
+	res, err := BatchInsert(ctx, s, tableName, data, opts...)
+	if res == nil {
+		return nil, err
+	}
+	o := &batchOptions{}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	if o.typ == insertCommon && firstInsertID(s) {
+		for i, r := range res.results {
+			if r != nil {
+				start, end := res.Rows(i)
+				backfillIDs(info, fields, values[start:end], r)
+			}
+		}
+	}
+	return res, err
+}
@@ -0,0 +1,115 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 21:30:12
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 21:30:12
+ * @Description: 批量写入用例
+ */
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func batchRows(n int) []map[string]interface{} {
+	data := make([]map[string]interface{}, n)
+	for i := range data {
+		data[i] = map[string]interface{}{"id": i, "name": fmt.Sprintf("u%d", i)}
+	}
+	return data
+}
+
+func TestChunkRows(t *testing.T) {
+	if got := chunkRows(batchRows(7), 3, 0); !reflect.DeepEqual(got, [][2]int{{0, 3}, {3, 6}, {6, 7}}) {
+		t.Errorf("chunk by rows: %v", got)
+	}
+	// 每行预估 31 字节，80 字节放得下 2 行
+	if got := chunkRows(batchRows(5), 100, 80); !reflect.DeepEqual(got, [][2]int{{0, 2}, {2, 4}, {4, 5}}) {
+		t.Errorf("chunk by bytes: %v", got)
+	}
+	// 单行超过上限时单独成一批
+	big := []map[string]interface{}{{"v": strings.Repeat("x", 200)}, {"v": "a"}}
+	if got := chunkRows(big, 100, 100); !reflect.DeepEqual(got, [][2]int{{0, 1}, {1, 2}}) {
+		t.Errorf("oversized row: %v", got)
+	}
+	if got := chunkRows(nil, 3, 0); len(got) != 0 {
+		t.Errorf("empty data: %v", got)
+	}
+}
+
+func TestBatchInsert(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	// 只有最后一批是1行
+	boom := errors.New("boom")
+	fc.execErr = func(query string) error {
+		if strings.HasPrefix(query, "INSERT") && strings.Count(query, "(?") == 1 {
+			return boom
+		}
+		return nil
+	}
+
+	res, err := BatchInsert(ctx, c, "tb_user", batchRows(10), WithChunkRows(3), WithConcurrency(3), WithBatchIgnore())
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "chunk 3") {
+		t.Errorf("want chunk 3 error, got %v", err)
+	}
+	if res.Chunks != 4 || res.RowsAffected != 3 || !reflect.DeepEqual(res.Failed, []int{3}) {
+		t.Errorf("unexpected result %+v", res)
+	}
+	if start, end := res.Rows(3); start != 9 || end != 10 {
+		t.Errorf("unexpected rows of chunk 3: %d-%d", start, end)
+	}
+	if !strings.HasPrefix(lastStatement(fc), "INSERT IGNORE") {
+		t.Errorf("want INSERT IGNORE, got %s", lastStatement(fc))
+	}
+	fc.statements()
+
+	// 事务中失败时全部回滚，不再执行后续批次
+	res, err = BatchInsert(ctx, c, "tb_user", batchRows(10), WithChunkRows(3), WithBatchTx(), WithConcurrency(3))
+	if !errors.Is(err, boom) || res.RowsAffected != 0 || !reflect.DeepEqual(res.Failed, []int{3}) {
+		t.Errorf("unexpected tx result %+v %v", res, err)
+	}
+	if got, want := fc.statements(), "BEGIN; INSERT; INSERT; INSERT; INSERT; ROLLBACK"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	fc.execErr = nil
+	res, err = BatchInsert(ctx, c, "tb_user", batchRows(10), WithChunkRows(5), WithBatchTx())
+	if err != nil || res.Chunks != 2 || res.RowsAffected != 2 {
+		t.Errorf("unexpected tx result %+v %v", res, err)
+	}
+	if got, want := fc.statements(), "BEGIN; INSERT; INSERT; COMMIT"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// ctx 取消后未执行的批次记为失败
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	res, err = BatchInsert(cctx, c, "tb_user", batchRows(4), WithChunkRows(2))
+	if !errors.Is(err, context.Canceled) || !reflect.DeepEqual(res.Failed, []int{0, 1}) {
+		t.Errorf("unexpected canceled result %+v %v", res, err)
+	}
+	if got := fc.statements(); got != "" {
+		t.Errorf("no statement should run, got %q", got)
+	}
+}
+
+func TestBatchInsertStructs(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.lastInsertID = 100
+	users := []*structUser{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	res, err := BatchInsertStructs(ctx, c, "tb_user", users, WithChunkRows(2))
+	if err != nil || res.Chunks != 2 {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+	// 每批按该批的 LastInsertId 回填
+	if users[0].ID != 100 || users[1].ID != 101 || users[2].ID != 100 {
+		t.Errorf("unexpected ids %d %d %d", users[0].ID, users[1].ID, users[2].ID)
+	}
+}
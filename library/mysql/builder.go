@@ -2,15 +2,15 @@
  * @Author: liziwei01
  * @Date: 2022-03-09 19:26:42
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 11:21:57
+ * @LastEditTime: 2026-10-22 10:05:44
  * @Description: file content
  */
 package mysql
 
 import (
 	"context"
-
-	"github.com/didi/gendry/builder"
+	"database/sql"
+	"fmt"
 )
 
 const (
@@ -42,6 +42,8 @@ type SelectBuilder struct {
 	table  string
 	where  map[string]interface{}
 	fields []string
+	// 表的约定，ShardClient 按逻辑表名指定，为 nil 时按表名查找 Client 的配置
+	convention *TableConvention
 
 	res *result
 }
@@ -57,16 +59,18 @@ type InsertBuilder struct {
 }
 
 type UpdateBuilder struct {
-	table  string
-	where  map[string]interface{}
-	update map[string]interface{}
+	table      string
+	where      map[string]interface{}
+	update     map[string]interface{}
+	convention *TableConvention
 
 	res *result
 }
 
 type DeleteBuilder struct {
-	table string
-	where map[string]interface{}
+	table      string
+	where      map[string]interface{}
+	convention *TableConvention
 
 	res *result
 }
@@ -142,9 +146,7 @@ func NewRawBuilder(sql string, args []interface{}) *RawBuilder {
 // }
 
 func (b *SelectBuilder) CompileContext(ctx context.Context, c Client) (string, []interface{}, error) {
-	cond, values, err := builder.BuildSelect(b.table, b.where, b.fields)
-	log(ctx, c, cond, values)
-	return cond, values, err
+	return clientDialect(c).buildSelect(b.table, clientConvention(c, b.table, b.convention).filter(ctx, b.where), b.fields)
 }
 
 // func (b *InsertBuilder) Result() *result {
@@ -152,23 +154,7 @@ func (b *SelectBuilder) CompileContext(ctx context.Context, c Client) (string, [
 // }
 
 func (b *InsertBuilder) CompileContext(ctx context.Context, c Client) (string, []interface{}, error) {
-	var (
-		cond   string
-		values []interface{}
-		err    error
-	)
-	switch b.typ {
-	case insertCommon:
-		cond, values, err = builder.BuildInsert(b.table, b.data)
-	case insertIgnore:
-		cond, values, err = builder.BuildInsertIgnore(b.table, b.data)
-	case insertReplace:
-		cond, values, err = builder.BuildReplaceInsert(b.table, b.data)
-	case insertOnDuplicate:
-		cond, values, err = builder.BuildInsertOnDuplicate(b.table, b.data, b.update)
-	}
-	log(ctx, c, cond, values)
-	return cond, values, err
+	return clientDialect(c).buildInsert(ctx, b.table, b.data, b.typ, b.update)
 }
 
 // func (b *UpdateBuilder) Result() *result {
@@ -176,9 +162,7 @@ func (b *InsertBuilder) CompileContext(ctx context.Context, c Client) (string, [
 // }
 
 func (b *UpdateBuilder) CompileContext(ctx context.Context, c Client) (string, []interface{}, error) {
-	cond, values, err := builder.BuildUpdate(b.table, b.where, b.update)
-	log(ctx, c, cond, values)
-	return cond, values, err
+	return clientConvention(c, b.table, b.convention).buildUpdate(ctx, clientDialect(c), b.table, b.where, b.update)
 }
 
 // func (b *DeleteBuilder) Result() *result {
@@ -186,9 +170,7 @@ func (b *UpdateBuilder) CompileContext(ctx context.Context, c Client) (string, [
 // }
 
 func (b *DeleteBuilder) CompileContext(ctx context.Context, c Client) (string, []interface{}, error) {
-	cond, values, err := builder.BuildDelete(b.table, b.where)
-	log(ctx, c, cond, values)
-	return cond, values, err
+	return clientConvention(c, b.table, b.convention).buildDelete(ctx, clientDialect(c), b.table, b.where)
 }
 
 // func (b *RawBuilder) Result() *result {
@@ -196,16 +178,37 @@ func (b *DeleteBuilder) CompileContext(ctx context.Context, c Client) (string, [
 // }
 
 func (b *RawBuilder) CompileContext(ctx context.Context, c Client) (string, []interface{}, error) {
-	log(ctx, c, b.sql, b.args)
 	return b.sql, b.args, nil
 }
 
-func log(ctx context.Context, c Client, cond string, values []interface{}) {
-	// query := fmt.Sprintf(cond, values...)
-	// if c.sqlloglen() != -1 {
-	// 	query = query[0:c.sqlloglen()]
-	// }
-	// if len(query) != 0 {
-	// 	logit.Logger.Info("[MySQL] [requestID]=%d, [query]=%s", ctx.Value("requestID"), query)
-	// }
+// sessionQuery 其他 Session 的实现没有连接，按 Builder 的类型调用对应的方法
+func sessionQuery(ctx context.Context, s Session, b Builder, data interface{}) error {
+	if sb, ok := b.(*SelectBuilder); ok {
+		return s.Query(ctx, sb.table, sb.where, sb.fields, data)
+	}
+	return fmt.Errorf("mysql: %T does not support query with %T", s, b)
+}
+
+// sessionExec 同 sessionQuery
+func sessionExec(ctx context.Context, s Session, b Builder) (sql.Result, error) {
+	switch eb := b.(type) {
+	case *InsertBuilder:
+		switch eb.typ {
+		case insertIgnore:
+			return s.InsertIgnore(ctx, eb.table, eb.data)
+		case insertReplace:
+			return s.InsertReplace(ctx, eb.table, eb.data)
+		case insertOnDuplicate:
+			return s.InsertOnDuplicate(ctx, eb.table, eb.data, eb.update)
+		default:
+			return s.Insert(ctx, eb.table, eb.data)
+		}
+	case *UpdateBuilder:
+		return s.Update(ctx, eb.table, eb.where, eb.update)
+	case *DeleteBuilder:
+		return s.Delete(ctx, eb.table, eb.where)
+	case *RawBuilder:
+		return s.ExecRaw(ctx, eb.sql, eb.args...)
+	}
+	return nil, fmt.Errorf("mysql: %T does not support exec with %T", s, b)
 }
@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-09 19:26:04
  * @LastEditors: liziwei01
- * @LastEditTime: 2022-04-17 15:39:35
+ * @LastEditTime: 2026-10-21 21:20:18
  * @Description: file content
  */
 package mysql
@@ -10,17 +10,14 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"regexp"
 	"sync"
 	"time"
 
-	"github.com/didi/gendry/manager"
-)
-
-var (
-	// 初始化互斥锁
-	mu sync.Mutex
+	"github.com/liziwei01/gin-lib/library/resilience"
 )
 
+// Client mysql 客户端，只包含导出的方法，包外可以实现，单测中可使用 mysqltest.Fake 替代
 type Client interface {
 	// Query 查询, 返回数据在data, 内有didi builder, 结构体使用tag `ddb`
 	Query(ctx context.Context, tableName string, where map[string]interface{}, columns []string, data interface{}) error
@@ -34,70 +31,102 @@ type Client interface {
 
 	InsertOnDuplicate(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}) (sql.Result, error)
 
+	// Update 表配置了乐观锁时版本号加 1，where 中带版本号且没有更新任何行时返回 ErrConflict
 	Update(ctx context.Context, tableName string, where map[string]interface{}, update map[string]interface{}) (sql.Result, error)
 
+	// Delete 表配置了软删除时改为设置删除时间，ctx 经 HardDelete 标记后物理删除
 	Delete(ctx context.Context, tableName string, where map[string]interface{}) (sql.Result, error)
 
 	// ExecRaw 拼接的原生sql语句
 	ExecRaw(ctx context.Context, sql string, args ...interface{}) (sql.Result, error)
 
-	connect(ctx context.Context) (*sql.DB, error)
-	open() (*sql.DB, error)
-
-	name() string
-	writeTimeOut() int
-	readTimeOut() int
-	retry() int
-	host() string
-	port() int
-	username() string
-	password() string
-	dbname() string
-	dbdriver() string
-	charset() string
-	collation() string
-	timeout() int
-	sqlloglen() int
+	// QueryRows 逐行读取查询结果，用于导出等大结果集，用完需 Close
+	QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*Rows, error)
+
+	// QueryPage 页码分页，先查询总数再查询当前页，where 中的 _limit 被忽略
+	QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error)
+
+	// QueryCursor 游标分页，按 page.Column 排序查询游标之后的一页，返回下一页的游标，没有下一页时为空
+	QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error)
+
+	// Transaction 在事务中执行 fn，fn 返回 nil 时提交，返回错误或 panic 时回滚
+	Transaction(ctx context.Context, fn func(tx Tx) error, opts ...TxOption) error
+
+	// Conn 获取一个独占的连接，用于 GET_LOCK 这类与会话绑定的操作，用完需 Close 归还连接池
+	Conn(ctx context.Context) (*sql.Conn, error)
+
+	// Close 停止健康检查并关闭连接池，之后不应再使用
+	Close() error
 }
 
 type client struct {
 	conf *Config
-	db   *sql.DB
+	// 主库的连接池，首次使用时创建
+	mu sync.RWMutex
+	db *sql.DB
+
+	replicas []*replica
+	// 轮询的计数
+	rr uint32
+
+	// 日志中需要打码的字段名
+	redact *regexp.Regexp
+
+	// 表的约定，key 为表名
+	conventions map[string]*TableConvention
+
+	// ExecWithBuilder、QueryWithBuilder 的重试、熔断与并发隔离
+	policy *resilience.Policy
+
+	// 后台健康检查
+	probeOnce sync.Once
+	stopProbe chan struct{}
+	probeDone chan struct{}
 }
 
+// connect 获取主库的连接池，不再每次 ping，连接的可用性由后台健康检查与 database/sql 的重连保证
 func (c *client) connect(ctx context.Context) (*sql.DB, error) {
-	var err error
+	c.startProbe()
+	c.mu.RLock()
+	db := c.db
+	c.mu.RUnlock()
+	if db != nil {
+		return db, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.db != nil {
-		if err = c.db.PingContext(ctx); err == nil {
-			return c.db, nil
-		}
+		return c.db, nil
+	}
+	db, err := c.open()
+	if err != nil {
+		return nil, err
 	}
-	mu.Lock()
-	defer mu.Unlock()
-	c.db, err = c.open()
-	return c.db, err
+	c.db = db
+	return db, nil
 }
 
 func (c *client) open() (*sql.DB, error) {
-	var (
-		db  *sql.DB
-		err error
-	)
-	// 内含 retry 2
-	db, err = manager.New(c.dbname(), c.username(), c.password(), c.host()).Set(
-		manager.SetCharset(c.charset()),
-		manager.SetAllowCleartextPasswords(true),
-		manager.SetAllowNativePasswords(true),
-		manager.SetInterpolateParams(true),
-		manager.SetAllowAllFiles(true),
-		manager.SetParseTime(true),
-		manager.SetLoc(time.Local.String()),
-		manager.SetTimeout(time.Duration(c.timeout())*time.Millisecond),
-		manager.SetReadTimeout(time.Duration(c.readTimeOut())*time.Millisecond),
-		manager.SetWriteTimeout(time.Duration(c.writeTimeOut())*time.Millisecond),
-		manager.SetCollation(c.collation()),
-	).Port(c.port()).Open(true)
-	return db, err
+	return c.openAddr(c.host(), c.port())
+}
+
+func (c *client) openAddr(host string, port int) (*sql.DB, error) {
+	d, err := dialectOf(c.dbdriver())
+	if err != nil {
+		return nil, err
+	}
+	db, err := d.open(c, host, port)
+	if err != nil {
+		return nil, err
+	}
+	c.setupPool(db)
+	if d == sqliteDialect && c.dbname() == sqliteMemory {
+		// 共享的内存数据库在所有连接关闭后清空，不主动关闭连接
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
+	pools.add(c.name(), d.instance(c, host, port), db)
+	return db, nil
 }
 
 func New(config *Config) Client {
@@ -105,6 +134,17 @@ func New(config *Config) Client {
 		conf: config,
 		db:   nil,
 	}
+	if config != nil {
+		for _, addr := range config.Resource.Manual.Replicas {
+			c.replicas = append(c.replicas, &replica{addr: addr})
+		}
+		c.redact = compileRedact(config.MySQL.RedactColumns)
+		c.conventions = make(map[string]*TableConvention, len(config.Conventions))
+		for i := range config.Conventions {
+			c.conventions[config.Conventions[i].Table] = &config.Conventions[i]
+		}
+		c.policy = resilience.New(config.Name, config.Retry, config.Resilience, resilience.WithFailure(unavailable))
+	}
 	return c
 }
 
@@ -140,6 +180,10 @@ func (c *client) dbdriver() string {
 	return c.conf.MySQL.DBDriver
 }
 
+func (c *client) sslmode() string {
+	return c.conf.MySQL.SSLMode
+}
+
 func (c *client) charset() string {
 	return c.conf.MySQL.Charset
 }
@@ -166,6 +210,37 @@ func (c *client) password() string {
 	return c.conf.MySQL.Password
 }
 
+func (c *client) strategy() string {
+	return c.conf.Strategy.Name
+}
+
+func (c *client) ejectTime() time.Duration {
+	if c.conf.Strategy.EjectTime <= 0 {
+		return defaultEjectTime
+	}
+	return time.Duration(c.conf.Strategy.EjectTime) * time.Millisecond
+}
+
 func (c *client) sqlloglen() int {
 	return c.conf.MySQL.SQLLogLen
 }
+
+func (c *client) slowThreshold() time.Duration {
+	return time.Duration(c.conf.MySQL.SlowThreshold) * time.Millisecond
+}
+
+func (c *client) redactColumn(column string) bool {
+	return c.redact != nil && c.redact.MatchString(column)
+}
+
+// compileRedact 编译打码字段名的正则，配置有误时使用默认规则
+func compileRedact(columns string) *regexp.Regexp {
+	if columns == "" {
+		columns = defaultRedactColumns
+	}
+	re, err := regexp.Compile("(?i)" + columns)
+	if err != nil {
+		return regexp.MustCompile("(?i)" + defaultRedactColumns)
+	}
+	return re
+}
@@ -2,11 +2,13 @@
  * @Author: liziwei01
  * @Date: 2022-03-04 15:42:58
  * @LastEditors: liziwei01
- * @LastEditTime: 2022-03-21 21:51:50
+ * @LastEditTime: 2026-10-21 21:20:18
  * @Description: file content
  */
 package mysql
 
+import "github.com/liziwei01/gin-lib/library/resilience"
+
 // Config 配置
 type Config struct {
 	// Service的名字, 必选
@@ -17,25 +19,104 @@ type Config struct {
 	WriteTimeOut int
 	// 读数据超时
 	ReadTimeOut int
-	// 请求失败后的重试次数: 总请求次数 = Retry + 1
+	// 请求失败后的重试次数: 总请求次数 = Retry + 1, 读重试数据库不可用的错误, 写只重试确定没有执行的错误
 	Retry int
 
+	// 重试的退避、熔断与并发隔离, 全部非必选
+	Resilience resilience.Config
+
+	// 资源使用策略, 配置了从库时生效, 非必选
+	Strategy struct {
+		// RoundRobin-依次轮询(默认), Random-随机
+		Name string
+		// 从库健康检查失败后被摘除的时长(ms), 之后再次尝试, 默认 30000
+		EjectTime int
+	}
+
 	// 资源定位: 手动配置 - 使用IP、端口
 	Resource struct {
 		Manual struct {
+			// 主库, 写与事务只使用主库
 			Host string
 			Port int
+			// 从库, 非必选, 为空时读写都使用主库
+			Replicas []Addr
 		}
 	}
 
+	// 连接池, 全部非必选, 主库与每个从库各有一个连接池
+	Pool struct {
+		// 最大连接数, 默认 100, 需小于数据库的 max_connections / 实例数
+		MaxOpenConns int
+		// 最大空闲连接数, 默认 10
+		MaxIdleConns int
+		// 连接的最长使用时长(ms), 默认 1800000, 需小于数据库的 wait_timeout
+		ConnMaxLifetime int
+		// 连接的最长空闲时长(ms), 默认 0 不限制
+		ConnMaxIdleTime int
+		// 后台健康检查的间隔(ms), 默认 5000, -1 不检查
+		HealthCheckInterval int
+	}
+
 	MySQL struct {
-		Username  string
-		Password  string
-		DBName    string
+		Username string
+		Password string
+		// sqlite 为数据库文件, 相对路径基于 data 目录, :memory: 为内存数据库
+		DBName string
+		// mysql(默认)、sqlite、postgres
 		DBDriver  string
 		Charset   string
 		Collation string
 		Timeout   int
+		// postgres 的 sslmode, 默认 disable
+		SSLMode string
+		// sql 日志的长度, 0 不打印, -1 打印完整的 sql
 		SQLLogLen int
+		// 慢查询阈值(ms), 超过时打印 WARNING 日志, 0 不检测
+		SlowThreshold int
+		// 日志中需要打码的字段名正则, 忽略大小写, 默认 password|passwd|secret|token
+		RedactColumns string
 	}
+
+	// 表的约定, 非必选, 按表开启乐观锁与软删除
+	Conventions []TableConvention
+
+	// 分库分表, 非必选, 配置了 Tables 时 GetClient 返回按分片规则路由的 ShardClient
+	Sharding struct {
+		// 各分库的 servicer, 分表按编号顺序均匀分布在各分库, 为空时所有分表都在当前 servicer
+		Databases []string
+		// 缺少分片字段时并发查询各分表的并发数, 默认 8
+		Concurrency int
+		// 各逻辑表的分片规则, 未配置的表不分片, 使用第一个分库
+		Tables []ShardRule
+	}
+}
+
+// Addr 实例地址
+type Addr struct {
+	Host string
+	Port int
+}
+
+// TableConvention 表的约定, 字段名为空时不开启
+type TableConvention struct {
+	Table string
+	// 乐观锁的版本号字段, Update 时加 1, where 中带版本号且没有更新任何行时返回 ErrConflict
+	Version string
+	// 软删除的时间字段, 为 NULL 时未删除, Delete 改为设置删除时间, 查询与更新只处理未删除的行
+	SoftDelete string
+}
+
+// ShardRule 逻辑表的分片规则, 物理表名为 逻辑表名_编号, 编号按 Count-1 的位数补零, 如 tb_order_00
+type ShardRule struct {
+	// 逻辑表名
+	Table string
+	// 分片字段
+	Column string
+	// hash(默认): crc32 取模, mod: 整数取模, range: 按 Ranges 分段
+	Algorithm string
+	// 分表数, range 时默认为 Ranges 的长度
+	Count int
+	// range 各分表的上界(不含), 升序
+	Ranges []int64
 }
@@ -0,0 +1,136 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 19:02:47
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 11:06:48
+ * @Description: 表的约定：乐观锁与软删除
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/didi/gendry/builder"
+)
+
+// ErrConflict 带版本号的 Update 没有更新任何行，数据已被其他请求修改或不存在
+var ErrConflict = errors.New("mysql: version conflict")
+
+type ctxDeletedKey struct{}
+
+type ctxHardDeleteKey struct{}
+
+// WithDeleted 标记 ctx 中的查询、更新包含已软删除的行
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxDeletedKey{}, true)
+}
+
+func withDeleted(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxDeletedKey{}).(bool)
+	return v
+}
+
+// HardDelete 标记 ctx 中软删除表的 Delete 为物理删除，用于清理数据
+func HardDelete(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxHardDeleteKey{}, true)
+}
+
+func hardDelete(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxHardDeleteKey{}).(bool)
+	return v
+}
+
+// clientConvention 表的约定，没有时为 nil，其他 Client 的实现（如 mysqltest.Fake）不使用约定
+//
+//	tc 为 builder 指定的约定，分表的物理表名不在 Client 的配置中，由 ShardClient 按逻辑表名指定
+func clientConvention(c Client, table string, tc *TableConvention) *TableConvention {
+	if tc != nil {
+		return tc
+	}
+	if dao, ok := c.(*client); ok {
+		return dao.conventions[table]
+	}
+	return nil
+}
+
+// hasColumn where 中是否有字段的条件
+func hasColumn(where map[string]interface{}, column string) bool {
+	for k := range where {
+		if fields := strings.Fields(k); len(fields) > 0 && strings.Trim(fields[0], "`\"") == column {
+			return true
+		}
+	}
+	return false
+}
+
+// filter 加上软删除字段 IS NULL 的条件，ctx 经 WithDeleted 标记或 where 中已有该字段的条件时不处理
+func (tc *TableConvention) filter(ctx context.Context, where map[string]interface{}) map[string]interface{} {
+	if tc == nil || tc.SoftDelete == "" || withDeleted(ctx) || hasColumn(where, tc.SoftDelete) {
+		return where
+	}
+	w := copyWhere(where)
+	w[tc.SoftDelete] = builder.IsNull
+	return w
+}
+
+// softDelete Delete 是否改为设置删除时间
+func (tc *TableConvention) softDelete(ctx context.Context) bool {
+	return tc != nil && tc.SoftDelete != "" && !hardDelete(ctx)
+}
+
+// checksVersion Update 的 where 中是否带版本号
+func (tc *TableConvention) checksVersion(where map[string]interface{}) bool {
+	return tc != nil && tc.Version != "" && hasColumn(where, tc.Version)
+}
+
+// buildUpdate 更新时版本号加 1，update 中的版本号被忽略
+func (tc *TableConvention) buildUpdate(ctx context.Context, d dialect, table string, where map[string]interface{}, update map[string]interface{}) (string, []interface{}, error) {
+	where = tc.filter(ctx, where)
+	if tc == nil || tc.Version == "" {
+		return d.buildUpdate(table, where, update)
+	}
+	q := string(d.identQuote())
+	column := q + tc.Version + q
+	update = copyWhere(update, tc.Version)
+	if len(update) == 0 {
+		// 只更新版本号时 gendry 不能生成空的 SET，以版本号占位后替换为自增，去掉占位的值
+		cond, values, err := d.buildUpdate(table, where, map[string]interface{}{tc.Version: 0})
+		if err != nil {
+			return "", nil, err
+		}
+		set := strings.Index(cond, " SET ") + len(" SET ")
+		end := set + strings.Index(cond[set:], "=?") + len("=?")
+		return cond[:set] + column + "=" + column + "+1" + cond[end:], values[1:], nil
+	}
+	cond, values, err := d.buildUpdate(table, where, update)
+	if err != nil {
+		return "", nil, err
+	}
+	// gendry 的 SET 不支持表达式，在第一个字段前加上版本号
+	return strings.Replace(cond, " SET ", " SET "+column+"="+column+"+1,", 1), values, nil
+}
+
+// buildDelete 软删除的表改为设置删除时间，只处理未删除的行
+func (tc *TableConvention) buildDelete(ctx context.Context, d dialect, table string, where map[string]interface{}) (string, []interface{}, error) {
+	if !tc.softDelete(ctx) {
+		return d.buildDelete(table, where)
+	}
+	return d.buildUpdate(table, tc.filter(ctx, where), map[string]interface{}{tc.SoftDelete: time.Now()})
+}
+
+// checkConflict 带版本号的 Update 没有更新任何行时返回 ErrConflict
+func checkConflict(c *client, b Builder, res sql.Result) error {
+	ub, ok := b.(*UpdateBuilder)
+	if !ok || !clientConvention(c, ub.table, ub.convention).checksVersion(ub.where) {
+		return nil
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrConflict, ub.table)
+	}
+	return nil
+}
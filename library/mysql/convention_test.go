@@ -0,0 +1,134 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 19:30:05
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 11:06:48
+ * @Description: 乐观锁与软删除用例
+ */
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/didi/gendry/builder"
+)
+
+var userConvention = TableConvention{Table: "tb_user", Version: "version", SoftDelete: "deleted_at"}
+
+func TestConventionSQL(t *testing.T) {
+	ctx := context.Background()
+	c := New(&Config{Name: "convention_sql", Conventions: []TableConvention{userConvention}})
+
+	cond, values, err := NewUpdateBuilder("tb_user", map[string]interface{}{"id": 1, "version": 3}, map[string]interface{}{"name": "a", "version": 9}).CompileContext(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "UPDATE tb_user SET `version`=`version`+1,name=? WHERE (id=? AND version=? AND deleted_at IS NULL)"; cond != want || !reflect.DeepEqual(values, []interface{}{"a", 1, 3}) {
+		t.Errorf("got %s %v", cond, values)
+	}
+	// 只更新版本号
+	for _, update := range []map[string]interface{}{nil, {"version": 9}} {
+		cond, values, err = NewUpdateBuilder("tb_user", map[string]interface{}{"id": 1, "version": 3}, update).CompileContext(ctx, c)
+		if want := "UPDATE tb_user SET `version`=`version`+1 WHERE (id=? AND version=? AND deleted_at IS NULL)"; err != nil || cond != want || !reflect.DeepEqual(values, []interface{}{1, 3}) {
+			t.Errorf("got %s %v %v", cond, values, err)
+		}
+	}
+	cond, _, err = NewSelectBuilder("tb_user", map[string]interface{}{"id": 1}, []string{"id"}).CompileContext(WithDeleted(ctx), c)
+	if err != nil || cond != "SELECT id FROM tb_user WHERE (id=?)" {
+		t.Errorf("got %s %v", cond, err)
+	}
+	cond, _, err = NewDeleteBuilder("tb_user", map[string]interface{}{"id": 1}).CompileContext(ctx, c)
+	if err != nil || cond != "UPDATE tb_user SET deleted_at=? WHERE (id=? AND deleted_at IS NULL)" {
+		t.Errorf("got %s %v", cond, err)
+	}
+	// 未配置约定的表不受影响
+	cond, _, err = NewDeleteBuilder("tb_order", map[string]interface{}{"id": 1}).CompileContext(ctx, c)
+	if err != nil || cond != "DELETE FROM tb_order WHERE (id=?)" {
+		t.Errorf("got %s %v", cond, err)
+	}
+}
+
+func TestConvention(t *testing.T) {
+	ctx := context.Background()
+	c := newDialectClient(DriverSQLite)
+	c.conf.Name = "convention_test"
+	c.conventions = map[string]*TableConvention{"tb_user": &userConvention}
+	defer c.Close()
+
+	if _, err := c.ExecRaw(ctx, "CREATE TABLE tb_user (id INTEGER PRIMARY KEY, name TEXT, version INTEGER NOT NULL DEFAULT 0, deleted_at DATETIME NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Insert(ctx, "tb_user", []map[string]interface{}{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一版本号只能更新一次
+	where := map[string]interface{}{"id": 1, "version": 0}
+	if _, err := c.Update(ctx, "tb_user", where, map[string]interface{}{"name": "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Update(ctx, "tb_user", where, map[string]interface{}{"name": "a2"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	// 不带版本号时只加 1，不检查冲突，update 中的版本号被忽略
+	if _, err := c.Update(ctx, "tb_user", map[string]interface{}{"id": 3}, map[string]interface{}{"name": "c"}); err != nil {
+		t.Errorf("unexpected err %v", err)
+	}
+	if _, err := c.Update(ctx, "tb_user", map[string]interface{}{"id": 1}, map[string]interface{}{"name": "a3", "version": 100}); err != nil {
+		t.Fatal(err)
+	}
+	type user struct {
+		ID      int64  `ddb:"id"`
+		Name    string `ddb:"name"`
+		Version int64  `ddb:"version"`
+	}
+	u, err := Get[user](ctx, c, "tb_user", map[string]interface{}{"id": 1})
+	if err != nil || u.Name != "a3" || u.Version != 2 {
+		t.Errorf("unexpected user %+v %v", u, err)
+	}
+	// 只更新版本号，如标记数据已被读取
+	if _, err := c.Update(ctx, "tb_user", map[string]interface{}{"id": 2, "version": 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := Get[user](ctx, c, "tb_user", map[string]interface{}{"id": 2}); err != nil || u.Name != "b" || u.Version != 1 {
+		t.Errorf("unexpected user %+v %v", u, err)
+	}
+
+	// 软删除后查询、更新不再包含该行
+	if _, err := c.Delete(ctx, "tb_user", map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get[user](ctx, c, "tb_user", map[string]interface{}{"id": 1}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := c.Update(ctx, "tb_user", map[string]interface{}{"id": 1, "version": 2}, map[string]interface{}{"name": "x"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	if n, err := Count(ctx, c, "tb_user", nil); err != nil || n != 1 {
+		t.Errorf("unexpected count %d %v", n, err)
+	}
+	if n, err := Count(WithDeleted(ctx), c, "tb_user", nil); err != nil || n != 2 {
+		t.Errorf("unexpected count with deleted %d %v", n, err)
+	}
+	if n, err := Count(ctx, c, "tb_user", map[string]interface{}{"deleted_at": builder.IsNotNull}); err != nil || n != 1 {
+		t.Errorf("unexpected deleted count %d %v", n, err)
+	}
+
+	// 事务中同样生效
+	err = c.Transaction(ctx, func(tx Tx) error {
+		_, err := tx.Delete(ctx, "tb_user", map[string]interface{}{"id": 2})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Delete(HardDelete(ctx), "tb_user", map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Count(WithDeleted(ctx), c, "tb_user", nil); err != nil || n != 1 {
+		t.Errorf("unexpected count after hard delete %d %v", n, err)
+	}
+}
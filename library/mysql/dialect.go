@@ -0,0 +1,498 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 14:05:44
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 13:10:45
+ * @Description: 数据库方言：mysql、sqlite、postgres 的连接、占位符、标识符引号与 upsert 语法
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/didi/gendry/builder"
+	"github.com/didi/gendry/manager"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/liziwei01/gin-lib/library/env"
+)
+
+// 配置中 DBDriver 可选的值，为空时为 mysql
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// sqlite 内存数据库的 DBName
+const sqliteMemory = ":memory:"
+
+// mysql、postgres 表不存在的错误码
+const (
+	errNoSuchTable   = 1146
+	errPgNoSuchTable = "42P01"
+)
+
+// dialect 数据库方言，builder 按 gendry 的规则生成 sql，再由方言处理各数据库的差异
+type dialect interface {
+	name() string
+	open(c *client, host string, port int) (*sql.DB, error)
+	// instance 监控指标中实例的名称
+	instance(c *client, host string, port int) string
+	buildSelect(table string, where map[string]interface{}, fields []string) (string, []interface{}, error)
+	buildInsert(ctx context.Context, table string, data []map[string]interface{}, typ int, update map[string]interface{}) (string, []interface{}, error)
+	buildUpdate(table string, where map[string]interface{}, update map[string]interface{}) (string, []interface{}, error)
+	buildDelete(table string, where map[string]interface{}) (string, []interface{}, error)
+	// rebind 将 ? 占位符转换为驱动要求的格式
+	rebind(query string) string
+	// identQuote 标识符的引号
+	identQuote() byte
+}
+
+var (
+	mysqlDialect    dialect = mysqlDialectImpl{}
+	sqliteDialect   dialect = &ansiDialect{driver: DriverSQLite}
+	postgresDialect dialect = &ansiDialect{driver: DriverPostgres, numbered: true}
+)
+
+// dialectOf DBDriver 对应的方言，不支持的驱动返回错误
+func dialectOf(driver string) (dialect, error) {
+	switch strings.ToLower(driver) {
+	case "", DriverMySQL:
+		return mysqlDialect, nil
+	case DriverSQLite, "sqlite3":
+		return sqliteDialect, nil
+	case DriverPostgres, "postgresql", "pg":
+		return postgresDialect, nil
+	}
+	return nil, fmt.Errorf("mysql: unsupported DBDriver %q", driver)
+}
+
+// dialect 配置的方言，不支持的驱动在连接时报错，这里按 mysql 处理
+func (c *client) dialect() dialect {
+	if c.conf == nil {
+		return mysqlDialect
+	}
+	d, err := dialectOf(c.dbdriver())
+	if err != nil {
+		return mysqlDialect
+	}
+	return d
+}
+
+// clientDialect builder 使用的方言，其他 Client 的实现（如 mysqltest.Fake）为 mysql
+func clientDialect(c Client) dialect {
+	if dao, ok := c.(*client); ok {
+		return dao.dialect()
+	}
+	return mysqlDialect
+}
+
+// Driver Client 使用的数据库，DriverMySQL、DriverSQLite 或 DriverPostgres
+//
+//	其他 Client 的实现（如 mysqltest.Fake）为 DriverMySQL
+func Driver(c Client) string {
+	return clientDialect(c).name()
+}
+
+// IsNoSuchTable 是否为表不存在的错误
+func IsNoSuchTable(err error) bool {
+	var (
+		me *mysqldriver.MySQLError
+		pe *pq.Error
+	)
+	switch {
+	case errors.As(err, &me):
+		return me.Number == errNoSuchTable
+	case errors.As(err, &pe):
+		return pe.Code == errPgNoSuchTable
+	}
+	// sqlite 的错误码不区分表不存在，按错误信息判断
+	return err != nil && strings.Contains(err.Error(), "no such table")
+}
+
+type ctxConflictKey struct{}
+
+// OnConflict 标记 ctx 中 upsert 冲突判断的字段，用于 sqlite、postgres
+//
+//	mysql 的 ON DUPLICATE KEY UPDATE 对任意唯一键生效，postgres 必须指定一个唯一约束，
+//	未指定时使用主键约束 <table>_pkey；mysql 忽略该标记
+func OnConflict(ctx context.Context, columns ...string) context.Context {
+	return context.WithValue(ctx, ctxConflictKey{}, columns)
+}
+
+func conflictColumns(ctx context.Context) []string {
+	columns, _ := ctx.Value(ctxConflictKey{}).([]string)
+	return columns
+}
+
+// mysqlDialectImpl 即 gendry 的默认行为
+type mysqlDialectImpl struct{}
+
+func (mysqlDialectImpl) name() string {
+	return DriverMySQL
+}
+
+func (mysqlDialectImpl) open(c *client, host string, port int) (*sql.DB, error) {
+	// 内含 retry 2
+	return manager.New(c.dbname(), c.username(), c.password(), host).Set(
+		manager.SetCharset(c.charset()),
+		manager.SetAllowCleartextPasswords(true),
+		manager.SetAllowNativePasswords(true),
+		manager.SetInterpolateParams(true),
+		manager.SetAllowAllFiles(true),
+		manager.SetParseTime(true),
+		manager.SetLoc(time.Local.String()),
+		manager.SetTimeout(time.Duration(c.timeout())*time.Millisecond),
+		manager.SetReadTimeout(time.Duration(c.readTimeOut())*time.Millisecond),
+		manager.SetWriteTimeout(time.Duration(c.writeTimeOut())*time.Millisecond),
+		manager.SetCollation(c.collation()),
+	).Port(port).Open(true)
+}
+
+func (mysqlDialectImpl) instance(c *client, host string, port int) string {
+	return instance(host, port)
+}
+
+func (mysqlDialectImpl) buildSelect(table string, where map[string]interface{}, fields []string) (string, []interface{}, error) {
+	return builder.BuildSelect(table, where, fields)
+}
+
+func (mysqlDialectImpl) buildInsert(ctx context.Context, table string, data []map[string]interface{}, typ int, update map[string]interface{}) (string, []interface{}, error) {
+	switch typ {
+	case insertIgnore:
+		return builder.BuildInsertIgnore(table, data)
+	case insertReplace:
+		return builder.BuildReplaceInsert(table, data)
+	case insertOnDuplicate:
+		return builder.BuildInsertOnDuplicate(table, data, update)
+	}
+	return builder.BuildInsert(table, data)
+}
+
+func (mysqlDialectImpl) buildUpdate(table string, where map[string]interface{}, update map[string]interface{}) (string, []interface{}, error) {
+	return builder.BuildUpdate(table, where, update)
+}
+
+func (mysqlDialectImpl) buildDelete(table string, where map[string]interface{}) (string, []interface{}, error) {
+	return builder.BuildDelete(table, where)
+}
+
+func (mysqlDialectImpl) rebind(query string) string {
+	return query
+}
+
+func (mysqlDialectImpl) identQuote() byte {
+	return '`'
+}
+
+// ansiDialect sqlite 与 postgres，标识符使用双引号，upsert 使用 ON CONFLICT
+//
+//	表名、字段名、where 的字段、_orderby、_groupby 中的普通标识符会加上引号，表达式（如 COUNT(*) AS n）保持不变
+type ansiDialect struct {
+	driver string
+	// 占位符为 $1、$2
+	numbered bool
+}
+
+func (d *ansiDialect) name() string {
+	return d.driver
+}
+
+func (d *ansiDialect) open(c *client, host string, port int) (*sql.DB, error) {
+	if d.driver == DriverSQLite {
+		dsn, err := sqliteDSN(c)
+		if err != nil {
+			return nil, err
+		}
+		return sql.Open(DriverSQLite, dsn)
+	}
+	db, err := sql.Open(DriverPostgres, postgresDSN(c, host, port))
+	if err != nil {
+		return nil, err
+	}
+	// 与 mysql 一致，创建时检查连接
+	ctx := context.Background()
+	if c.timeout() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.timeout())*time.Millisecond)
+		defer cancel()
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// sqliteDSN DBName 为数据库文件，相对路径基于 data 目录；:memory: 为同一 servicer 共享的内存数据库
+func sqliteDSN(c *client) (string, error) {
+	if c.dbname() == sqliteMemory {
+		return "file:" + url.PathEscape(c.name()) + "?mode=memory&cache=shared&_pragma=foreign_keys(1)&_time_format=sqlite", nil
+	}
+	path := c.dbname()
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(env.DataDir(), path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	busy := c.timeout()
+	if busy <= 0 {
+		busy = 5000
+	}
+	return "file:" + path + "?_pragma=busy_timeout(" + strconv.Itoa(busy) + ")&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_time_format=sqlite", nil
+}
+
+// postgresDSN sslmode 默认 disable，Timeout 为连接超时
+func postgresDSN(c *client, host string, port int) string {
+	q := url.Values{}
+	sslmode := c.sslmode()
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+	q.Set("sslmode", sslmode)
+	if c.timeout() > 0 {
+		q.Set("connect_timeout", strconv.Itoa(int(math.Ceil(float64(c.timeout())/1000))))
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.username(), c.password()),
+		Host:     instance(host, port),
+		Path:     "/" + c.dbname(),
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+func (d *ansiDialect) identQuote() byte {
+	return '"'
+}
+
+func (d *ansiDialect) instance(c *client, host string, port int) string {
+	if d.driver == DriverSQLite {
+		return c.dbname()
+	}
+	return instance(host, port)
+}
+
+// identRegexp 可加引号的普通标识符，如 id、tb_user.id
+var identRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// quote 普通标识符加上双引号，其他表达式保持不变
+func (d *ansiDialect) quote(ident string) string {
+	if !identRegexp.MatchString(ident) {
+		return ident
+	}
+	parts := strings.Split(ident, ".")
+	for i, p := range parts {
+		parts[i] = `"` + p + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+func (d *ansiDialect) quoteFields(fields []string) []string {
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		out[i] = d.quote(strings.TrimSpace(f))
+	}
+	return out
+}
+
+// quoteList _orderby、_groupby 形如 "id desc, name"
+func (d *ansiDialect) quoteList(list string) string {
+	items := strings.Split(list, ",")
+	for i, item := range items {
+		words := strings.Fields(item)
+		if len(words) > 0 {
+			words[0] = d.quote(words[0])
+		}
+		items[i] = strings.Join(words, " ")
+	}
+	return strings.Join(items, ", ")
+}
+
+// quoteMap 复制 where 或写入的数据，字段名加上引号
+func (d *ansiDialect) quoteMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		switch {
+		case k == "_orderby" || k == "_groupby":
+			if s, ok := v.(string); ok {
+				v = d.quoteList(s)
+			}
+			out[k] = v
+		case k == "_having":
+			if h, ok := v.(map[string]interface{}); ok {
+				v = d.quoteMap(h)
+			}
+			out[k] = v
+		case strings.HasPrefix(k, "_or"):
+			if ors, ok := v.([]map[string]interface{}); ok {
+				quoted := make([]map[string]interface{}, len(ors))
+				for i, or := range ors {
+					quoted[i] = d.quoteMap(or)
+				}
+				v = quoted
+			}
+			out[k] = v
+		case strings.HasPrefix(k, "_"):
+			out[k] = v
+		default:
+			key := strings.TrimSpace(k)
+			if i := strings.IndexByte(key, ' '); i >= 0 {
+				key = d.quote(key[:i]) + key[i:]
+			} else {
+				key = d.quote(key)
+			}
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// buildSelect postgres 不支持 LIMIT ?,? 与 LOCK IN SHARE MODE，由方言生成；sqlite 没有行锁，忽略 _lockMode
+func (d *ansiDialect) buildSelect(table string, where map[string]interface{}, fields []string) (string, []interface{}, error) {
+	w := d.quoteMap(where)
+	limit, hasLimit := w["_limit"]
+	lockMode, _ := w["_lockMode"].(string)
+	delete(w, "_lockMode")
+	if d.numbered {
+		delete(w, "_limit")
+	}
+	cond, values, err := builder.BuildSelect(d.quote(table), w, d.quoteFields(fields))
+	if err != nil {
+		return "", nil, err
+	}
+	if d.numbered && hasLimit {
+		l, ok := limit.([]uint)
+		if !ok || len(l) == 0 || len(l) > 2 {
+			return "", nil, fmt.Errorf(`mysql: the value of "_limit" must be []uint with one or two elements`)
+		}
+		offset, count := uint(0), l[0]
+		if len(l) == 2 {
+			offset, count = l[0], l[1]
+		}
+		cond += " LIMIT ? OFFSET ?"
+		values = append(values, int(count), int(offset))
+	}
+	if d.numbered {
+		switch lockMode {
+		case "exclusive":
+			cond += " FOR UPDATE"
+		case "shared":
+			cond += " FOR SHARE"
+		}
+	}
+	return cond, values, nil
+}
+
+// buildInsert 冲突时的处理使用 ON CONFLICT
+//
+//	InsertIgnore 为 DO NOTHING；InsertOnDuplicate 为 DO UPDATE SET；
+//	InsertReplace 在 sqlite 中为 REPLACE INTO，在 postgres 中为更新冲突行的所有字段
+func (d *ansiDialect) buildInsert(ctx context.Context, table string, data []map[string]interface{}, typ int, update map[string]interface{}) (string, []interface{}, error) {
+	rows := make([]map[string]interface{}, len(data))
+	for i, row := range data {
+		rows[i] = d.quoteMap(row)
+	}
+	if typ == insertReplace && !d.numbered {
+		return builder.BuildReplaceInsert(d.quote(table), rows)
+	}
+	cond, values, err := builder.BuildInsert(d.quote(table), rows)
+	if err != nil {
+		return "", nil, err
+	}
+	target := ""
+	if columns := conflictColumns(ctx); len(columns) > 0 {
+		target = " (" + strings.Join(d.quoteFields(columns), ",") + ")"
+	} else if d.numbered {
+		target = " ON CONSTRAINT " + d.quote(strings.ReplaceAll(table, ".", "_")+"_pkey")
+	}
+	switch typ {
+	case insertIgnore:
+		cond += " ON CONFLICT DO NOTHING"
+	case insertReplace:
+		var sets []string
+		for _, k := range sortedKeys(rows[0]) {
+			sets = append(sets, k+"=EXCLUDED."+k)
+		}
+		cond += " ON CONFLICT" + target + " DO UPDATE SET " + strings.Join(sets, ",")
+	case insertOnDuplicate:
+		if len(update) == 0 {
+			return "", nil, fmt.Errorf("mysql: empty update for %s", table)
+		}
+		upd := d.quoteMap(update)
+		var sets []string
+		for _, k := range sortedKeys(upd) {
+			sets = append(sets, k+"=?")
+			values = append(values, upd[k])
+		}
+		cond += " ON CONFLICT" + target + " DO UPDATE SET " + strings.Join(sets, ",")
+	}
+	return cond, values, nil
+}
+
+func (d *ansiDialect) buildUpdate(table string, where map[string]interface{}, update map[string]interface{}) (string, []interface{}, error) {
+	return builder.BuildUpdate(d.quote(table), d.quoteMap(where), d.quoteMap(update))
+}
+
+func (d *ansiDialect) buildDelete(table string, where map[string]interface{}) (string, []interface{}, error) {
+	return builder.BuildDelete(d.quote(table), d.quoteMap(where))
+}
+
+// rebind postgres 的占位符为 $1、$2，跳过字符串与带引号的标识符中的 ?
+func (d *ansiDialect) rebind(query string) string {
+	if !d.numbered || !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		b     strings.Builder
+		n     int
+		quote byte
+	)
+	b.Grow(len(query) + 8)
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,169 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 15:12:09
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 11:14:52
+ * @Description: sqlite、postgres 方言用例
+ */
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newDialectClient(driver string) *client {
+	conf := &Config{Name: "dialect_" + driver}
+	conf.MySQL.DBDriver = driver
+	conf.MySQL.DBName = sqliteMemory
+	conf.Pool.HealthCheckInterval = -1
+	return New(conf).(*client)
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	c := newDialectClient(DriverSQLite)
+	defer c.Close()
+
+	// order 为关键字，需加引号
+	_, err := c.ExecRaw(ctx, `CREATE TABLE "order" (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE, "group" TEXT, score REAL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Insert(ctx, "order", []map[string]interface{}{
+		{"name": "a", "group": "x", "score": 1.5},
+		{"name": "b", "group": "y", "score": 2.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := res.LastInsertId(); id != 2 {
+		t.Errorf("unexpected last insert id %d", id)
+	}
+	// sqlite 的 LastInsertId 为最后一行，结构体的自增主键不回填
+	type named struct {
+		ID   int64  `ddb:"id,autoincr"`
+		Name string `ddb:"name"`
+	}
+	if _, err := c.ExecRaw(ctx, `CREATE TABLE named (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE)`); err != nil {
+		t.Fatal(err)
+	}
+	rows := []*named{{Name: "a"}, {Name: "b"}}
+	if _, err := InsertStructs(ctx, c, "named", rows); err != nil || rows[0].ID != 0 || rows[1].ID != 0 {
+		t.Errorf("sqlite ids should not be filled, got %d %d %v", rows[0].ID, rows[1].ID, err)
+	}
+	if _, err := BatchInsertStructs(ctx, c, "named", []*named{{Name: "c"}, {Name: "d"}}); err != nil {
+		t.Fatal(err)
+	}
+	u := &named{Name: "a"}
+	if _, err := Upsert(OnConflict(ctx, "name"), c, "named", u); err != nil || u.ID != 0 {
+		t.Errorf("sqlite upsert should not fill a stale id, got %d %v", u.ID, err)
+	}
+	if res, err = c.InsertIgnore(ctx, "order", []map[string]interface{}{{"name": "a", "group": "z", "score": 0.0}}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 0 {
+		t.Errorf("insert ignore affected %d", n)
+	}
+	_, err = c.InsertOnDuplicate(OnConflict(ctx, "name"), "order", []map[string]interface{}{{"name": "b", "group": "y", "score": 0.0}}, map[string]interface{}{"score": 9.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.InsertReplace(ctx, "order", []map[string]interface{}{{"name": "c", "group": "x", "score": 3.0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	type order struct {
+		ID    int64   `ddb:"id"`
+		Name  string  `ddb:"name"`
+		Group string  `ddb:"group"`
+		Score float64 `ddb:"score"`
+	}
+	var orders []order
+	err = c.Query(ctx, "order", map[string]interface{}{"group in": []string{"x", "y"}, "_orderby": "score desc", "_limit": []uint{0, 2}}, []string{"id", "name", "group", "score"}, &orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].Name != "b" || orders[0].Score != 9.5 || orders[1].Name != "c" {
+		t.Errorf("unexpected orders %+v", orders)
+	}
+
+	errAbort := errors.New("abort")
+	err = c.Transaction(ctx, func(tx Tx) error {
+		if _, err := tx.Delete(ctx, "order", nil); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("unexpected err %v", err)
+	}
+	n, err := Count(ctx, c, "order", map[string]interface{}{"group": "x"})
+	if err != nil || n != 2 {
+		t.Errorf("unexpected count %d %v", n, err)
+	}
+}
+
+func TestPostgresSQL(t *testing.T) {
+	ctx := context.Background()
+	c := newDialectClient(DriverPostgres)
+
+	cases := []struct {
+		builder Builder
+		ctx     context.Context
+		sql     string
+		values  []interface{}
+	}{
+		{
+			NewSelectBuilder("tb_user", map[string]interface{}{"name like": "a%", "_orderby": "id desc", "_limit": []uint{20, 10}, "_lockMode": "shared"}, []string{"id", "COUNT(*) AS n"}),
+			ctx,
+			`SELECT "id",COUNT(*) AS n FROM "tb_user" WHERE ("name" LIKE $1) ORDER BY "id" desc LIMIT $2 OFFSET $3 FOR SHARE`,
+			[]interface{}{"a%", 10, 20},
+		},
+		{
+			NewInsertBuilder("tb_user", []map[string]interface{}{{"id": 1, "name": "a"}}, insertOnDuplicate, map[string]interface{}{"name": "b"}),
+			ctx,
+			`INSERT INTO "tb_user" ("id","name") VALUES ($1,$2) ON CONFLICT ON CONSTRAINT "tb_user_pkey" DO UPDATE SET "name"=$3`,
+			[]interface{}{1, "a", "b"},
+		},
+		{
+			NewInsertBuilder("tb_user", []map[string]interface{}{{"email": "e", "name": "a"}}, insertReplace),
+			OnConflict(ctx, "email"),
+			`INSERT INTO "tb_user" ("email","name") VALUES ($1,$2) ON CONFLICT ("email") DO UPDATE SET "email"=EXCLUDED."email","name"=EXCLUDED."name"`,
+			[]interface{}{"e", "a"},
+		},
+		{
+			NewInsertBuilder("tb_user", []map[string]interface{}{{"name": "a"}}, insertIgnore),
+			ctx,
+			`INSERT INTO "tb_user" ("name") VALUES ($1) ON CONFLICT DO NOTHING`,
+			[]interface{}{"a"},
+		},
+		{
+			NewUpdateBuilder("tb_user", map[string]interface{}{"_or": []map[string]interface{}{{"id": 1}, {"user.name": "a"}}}, map[string]interface{}{"note": "?"}),
+			ctx,
+			`UPDATE "tb_user" SET "note"=$1 WHERE ((("id"=$2) OR ("user"."name"=$3)))`,
+			[]interface{}{"?", 1, "a"},
+		},
+		{
+			NewRawBuilder(`SELECT '?' FROM t WHERE a = ? AND "b?" = ?`, []interface{}{1, 2}),
+			ctx,
+			`SELECT '?' FROM t WHERE a = $1 AND "b?" = $2`,
+			[]interface{}{1, 2},
+		},
+	}
+	for _, cs := range cases {
+		cond, values, err := cs.builder.CompileContext(cs.ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.dialect().rebind(cond); got != cs.sql || !reflect.DeepEqual(values, cs.values) {
+			t.Errorf("got %s %v, want %s %v", got, values, cs.sql, cs.values)
+		}
+	}
+
+	if _, err := dialectOf("oracle"); err == nil {
+		t.Error("expected unsupported driver")
+	}
+}
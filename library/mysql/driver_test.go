@@ -0,0 +1,172 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 17:10:26
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 17:10:26
+ * @Description: 记录 sql 的假驱动，单测不依赖 mysql
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeConnector 记录执行的语句，execErr 返回非空时该语句执行失败，pingErr 非空时健康检查失败
+//
+//	rows 为查询返回的数据，lastInsertID 为写入返回的自增 id
+type fakeConnector struct {
+	mu           sync.Mutex
+	log          []string
+	execErr      func(query string) error
+	pingErr      error
+	rows         func(query string, args []driver.NamedValue) *fakeRows
+	lastInsertID int64
+}
+
+func (fc *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{fc: fc}, nil
+}
+
+func (fc *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (fc *fakeConnector) record(s string) {
+	fc.mu.Lock()
+	fc.log = append(fc.log, s)
+	fc.mu.Unlock()
+}
+
+// statements 执行过的语句，只保留第一个单词，BEGIN 与 SAVEPOINT 相关保留完整语句
+func (fc *fakeConnector) statements() string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	out := make([]string, 0, len(fc.log))
+	for _, s := range fc.log {
+		if !strings.HasPrefix(s, "BEGIN") && !strings.Contains(s, "SAVEPOINT") {
+			s = strings.Fields(s)[0]
+		}
+		out = append(out, s)
+	}
+	fc.log = nil
+	return strings.Join(out, "; ")
+}
+
+type fakeConn struct {
+	fc *fakeConnector
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	c.fc.mu.Lock()
+	defer c.fc.mu.Unlock()
+	if c.fc.pingErr != nil {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	s := "BEGIN"
+	if opts.Isolation != 0 {
+		s += " " + sql.IsolationLevel(opts.Isolation).String()
+	}
+	if opts.ReadOnly {
+		s += " READONLY"
+	}
+	c.fc.record(s)
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.fc.record("COMMIT")
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.fc.record("ROLLBACK")
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.fc.record(query)
+	if c.fc.execErr != nil {
+		if err := c.fc.execErr(query); err != nil {
+			return nil, err
+		}
+	}
+	return fakeResult{id: c.fc.lastInsertID, affected: 1}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.fc.record(query)
+	if c.fc.execErr != nil {
+		if err := c.fc.execErr(query); err != nil {
+			return nil, err
+		}
+	}
+	if c.fc.rows == nil {
+		return &fakeRows{}, nil
+	}
+	return c.fc.rows(query, args), nil
+}
+
+type fakeResult struct {
+	id       int64
+	affected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.id, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+// fakeRows 查询返回的数据
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	idx     int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.idx])
+	r.idx++
+	return nil
+}
+
+func newFakeClient(retry int) (*client, *fakeConnector) {
+	fc := &fakeConnector{}
+	conf := &Config{Name: "fake", Retry: retry}
+	return &client{conf: conf, db: sql.OpenDB(fc), redact: compileRedact("")}, fc
+}
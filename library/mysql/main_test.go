@@ -0,0 +1,32 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-23 14:38:19
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 14:38:19
+ * @Description: 测试入口，没有可用的数据库配置时跳过集成测试
+ */
+package mysql
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// mysql_test.go 中依赖 db_lib_user 的集成测试，获取 client 失败时会 panic 导致整个包的测试中止
+const integrationTests = "^(TestQuery|TestInsert|TestUpdate|TestDelete|TestExecRaw)$"
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if _, err := GetClient(context.Background(), "db_lib_user"); err != nil {
+		skip := integrationTests
+		if s := flag.Lookup("test.skip").Value.String(); s != "" {
+			skip = s + "|" + skip
+		}
+		flag.Set("test.skip", skip)
+		fmt.Fprintf(os.Stderr, "skip mysql integration tests: %v\n", err)
+	}
+	os.Exit(m.Run())
+}
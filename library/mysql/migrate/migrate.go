@@ -0,0 +1,404 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 10:40:19
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 13:10:45
+ * @Description: mysql 表结构迁移，已执行的版本记录在 schema_migrations 表
+ */
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/env"
+	"github.com/liziwei01/gin-lib/library/lock"
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/mysql"
+)
+
+const (
+	// DefaultTable 记录已执行版本的表
+	DefaultTable = "schema_migrations"
+	// DefaultLockTimeout 等待其他实例迁移完成的最长时间
+	DefaultLockTimeout = 30 * time.Second
+
+	// 迁移文件的目录，conf/migrations/<servicer>/
+	migrationsDir = "migrations"
+	lockKeyPrefix = "migrate:"
+	lockTTL       = time.Minute
+	lockRetry     = 500 * time.Millisecond
+)
+
+var (
+	// ErrDirty 上一次迁移执行到一半失败，需人工修复后删除或更新 schema_migrations 中 dirty 的记录
+	ErrDirty = errors.New("migrate: dirty migration")
+	// ErrNoDown 回滚的版本没有 down 文件
+	ErrNoDown = errors.New("migrate: no down migration")
+	// ErrLocked 其他实例正在迁移，等待超时
+	ErrLocked = errors.New("migrate: locked by another instance")
+	// ErrNoLocker postgres 没有默认的锁，需通过 WithLocker 指定
+	ErrNoLocker = errors.New("migrate: no default locker for postgres, use WithLocker")
+)
+
+// Option 迁移的选项
+type Option interface {
+	apply(*Migrator)
+}
+
+type funcOption struct {
+	f func(*Migrator)
+}
+
+func (fo *funcOption) apply(m *Migrator) {
+	fo.f(m)
+}
+
+func newFuncOption(f func(*Migrator)) *funcOption {
+	return &funcOption{
+		f: f,
+	}
+}
+
+// WithDryRun 只输出将要执行的 sql，不执行、不加锁、不创建 schema_migrations
+func WithDryRun(w io.Writer) Option {
+	return newFuncOption(func(m *Migrator) {
+		m.dryRun = w
+	})
+}
+
+// WithTable 记录已执行版本的表，默认 DefaultTable
+func WithTable(table string) Option {
+	return newFuncOption(func(m *Migrator) {
+		m.table = table
+	})
+}
+
+// WithLocker 防止多个实例同时迁移的锁
+//
+//	mysql 默认为基于 GET_LOCK 的 lock.NewMySQLLocker；sqlite 默认为进程内的 lock.NewMemoryLocker，
+//	不能防止多个进程同时迁移同一个库文件；postgres 没有默认的锁，必须指定，否则返回 ErrNoLocker
+func WithLocker(locker lock.Locker) Option {
+	return newFuncOption(func(m *Migrator) {
+		m.locker = locker
+	})
+}
+
+// WithLockTimeout 等待锁的最长时间，默认 DefaultLockTimeout
+func WithLockTimeout(d time.Duration) Option {
+	return newFuncOption(func(m *Migrator) {
+		m.lockTimeout = d
+	})
+}
+
+// Migrator 执行一个 mysql servicer 的迁移
+//
+//	mysql 的 DDL 不能回滚，每个版本执行前在 schema_migrations 中记录 dirty，全部语句成功后清除；
+//	存在 dirty 的记录时拒绝继续执行，返回 ErrDirty
+type Migrator struct {
+	client      mysql.Client
+	dir         string
+	name        string
+	table       string
+	locker      lock.Locker
+	lockTimeout time.Duration
+	dryRun      io.Writer
+}
+
+// New 读取 dir 中的迁移文件，在 client 上执行，name 用于锁与日志
+func New(client mysql.Client, name, dir string, opts ...Option) *Migrator {
+	m := &Migrator{
+		client:      client,
+		dir:         dir,
+		name:        name,
+		table:       DefaultTable,
+		lockTimeout: DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt.apply(m)
+	}
+	if m.locker == nil {
+		switch mysql.Driver(client) {
+		case mysql.DriverMySQL:
+			m.locker = lock.NewMySQLLocker(client, lockKeyPrefix)
+		case mysql.DriverSQLite:
+			m.locker = lock.NewMemoryLocker()
+		}
+	}
+	return m
+}
+
+// NewServicer 使用 mysql.GetClient(servicer) 的客户端，迁移文件在 conf/migrations/<servicer>/
+func NewServicer(ctx context.Context, servicer string, opts ...Option) (*Migrator, error) {
+	client, err := mysql.GetClient(ctx, servicer)
+	if err != nil {
+		return nil, err
+	}
+	return New(client, servicer, Dir(servicer), opts...), nil
+}
+
+// Dir servicer 的迁移文件目录
+func Dir(servicer string) string {
+	return filepath.Join(env.ConfDir(), migrationsDir, servicer)
+}
+
+// Servicers conf/migrations 下有迁移文件目录的 servicer，目录不存在时为空
+func Servicers() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(env.ConfDir(), migrationsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var servicers []string
+	for _, e := range entries {
+		if e.IsDir() {
+			servicers = append(servicers, e.Name())
+		}
+	}
+	return servicers, nil
+}
+
+// Status 一个版本的状态
+type Status struct {
+	Version uint64
+	Name    string
+	Applied bool
+	// 执行到一半失败
+	Dirty bool
+	// 已执行但迁移文件已删除
+	Missing bool
+}
+
+type record struct {
+	Version uint64 `ddb:"version"`
+	Name    string `ddb:"name"`
+	Dirty   bool   `ddb:"dirty"`
+}
+
+// Up 按版本号顺序执行所有未执行的版本，返回执行的版本
+//
+//	比已执行的最大版本小的新版本（如合并分支产生的）同样会执行
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := m.run(ctx, func(ctx context.Context, migrations []*Migration, applied map[uint64]record) error {
+		for _, mg := range migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, mg, "up"); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 从最大的版本开始回滚 steps 个已执行的版本，返回回滚的版本
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.run(ctx, func(ctx context.Context, migrations []*Migration, applied map[uint64]record) error {
+		byVersion := make(map[uint64]*Migration, len(migrations))
+		for _, mg := range migrations {
+			byVersion[mg.Version] = mg
+		}
+		versions := make([]uint64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i] > versions[j]
+		})
+		for _, v := range versions {
+			if len(done) >= steps {
+				break
+			}
+			mg, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("%w: version %d has no migration file", ErrNoDown, v)
+			}
+			if !mg.hasDown {
+				return fmt.Errorf("%w: %s", ErrNoDown, mg.File("down"))
+			}
+			if err := m.apply(ctx, mg, "down"); err != nil {
+				return err
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status 所有版本的状态，按版本号排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := Load(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Status
+	for _, mg := range migrations {
+		r, ok := applied[mg.Version]
+		out = append(out, Status{Version: mg.Version, Name: mg.Name, Applied: ok, Dirty: r.Dirty})
+		delete(applied, mg.Version)
+	}
+	for _, r := range applied {
+		out = append(out, Status{Version: r.Version, Name: r.Name, Applied: true, Dirty: r.Dirty, Missing: true})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Version < out[j].Version
+	})
+	return out, nil
+}
+
+// run 加锁后读取迁移文件与已执行的版本，dry run 时不加锁
+func (m *Migrator) run(ctx context.Context, fn func(ctx context.Context, migrations []*Migration, applied map[uint64]record) error) error {
+	migrations, err := Load(m.dir)
+	if err != nil {
+		return err
+	}
+	if m.dryRun == nil {
+		var lease *lock.Lease
+		ctx, lease, err = m.lock(ctx)
+		if err != nil {
+			return err
+		}
+		defer lease.Release(context.WithoutCancel(ctx))
+		if err := m.createTable(ctx); err != nil {
+			return err
+		}
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range applied {
+		if r.Dirty {
+			return fmt.Errorf("%w: version %d in %s", ErrDirty, r.Version, m.table)
+		}
+	}
+	return fn(ctx, migrations, applied)
+}
+
+// lock 等待其他实例迁移完成，超过 lockTimeout 返回 ErrLocked
+func (m *Migrator) lock(ctx context.Context) (context.Context, *lock.Lease, error) {
+	if m.locker == nil {
+		return ctx, nil, ErrNoLocker
+	}
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		lockCtx, lease, err := lock.Hold(ctx, m.locker, lockKeyPrefix+m.name, lockTTL)
+		if err == nil {
+			return lockCtx, lease, nil
+		}
+		if !errors.Is(err, lock.ErrNotObtained) {
+			return ctx, nil, err
+		}
+		if time.Now().After(deadline) {
+			return ctx, nil, ErrLocked
+		}
+		select {
+		case <-ctx.Done():
+			return ctx, nil, ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+}
+
+// createTable 创建 schema_migrations，sqlite、postgres 没有 UNSIGNED、TINYINT 与表选项
+func (m *Migrator) createTable(ctx context.Context) error {
+	if mysql.Driver(m.client) == mysql.DriverMySQL {
+		_, err := m.client.ExecRaw(ctx, "CREATE TABLE IF NOT EXISTS "+m.table+" ("+
+			"version BIGINT UNSIGNED NOT NULL PRIMARY KEY, "+
+			"name VARCHAR(255) NOT NULL DEFAULT '', "+
+			"dirty TINYINT(1) NOT NULL DEFAULT 0, "+
+			"applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+		return err
+	}
+	_, err := m.client.ExecRaw(ctx, "CREATE TABLE IF NOT EXISTS "+m.table+" ("+
+		"version BIGINT NOT NULL PRIMARY KEY, "+
+		"name VARCHAR(255) NOT NULL DEFAULT '', "+
+		"dirty SMALLINT NOT NULL DEFAULT 0, "+
+		"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)")
+	return err
+}
+
+// applied 已执行的版本，dry run 时表不存在视为没有执行过
+//
+//	读主库：锁在主库上，从库的延迟会导致重复执行某个版本或漏掉 dirty 的记录
+func (m *Migrator) applied(ctx context.Context) (map[uint64]record, error) {
+	records, err := mysql.Select[record](mysql.UsePrimary(ctx), m.client, m.table, map[string]interface{}{"_orderby": "version"})
+	if mysql.IsNoSuchTable(err) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[uint64]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// apply 执行一个版本的 up 或 down，执行前标记 dirty，成功后清除
+func (m *Migrator) apply(ctx context.Context, mg *Migration, direction string) error {
+	content := mg.up
+	if direction == "down" {
+		content = mg.down
+	}
+	stmts := splitStatements(content)
+	if m.dryRun != nil {
+		fmt.Fprintf(m.dryRun, "-- %s\n", mg.File(direction))
+		for _, stmt := range stmts {
+			fmt.Fprintf(m.dryRun, "%s;\n", stmt)
+		}
+		return nil
+	}
+
+	where := map[string]interface{}{"version": mg.Version}
+	var err error
+	if direction == "up" {
+		_, err = m.client.Insert(ctx, m.table, []map[string]interface{}{{"version": mg.Version, "name": mg.Name, "dirty": 1}})
+	} else {
+		_, err = m.client.Update(ctx, m.table, where, map[string]interface{}{"dirty": 1})
+	}
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	for i, stmt := range stmts {
+		if _, err := m.client.ExecRaw(ctx, stmt); err != nil {
+			return fmt.Errorf("migrate: %s statement %d: %w", mg.File(direction), i+1, err)
+		}
+	}
+	if direction == "up" {
+		_, err = m.client.Update(ctx, m.table, where, map[string]interface{}{"dirty": 0})
+	} else {
+		_, err = m.client.Delete(ctx, m.table, where)
+	}
+	if err != nil {
+		return err
+	}
+	logit.Svr().Notice(ctx, "mysql migration applied",
+		logit.String("servicer", m.name),
+		logit.String("file", mg.File(direction)),
+		logit.Int("statements", len(stmts)),
+		logit.Duration("cost", time.Since(start)),
+	)
+	return nil
+}
@@ -0,0 +1,220 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 11:48:13
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 13:10:45
+ * @Description: 迁移用例
+ */
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/lock"
+	"github.com/liziwei01/gin-lib/library/mysql"
+	"github.com/liziwei01/gin-lib/library/mysql/mysqltest"
+)
+
+const createTable = "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT UNSIGNED NOT NULL PRIMARY KEY, " +
+	"name VARCHAR(255) NOT NULL DEFAULT '', dirty TINYINT(1) NOT NULL DEFAULT 0, " +
+	"applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements(`
+-- 用户表; 注释中的分号
+CREATE TABLE tb_user (id INT, note VARCHAR(8) DEFAULT 'a;b'); # 行尾注释
+/* 块注释; */ INSERT INTO tb_user VALUES (1, "it\"s;");
+ALTER TABLE ` + "`tb;user`" + ` ADD COLUMN n INT;;
+`)
+	want := []string{
+		"CREATE TABLE tb_user (id INT, note VARCHAR(8) DEFAULT 'a;b')",
+		`INSERT INTO tb_user VALUES (1, "it\"s;")`,
+		"ALTER TABLE `tb;user` ADD COLUMN n INT",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected statements %q", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"2_add_name.up.sql":     "ALTER TABLE t ADD name INT;",
+		"1_create.up.sql":       "CREATE TABLE t (id INT);",
+		"1_create.down.sql":     "DROP TABLE t;",
+		"README.md":             "ignored",
+		"10_add_index.up.sql":   "CREATE INDEX i ON t (name);",
+		"10_add_index.down.sql": "DROP INDEX i ON t;",
+	})
+	migrations, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []uint64
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	if !reflect.DeepEqual(versions, []uint64{1, 2, 10}) || migrations[1].hasDown || migrations[2].Name != "add_index" {
+		t.Errorf("unexpected migrations %v", versions)
+	}
+
+	dir = writeFiles(t, map[string]string{"1_create.down.sql": "DROP TABLE t;"})
+	if _, err := Load(dir); err == nil {
+		t.Error("expected missing up error")
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	dir := writeFiles(t, map[string]string{
+		"1_create.up.sql":     "CREATE TABLE t (id INT);",
+		"1_create.down.sql":   "DROP TABLE t;",
+		"2_add_name.up.sql":   "ALTER TABLE t ADD name INT;\nCREATE INDEX i ON t (name);",
+		"2_add_name.down.sql": "ALTER TABLE t DROP name;",
+	})
+	f := mysqltest.New()
+	m := New(f, "db_test", dir, WithLocker(lock.NewMemoryLocker()))
+
+	f.ExpectExec(createTable)
+	f.ExpectQuery("SELECT version,name,dirty FROM schema_migrations ORDER BY version").
+		WillReturnRows([]string{"version", "name", "dirty"}, []interface{}{1, "create", 0})
+	f.ExpectExec("INSERT INTO schema_migrations (dirty,name,version) VALUES (?,?,?)").WithArgs(1, "add_name", 2)
+	f.ExpectExec("ALTER TABLE t ADD name INT")
+	f.ExpectExec("CREATE INDEX i ON t (name)")
+	f.ExpectExec("UPDATE schema_migrations SET dirty=? WHERE (version=?)").WithArgs(0, 2)
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Errorf("unexpected done %v", done)
+	}
+	f.AssertExpectations(t)
+
+	f = mysqltest.New()
+	m = New(f, "db_test", dir, WithLocker(lock.NewMemoryLocker()))
+	f.ExpectExec(createTable)
+	f.ExpectQuery("SELECT version,name,dirty FROM schema_migrations ORDER BY version").
+		WillReturnRows([]string{"version", "name", "dirty"}, []interface{}{1, "create", 0}, []interface{}{2, "add_name", 0})
+	f.ExpectExec("UPDATE schema_migrations SET dirty=? WHERE (version=?)").WithArgs(1, 2)
+	f.ExpectExec("ALTER TABLE t DROP name")
+	f.ExpectExec("DELETE FROM schema_migrations WHERE (version=?)").WithArgs(2)
+	if done, err = m.Down(ctx, 1); err != nil || len(done) != 1 {
+		t.Fatalf("unexpected down %v %v", done, err)
+	}
+	f.AssertExpectations(t)
+}
+
+func TestDirtyAndLock(t *testing.T) {
+	ctx := context.Background()
+	dir := writeFiles(t, map[string]string{"1_create.up.sql": "CREATE TABLE t (id INT);"})
+	f := mysqltest.New()
+	f.ExpectExec(createTable)
+	f.ExpectQuery("SELECT version,name,dirty FROM schema_migrations ORDER BY version").
+		WillReturnRows([]string{"version", "name", "dirty"}, []interface{}{1, "create", 1})
+	locker := lock.NewMemoryLocker()
+	m := New(f, "db_test", dir, WithLocker(locker))
+	if _, err := m.Up(ctx); !errors.Is(err, ErrDirty) {
+		t.Errorf("expected dirty, got %v", err)
+	}
+
+	// 其他实例持有锁
+	l, err := locker.TryLock(ctx, lockKeyPrefix+"db_test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Release(ctx)
+	m = New(mysqltest.New(), "db_test", dir, WithLocker(locker), WithLockTimeout(0))
+	if _, err := m.Up(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected locked, got %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+	dir := writeFiles(t, map[string]string{
+		"1_create.up.sql":   "CREATE TABLE t (id INT);",
+		"2_add_name.up.sql": "ALTER TABLE t ADD name INT; -- 名称",
+	})
+	f := mysqltest.New()
+	if _, err := f.Insert(ctx, DefaultTable, []map[string]interface{}{{"version": 1, "name": "create", "dirty": 0}}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	m := New(f, "db_test", dir, WithDryRun(&out))
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-- 2_add_name.up.sql\nALTER TABLE t ADD name INT;\n"
+	if len(done) != 1 || out.String() != want {
+		t.Errorf("unexpected dry run %d %q", len(done), out.String())
+	}
+	// 只读取了 schema_migrations
+	if n := len(f.Statements()); n != 2 {
+		t.Errorf("unexpected statements %v", f.Statements())
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	ctx := context.Background()
+	dir := writeFiles(t, map[string]string{
+		"1_create.up.sql":   "CREATE TABLE t (id INTEGER);",
+		"1_create.down.sql": "DROP TABLE t;",
+		"2_add_name.up.sql": "ALTER TABLE t ADD name TEXT;",
+	})
+	conf := &mysql.Config{Name: "migrate_sqlite"}
+	conf.MySQL.DBDriver = mysql.DriverSQLite
+	conf.MySQL.DBName = ":memory:"
+	conf.Pool.HealthCheckInterval = -1
+	client := mysql.New(conf)
+	defer client.Close()
+
+	// 表不存在时视为没有执行过
+	m := New(client, "migrate_sqlite", dir)
+	status, err := m.Status(ctx)
+	if err != nil || len(status) != 2 || status[0].Applied {
+		t.Fatalf("unexpected status %+v %v", status, err)
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 2 {
+		t.Fatalf("unexpected up %v %v", done, err)
+	}
+	if _, err := client.ExecRaw(ctx, "INSERT INTO t (id, name) VALUES (1, 'a')"); err != nil {
+		t.Fatal(err)
+	}
+	if status, err = m.Status(ctx); err != nil || !status[1].Applied || status[1].Dirty {
+		t.Errorf("unexpected status %+v %v", status, err)
+	}
+	if _, err := m.Down(ctx, 2); !errors.Is(err, ErrNoDown) {
+		t.Errorf("expected ErrNoDown, got %v", err)
+	}
+}
+
+func TestPostgresLocker(t *testing.T) {
+	conf := &mysql.Config{Name: "migrate_postgres"}
+	conf.MySQL.DBDriver = mysql.DriverPostgres
+	conf.Pool.HealthCheckInterval = -1
+	client := mysql.New(conf)
+	defer client.Close()
+	dir := writeFiles(t, map[string]string{"1_create.up.sql": "CREATE TABLE t (id INT);"})
+	if _, err := New(client, "migrate_postgres", dir).Up(context.Background()); !errors.Is(err, ErrNoLocker) {
+		t.Errorf("expected ErrNoLocker, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 10:12:37
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 10:12:37
+ * @Description: 读取迁移文件，拆分 sql 语句
+ */
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 文件名形如 20261021101500_create_user.up.sql、20261021101500_create_user.down.sql
+var fileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	// 版本号，文件名的数字前缀，按从小到大的顺序执行
+	Version uint64
+	// 名称，文件名中版本号之后的部分
+	Name string
+
+	up      string
+	down    string
+	hasDown bool
+}
+
+// File 迁移文件名，direction 为 up 或 down
+func (m *Migration) File(direction string) string {
+	return fmt.Sprintf("%d_%s.%s.sql", m.Version, m.Name, direction)
+}
+
+// Load 读取目录中的迁移文件，按版本号排序
+//
+//	每个版本必须有 up 文件，down 文件可选；目录不存在时返回错误
+func Load(dir string) ([]*Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := fileRegexp.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %s: %w", e.Name(), err)
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d has different names %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+			m.hasDown = true
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.up) == "" {
+			return nil, fmt.Errorf("migrate: %s is missing or empty", m.File("up"))
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements 按分号拆分 sql，忽略字符串与注释中的分号，去掉注释与空语句
+//
+//	go-sql-driver 默认不允许一次执行多条语句，因此逐条执行；不支持 DELIMITER
+func splitStatements(content string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+		quote byte
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case quote != 0:
+			cur.WriteByte(ch)
+			if ch == '\\' && quote != '`' && i+1 < len(content) {
+				i++
+				cur.WriteByte(content[i])
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			cur.WriteByte(ch)
+		case ch == '#' || isDashComment(content[i:]):
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			cur.WriteByte('\n')
+		case ch == '/' && strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				i = len(content)
+			} else {
+				i += end + 3
+			}
+			cur.WriteByte(' ')
+		case ch == ';':
+			flush()
+		default:
+			cur.WriteByte(ch)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// isDashComment mysql 的 -- 注释需后跟空白
+func isDashComment(s string) bool {
+	return strings.HasPrefix(s, "--") && (len(s) == 2 || s[2] == ' ' || s[2] == '\t' || s[2] == '\n' || s[2] == '\r')
+}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/didi/gendry/scanner"
 	_ "github.com/go-sql-driver/mysql"
@@ -48,45 +49,120 @@ func (dao *client) ExecRaw(ctx context.Context, sql string, args ...interface{})
 	return ExecWithBuilder(ctx, dao, builder)
 }
 
-// QueryWithBuilder 传入一个 SQLBuilder 并执行 QueryContext
-func QueryWithBuilder(ctx context.Context, client Client, builder Builder, data interface{}) error {
-	db, err := client.connect(ctx)
+func (dao *client) QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*Rows, error) {
+	db, err := dao.connectRead(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	cond, values, err := builder.CompileContext(ctx, client)
+	return queryRows(ctx, dao, db, NewSelectBuilder(tableName, where, columns))
+}
+
+func (dao *client) QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error) {
+	return QueryPage(ctx, dao, tableName, where, columns, page, data)
+}
+
+func (dao *client) QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error) {
+	return QueryCursor(ctx, dao, tableName, where, columns, page, data)
+}
+
+func (dao *client) Conn(ctx context.Context) (*sql.Conn, error) {
+	db, err := dao.connect(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	rows, err := db.QueryContext(ctx, cond, values...)
-	if err != nil {
-		return err
+	return db.Conn(ctx)
+}
+
+// QueryWithBuilder 传入一个 SQLBuilder 并执行 QueryContext
+//
+//	配置了从库时读从库，ctx 经 UsePrimary 标记后读主库
+//	数据库不可用时按配置的 Retry 重试，连续失败后熔断，熔断时返回 resilience.ErrOpen
+//	其他 Client 的实现（如 mysqltest.Fake）按 Builder 的类型调用 Query 方法
+func QueryWithBuilder(ctx context.Context, c Client, builder Builder, data interface{}) error {
+	dao, ok := c.(*client)
+	if !ok {
+		return sessionQuery(ctx, c, builder, data)
 	}
-	return scanner.ScanClose(rows, data)
+	return dao.policy.Do(ctx, retryableRead, func(ctx context.Context) error {
+		db, err := dao.connectRead(ctx)
+		if err != nil {
+			return err
+		}
+		return queryWithBuilder(ctx, dao, db, builder, data)
+	})
 }
 
-func ExecWithBuilder(ctx context.Context, client Client, builder Builder) (sql.Result, error) {
-	db, err := client.connect(ctx)
-	if err != nil {
-		return nil, err
+// ExecWithBuilder 传入一个 SQLBuilder 并在主库执行 ExecContext
+//
+//	其他 Client 的实现（如 mysqltest.Fake）按 Builder 的类型调用 Insert、Update、Delete、ExecRaw 方法
+//	表配置了乐观锁时，where 中带版本号的 Update 没有更新任何行返回 ErrConflict
+//	只重试确定没有执行的错误，熔断同 QueryWithBuilder
+func ExecWithBuilder(ctx context.Context, c Client, builder Builder) (sql.Result, error) {
+	dao, ok := c.(*client)
+	if !ok {
+		return sessionExec(ctx, c, builder)
 	}
+	var res sql.Result
+	err := dao.policy.Do(ctx, retryableWrite, func(ctx context.Context) error {
+		db, err := dao.connect(ctx)
+		if err != nil {
+			return err
+		}
+		res, err = execWithBuilder(ctx, dao, db, builder)
+		return err
+	})
+	return res, err
+}
+
+// executor *sql.DB 与 *sql.Tx 共同的方法
+type executor interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func queryWithBuilder(ctx context.Context, client *client, db executor, builder Builder, data interface{}) error {
 	cond, values, err := builder.CompileContext(ctx, client)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return db.ExecContext(ctx, cond, values...)
+	ctx, span := startSpan(ctx, client, cond)
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, client.dialect().rebind(cond), values...)
+	if err != nil {
+		log(ctx, client, cond, values, time.Since(start), err)
+		endSpan(span, err)
+		return err
+	}
+	err = scanner.ScanClose(rows, data)
+	// 查询结果为空不视为 sql 执行失败
+	if err == scanner.ErrEmptyResult {
+		log(ctx, client, cond, values, time.Since(start), nil)
+		endSpan(span, nil)
+	} else {
+		log(ctx, client, cond, values, time.Since(start), err)
+		endSpan(span, err)
+	}
+	return err
 }
 
-func Execraw(ctx context.Context, client Client, builder Builder) (sql.Result, error) {
-	db, err := client.connect(ctx)
+func execWithBuilder(ctx context.Context, client *client, db executor, builder Builder) (sql.Result, error) {
+	cond, values, err := builder.CompileContext(ctx, client)
 	if err != nil {
 		return nil, err
 	}
-	cond, values, err := builder.CompileContext(ctx, client)
+	ctx, span := startSpan(ctx, client, cond)
+	start := time.Now()
+	res, err := db.ExecContext(ctx, client.dialect().rebind(cond), values...)
+	log(ctx, client, cond, values, time.Since(start), err)
+	endSpan(span, err)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
-	return db.ExecContext(ctx, cond, values...)
+	return res, checkConflict(client, builder, res)
+}
+
+func Execraw(ctx context.Context, c Client, builder Builder) (sql.Result, error) {
+	return ExecWithBuilder(ctx, c, builder)
 }
 
 var _ Client = (*client)(nil)
@@ -0,0 +1,586 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 23:20:48
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 23:20:48
+ * @Description: 在内存中计算 gendry 风格的 where、排序、分页与聚合
+ */
+package mysqltest
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/didi/gendry/builder"
+)
+
+// mysql 时间的格式
+const timeLayout = "2006-01-02 15:04:05.999999"
+
+// where 中不参与条件计算的 key
+var specialKeys = map[string]bool{
+	"_orderby":  true,
+	"_groupby":  true,
+	"_having":   true,
+	"_limit":    true,
+	"_lockMode": true,
+}
+
+// normalize 转换为 mysql 驱动返回的类型：int64、float64、[]byte、time.Time 或 nil
+func normalize(v interface{}) (interface{}, error) {
+	if nt, ok := v.(builder.NullType); ok {
+		return nt, nil
+	}
+	dv, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return nil, err
+	}
+	switch val := dv.(type) {
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return append([]byte(nil), val...), nil
+	case bool:
+		if val {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return dv, nil
+}
+
+// compare 比较两个值，有 NULL 时 ok 为 false
+//
+//	字符串按 *_ci 排序规则忽略大小写，数字与字符串比较时字符串转换为数字
+func compare(a, b interface{}) (c int, ok bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmpOrdered(x, y), true
+		}
+		return cmpOrdered(float64(x), toFloat(b)), true
+	case float64:
+		return cmpOrdered(x, toFloat(b)), true
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y), true
+		}
+		if y, err := time.ParseInLocation(timeLayout, toString(b), x.Location()); err == nil {
+			return x.Compare(y), true
+		}
+		return cmpOrdered(x.Format(timeLayout), toString(b)), true
+	case []byte:
+		switch b.(type) {
+		case int64, float64, time.Time:
+			c, ok := compare(b, a)
+			return -c, ok
+		}
+		return bytes.Compare(bytes.ToLower(x), bytes.ToLower([]byte(toString(b)))), true
+	}
+	return cmpOrdered(toString(a), toString(b)), true
+}
+
+func cmpOrdered[T int64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case int64:
+		return float64(val)
+	case float64:
+		return val
+	case time.Time:
+		f, _ := strconv.ParseFloat(val.Format("20060102150405"), 64)
+		return f
+	}
+	// 与 mysql 一致，无法转换的字符串视为 0
+	f, _ := strconv.ParseFloat(strings.TrimSpace(toString(v)), 64)
+	return f
+}
+
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.Format(timeLayout)
+	}
+	return fmt.Sprint(v)
+}
+
+// likeRegexp 将 like 的模式转换为正则，忽略大小写
+func likeRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// splitKey 同 gendry：key 的第一个空格前为字段名，之后为操作符，没有操作符时为 = 或 in
+func splitKey(key string, val interface{}) (field, op string) {
+	key = strings.TrimSpace(key)
+	idx := strings.IndexByte(key, ' ')
+	if idx == -1 {
+		if val != nil && reflect.ValueOf(val).Kind() == reflect.Slice {
+			if _, ok := val.([]byte); !ok {
+				return key, "in"
+			}
+		}
+		return key, "="
+	}
+	return key[:idx], strings.ToLower(strings.TrimSpace(key[idx+1:]))
+}
+
+func toSlice(val interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("mysqltest: the value of in/between must be a slice, got %T", val)
+	}
+	out := make([]interface{}, v.Len())
+	for i := range out {
+		n, err := normalize(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// matchWhere 行是否满足 where，column 用于检查字段是否存在
+func matchWhere(row map[string]interface{}, where map[string]interface{}, column func(string) error) (bool, error) {
+	for key, val := range where {
+		if specialKeys[key] {
+			continue
+		}
+		if strings.HasPrefix(key, "_or") {
+			ors, ok := val.([]map[string]interface{})
+			if !ok {
+				return false, fmt.Errorf(`mysqltest: the value of "_or" must be []map[string]interface{}, got %T`, val)
+			}
+			matched := false
+			for _, w := range ors {
+				if w == nil {
+					continue
+				}
+				ok, err := matchWhere(row, w, column)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false, nil
+			}
+			continue
+		}
+		field, op := splitKey(key, val)
+		if err := column(field); err != nil {
+			return false, err
+		}
+		ok, err := matchCond(row[field], op, val)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchCond(v interface{}, op string, val interface{}) (bool, error) {
+	if nt, ok := val.(builder.NullType); ok {
+		return (v == nil) == (nt == builder.IsNull), nil
+	}
+	switch op {
+	case "in", "not in":
+		list, err := toSlice(val)
+		if err != nil {
+			return false, err
+		}
+		if v == nil {
+			return false, nil
+		}
+		found := false
+		for _, item := range list {
+			if c, ok := compare(v, item); ok && c == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (op == "in"), nil
+	case "between", "not between":
+		list, err := toSlice(val)
+		if err != nil {
+			return false, err
+		}
+		if len(list) != 2 {
+			return false, fmt.Errorf("mysqltest: the value of between must contain 2 elements")
+		}
+		lo, ok1 := compare(v, list[0])
+		hi, ok2 := compare(v, list[1])
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return (lo >= 0 && hi <= 0) == (op == "between"), nil
+	case "like", "not like":
+		if v == nil {
+			return false, nil
+		}
+		re, err := likeRegexp(toString(mustNormalize(val)))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(toString(v)) == (op == "like"), nil
+	}
+	n, err := normalize(val)
+	if err != nil {
+		return false, err
+	}
+	c, ok := compare(v, n)
+	if !ok {
+		return false, nil
+	}
+	switch op {
+	case "=":
+		return c == 0, nil
+	case "!=", "<>":
+		return c != 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	}
+	return false, fmt.Errorf("mysqltest: unsupported operator %q", op)
+}
+
+func mustNormalize(v interface{}) interface{} {
+	n, err := normalize(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return n
+}
+
+type orderBy struct {
+	column string
+	desc   bool
+}
+
+// parseOrderBy 解析 _orderby，如 "id desc, name"
+func parseOrderBy(where map[string]interface{}) ([]orderBy, error) {
+	val, ok := where["_orderby"]
+	if !ok {
+		return nil, nil
+	}
+	s, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf(`mysqltest: the value of "_orderby" must be string, got %T`, val)
+	}
+	var out []orderBy
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Fields(part)
+		switch {
+		case len(fields) == 0:
+			continue
+		case len(fields) == 1:
+			out = append(out, orderBy{column: fields[0]})
+		case len(fields) == 2 && strings.EqualFold(fields[1], "desc"):
+			out = append(out, orderBy{column: fields[0], desc: true})
+		case len(fields) == 2 && strings.EqualFold(fields[1], "asc"):
+			out = append(out, orderBy{column: fields[0]})
+		default:
+			return nil, fmt.Errorf("mysqltest: unsupported _orderby %q", s)
+		}
+	}
+	return out, nil
+}
+
+// sortRows 稳定排序
+func sortRows(rows []map[string]interface{}, orders []orderBy) {
+	if len(orders) == 0 {
+		return
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessRow(rows[i], rows[j], orders)
+	})
+}
+
+// lessRow 按 orders 比较两行，NULL 排在最前，与 mysql 一致
+func lessRow(a, b map[string]interface{}, orders []orderBy) bool {
+	for _, o := range orders {
+		x, y := a[o.column], b[o.column]
+		var c int
+		switch {
+		case x == nil && y == nil:
+			c = 0
+		case x == nil:
+			c = -1
+		case y == nil:
+			c = 1
+		default:
+			c, _ = compare(x, y)
+		}
+		if c == 0 {
+			continue
+		}
+		if o.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	return false
+}
+
+// parseLimit 解析 _limit，返回 offset 与条数，没有时 n 为 -1
+func parseLimit(where map[string]interface{}) (offset, n int, err error) {
+	val, ok := where["_limit"]
+	if !ok {
+		return 0, -1, nil
+	}
+	switch l := val.(type) {
+	case []uint:
+		switch len(l) {
+		case 1:
+			return 0, int(l[0]), nil
+		case 2:
+			return int(l[0]), int(l[1]), nil
+		}
+	case int, int64, uint, uint64:
+		// update、delete 的 _limit
+		return 0, int(reflect.ValueOf(l).Convert(reflect.TypeOf(int64(0))).Int()), nil
+	}
+	return 0, 0, fmt.Errorf(`mysqltest: unsupported "_limit" %v`, val)
+}
+
+func limitRows[T any](rows []T, offset, n int) []T {
+	if offset >= len(rows) {
+		return rows[:0]
+	}
+	rows = rows[offset:]
+	if n >= 0 && n < len(rows) {
+		rows = rows[:n]
+	}
+	return rows
+}
+
+// column 查询的一个字段或聚合表达式
+type column struct {
+	// 结果中的字段名：别名，没有别名时为表达式
+	name string
+	// 聚合函数，小写，不是聚合时为空
+	fn string
+	// 字段名，COUNT(*) 为 *
+	field string
+}
+
+var columnRegexp = regexp.MustCompile(`(?i)^(?:(count|sum|max|min|avg)\(\s*(\*|[\w.]+)\s*\)|([\w.]+))(?:\s+(?:as\s+)?(\w+))?$`)
+
+func parseColumns(columns []string, all []string) ([]column, error) {
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+	var out []column
+	for _, expr := range columns {
+		expr = strings.TrimSpace(expr)
+		if expr == "*" {
+			for _, c := range all {
+				out = append(out, column{name: c, field: c})
+			}
+			continue
+		}
+		m := columnRegexp.FindStringSubmatch(expr)
+		if m == nil {
+			return nil, fmt.Errorf("mysqltest: unsupported column %q", expr)
+		}
+		c := column{name: m[4], fn: strings.ToLower(m[1]), field: m[2]}
+		if c.fn == "" {
+			c.field = m[3]
+		}
+		if c.name == "" {
+			c.name = expr
+		}
+		if c.field == "*" && c.fn != "count" {
+			return nil, fmt.Errorf("mysqltest: unsupported column %q", expr)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func hasAggregate(columns []column) bool {
+	for _, c := range columns {
+		if c.fn != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregateValue 计算一组行的聚合值，SUM、AVG 与 mysql 一样返回 DECIMAL 的字符串
+func aggregateValue(c column, rows []map[string]interface{}) interface{} {
+	if c.fn == "count" {
+		n := int64(0)
+		for _, r := range rows {
+			if c.field == "*" || r[c.field] != nil {
+				n++
+			}
+		}
+		return n
+	}
+	var (
+		result   interface{}
+		sum      float64
+		count    int
+		intsOnly = true
+	)
+	for _, r := range rows {
+		v := r[c.field]
+		if v == nil {
+			continue
+		}
+		count++
+		switch c.fn {
+		case "max", "min":
+			if result == nil {
+				result = v
+				continue
+			}
+			if cmp, _ := compare(v, result); (c.fn == "max" && cmp > 0) || (c.fn == "min" && cmp < 0) {
+				result = v
+			}
+		default:
+			if _, ok := v.(int64); !ok {
+				intsOnly = false
+			}
+			sum += toFloat(v)
+		}
+	}
+	switch c.fn {
+	case "sum":
+		if count == 0 {
+			return nil
+		}
+		if intsOnly {
+			return []byte(strconv.FormatInt(int64(sum), 10))
+		}
+		return []byte(strconv.FormatFloat(sum, 'f', -1, 64))
+	case "avg":
+		if count == 0 {
+			return nil
+		}
+		return []byte(strconv.FormatFloat(sum/float64(count), 'f', 4, 64))
+	}
+	return result
+}
+
+// project 计算查询的字段，有聚合或 _groupby 时按组计算
+func project(rows []map[string]interface{}, columns []column, groupBy []string) [][]interface{} {
+	if len(groupBy) == 0 && !hasAggregate(columns) {
+		out := make([][]interface{}, len(rows))
+		for i, r := range rows {
+			vals := make([]interface{}, len(columns))
+			for j, c := range columns {
+				vals[j] = r[c.field]
+			}
+			out[i] = vals
+		}
+		return out
+	}
+	// 分组的顺序为组内第一行出现的顺序
+	var (
+		keys   []string
+		groups = map[string][]map[string]interface{}{}
+	)
+	for _, r := range rows {
+		var kb strings.Builder
+		for _, g := range groupBy {
+			fmt.Fprintf(&kb, "%T:%s\x00", r[g], strings.ToLower(toString(r[g])))
+		}
+		k := kb.String()
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], r)
+	}
+	if len(groupBy) == 0 && len(keys) == 0 {
+		// 没有分组时，空表的聚合也返回一行
+		keys, groups[""] = []string{""}, nil
+	}
+	out := make([][]interface{}, 0, len(keys))
+	for _, k := range keys {
+		g := groups[k]
+		vals := make([]interface{}, len(columns))
+		for j, c := range columns {
+			if c.fn != "" {
+				vals[j] = aggregateValue(c, g)
+			} else if len(g) > 0 {
+				vals[j] = g[0][c.field]
+			}
+		}
+		out = append(out, vals)
+	}
+	return out
+}
+
+func parseGroupBy(where map[string]interface{}) ([]string, error) {
+	val, ok := where["_groupby"]
+	if !ok {
+		return nil, nil
+	}
+	s, ok := val.(string)
+	if !ok {
+		return nil, fmt.Errorf(`mysqltest: the value of "_groupby" must be string, got %T`, val)
+	}
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
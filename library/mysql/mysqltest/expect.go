@@ -0,0 +1,188 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 23:48:06
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 23:48:06
+ * @Description: 期望模式：按顺序断言生成的 sql 与参数
+ */
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 语句的类型
+const (
+	kindQuery    = "query"
+	kindExec     = "exec"
+	kindBegin    = "begin"
+	kindCommit   = "commit"
+	kindRollback = "rollback"
+)
+
+// Expectation 期望执行的一条语句
+//
+//	添加了期望后，Fake 不再读写内存中的数据，每条语句需按顺序与期望匹配，
+//	匹配时返回期望中设置的结果，不匹配时返回错误，并在 ExpectationsWereMet 中报告
+type Expectation struct {
+	kind    string
+	sql     string
+	args    []interface{}
+	anyArgs bool
+
+	columns []string
+	rows    [][]interface{}
+	result  sql.Result
+	err     error
+
+	met bool
+}
+
+// ExpectQuery 期望执行查询，sql 为 gendry 生成的语句，忽略多余的空白
+func (f *Fake) ExpectQuery(sql string) *Expectation {
+	return f.addExpectation(kindQuery, sql)
+}
+
+// ExpectExec 期望执行写入、更新、删除或 ExecRaw
+func (f *Fake) ExpectExec(sql string) *Expectation {
+	return f.addExpectation(kindExec, sql)
+}
+
+// ExpectBegin 期望开始事务
+func (f *Fake) ExpectBegin() *Expectation {
+	return f.addExpectation(kindBegin, "BEGIN")
+}
+
+// ExpectCommit 期望提交事务
+func (f *Fake) ExpectCommit() *Expectation {
+	return f.addExpectation(kindCommit, "COMMIT")
+}
+
+// ExpectRollback 期望回滚事务
+func (f *Fake) ExpectRollback() *Expectation {
+	return f.addExpectation(kindRollback, "ROLLBACK")
+}
+
+func (f *Fake) addExpectation(kind, sql string) *Expectation {
+	e := &Expectation{kind: kind, sql: normalizeSQL(sql), anyArgs: true, result: result{}}
+	f.mu.Lock()
+	f.expects = append(f.expects, e)
+	f.mu.Unlock()
+	return e
+}
+
+// WithArgs 期望的参数，未调用时不比对参数
+//
+//	参数按 mysql 驱动的规则转换后比对，如 int 与 int64 相等
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.anyArgs = false
+	return e
+}
+
+// WillReturnRows 查询返回的数据，每个 row 的值与 columns 一一对应
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = make([][]interface{}, len(rows))
+	for i, row := range rows {
+		e.rows[i] = make([]interface{}, len(row))
+		for j, v := range row {
+			e.rows[i][j] = mustNormalize(v)
+		}
+	}
+	return e
+}
+
+// WillReturnResult 写入、更新、删除返回的结果
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return e
+}
+
+// WillReturnError 执行返回的错误
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) String() string {
+	if e.anyArgs {
+		return fmt.Sprintf("%s %q", e.kind, e.sql)
+	}
+	return fmt.Sprintf("%s %q with args %v", e.kind, e.sql, e.args)
+}
+
+// ExpectationsWereMet 所有期望都已按顺序执行，且没有多余的语句
+func (f *Fake) ExpectationsWereMet() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var msgs []string
+	msgs = append(msgs, f.unexpected...)
+	for _, e := range f.expects {
+		if !e.met {
+			msgs = append(msgs, "expected "+e.String()+" was not executed")
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("mysqltest: %s", strings.Join(msgs, "; "))
+}
+
+// AssertExpectations 同 ExpectationsWereMet，未满足时标记测试失败
+func (f *Fake) AssertExpectations(t testing.TB) {
+	t.Helper()
+	if err := f.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// expect 记录执行的语句；期望模式下与下一个期望比对，非期望模式返回 nil, nil，需持有 f.mu
+func (f *Fake) expect(ctx context.Context, kind, sql string, args []interface{}) (*Expectation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.statements = append(f.statements, Statement{SQL: sql, Args: args})
+	if len(f.expects) == 0 {
+		return nil, nil
+	}
+	var next *Expectation
+	for _, e := range f.expects {
+		if !e.met {
+			next = e
+			break
+		}
+	}
+	got := fmt.Sprintf("%s %q with args %v", kind, normalizeSQL(sql), args)
+	if next == nil {
+		f.unexpected = append(f.unexpected, "unexpected "+got)
+		return nil, fmt.Errorf("mysqltest: unexpected %s, all expectations were already met", got)
+	}
+	if next.kind != kind || next.sql != normalizeSQL(sql) || (!next.anyArgs && !sameArgs(next.args, args)) {
+		f.unexpected = append(f.unexpected, "unexpected "+got+", want "+next.String())
+		return nil, fmt.Errorf("mysqltest: unexpected %s, want %s", got, next.String())
+	}
+	next.met = true
+	return next, nil
+}
+
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+func sameArgs(want, got []interface{}) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if !reflect.DeepEqual(mustNormalize(want[i]), mustNormalize(got[i])) {
+			return false
+		}
+	}
+	return true
+}
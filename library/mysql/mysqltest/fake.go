@@ -0,0 +1,873 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 23:20:48
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 23:20:48
+ * @Description: 单测用的 mysql.Client，数据保存在内存中，不依赖 mysql
+ */
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/didi/gendry/builder"
+	"github.com/didi/gendry/scanner"
+	driver "github.com/go-sql-driver/mysql"
+
+	"github.com/liziwei01/gin-lib/library/mysql"
+)
+
+// mysql 的错误码
+const (
+	errDupEntry      = 1062
+	errUnknownColumn = 1054
+)
+
+// ErrNotSupported Fake 不支持的操作
+var ErrNotSupported = errors.New("mysqltest: not supported")
+
+// Fake 数据保存在内存中的 mysql.Client
+//
+//	支持 gendry 风格的 where（=、!=、in、not in、>、>=、<、<=、like、not like、between、_or、IsNull）
+//	以及 _orderby、_limit、_groupby、_having 与 COUNT、SUM、MAX、MIN、AVG 聚合
+//	未创建的表视为没有唯一键的空表；字符串比较与 *_ci 排序规则一致，忽略大小写
+//	不推断字段类型，值按写入时的类型保存（整数为 int64，浮点数为 float64），浮点字段需写入浮点数
+//	事务串行执行，回滚时恢复到事务开始时的数据，不模拟隔离级别
+//	添加了期望（ExpectQuery、ExpectExec 等）后进入期望模式，见 expect.go
+type Fake struct {
+	mu     sync.Mutex
+	tables map[string]*table
+
+	// 执行过的 sql
+	statements []Statement
+
+	// 期望模式
+	expects    []*Expectation
+	unexpected []string
+
+	// 事务串行执行
+	txMu sync.Mutex
+}
+
+var (
+	_ mysql.Client = (*Fake)(nil)
+	_ mysql.Tx     = (*fakeTx)(nil)
+)
+
+// New 创建一个空的 Fake
+func New() *Fake {
+	return &Fake{
+		tables: map[string]*table{},
+	}
+}
+
+// Statement 一次执行的 sql 与参数
+type Statement struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Statements 执行过的 sql，事务为 BEGIN、COMMIT、ROLLBACK
+func (f *Fake) Statements() []Statement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Statement(nil), f.statements...)
+}
+
+// TableOption 建表的选项
+type TableOption interface {
+	apply(*table)
+}
+
+type tableFuncOption struct {
+	f func(*table)
+}
+
+func (fo *tableFuncOption) apply(t *table) {
+	fo.f(t)
+}
+
+func newTableFuncOption(f func(*table)) *tableFuncOption {
+	return &tableFuncOption{
+		f: f,
+	}
+}
+
+// WithAutoIncrement 自增主键，写入时为空或零值则自动生成
+func WithAutoIncrement(column string) TableOption {
+	return newTableFuncOption(func(t *table) {
+		t.autoIncr = column
+		t.keys = append([]uniqueKey{{name: "PRIMARY", columns: []string{column}}}, t.keys...)
+		t.addColumns(column)
+	})
+}
+
+// WithPrimaryKey 主键
+func WithPrimaryKey(columns ...string) TableOption {
+	return newTableFuncOption(func(t *table) {
+		t.keys = append([]uniqueKey{{name: "PRIMARY", columns: columns}}, t.keys...)
+		t.addColumns(columns...)
+	})
+}
+
+// WithUniqueKey 唯一键，用于 INSERT IGNORE、REPLACE、ON DUPLICATE KEY UPDATE 的冲突判断
+func WithUniqueKey(columns ...string) TableOption {
+	return newTableFuncOption(func(t *table) {
+		t.keys = append(t.keys, uniqueKey{name: strings.Join(columns, "_"), columns: columns})
+		t.addColumns(columns...)
+	})
+}
+
+// WithColumns 表的字段，查询 * 时的顺序；未指定时为首次写入的顺序
+//
+//	指定了字段后，where、查询、写入不存在的字段时返回 1054 错误
+func WithColumns(columns ...string) TableOption {
+	return newTableFuncOption(func(t *table) {
+		t.addColumns(columns...)
+		t.strict = true
+	})
+}
+
+// CreateTable 创建表，已存在时清空数据
+func (f *Fake) CreateTable(name string, opts ...TableOption) {
+	t := &table{nextID: 1}
+	for _, opt := range opts {
+		opt.apply(t)
+	}
+	f.mu.Lock()
+	f.tables[name] = t
+	f.mu.Unlock()
+}
+
+// Rows 表中的所有行，按写入顺序，用于断言
+//
+//	值的类型与 mysql 驱动返回的一致：int64、float64、[]byte、time.Time 或 nil
+func (f *Fake) Rows(tableName string) []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tables[tableName]
+	if !ok {
+		return nil
+	}
+	return cloneRows(t.rows)
+}
+
+type uniqueKey struct {
+	name    string
+	columns []string
+}
+
+type table struct {
+	columns  []string
+	strict   bool
+	rows     []map[string]interface{}
+	keys     []uniqueKey
+	autoIncr string
+	nextID   int64
+}
+
+func (t *table) addColumns(columns ...string) {
+	for _, c := range columns {
+		if !t.hasColumn(c) {
+			t.columns = append(t.columns, c)
+		}
+	}
+}
+
+func (t *table) hasColumn(c string) bool {
+	for _, col := range t.columns {
+		if col == c {
+			return true
+		}
+	}
+	return false
+}
+
+// checkColumn 指定了字段的表，字段不存在时返回 1054 错误
+func (t *table) checkColumn(clause string) func(string) error {
+	return func(c string) error {
+		if t.strict && !t.hasColumn(c) {
+			return &driver.MySQLError{Number: errUnknownColumn, Message: fmt.Sprintf("Unknown column '%s' in '%s'", c, clause)}
+		}
+		return nil
+	}
+}
+
+// conflict 与 row 唯一键冲突的行的下标，skip 为要忽略的行
+func (t *table) conflict(row map[string]interface{}, skip int) (int, *uniqueKey) {
+	for k := range t.keys {
+		key := &t.keys[k]
+		for i, r := range t.rows {
+			if i == skip {
+				continue
+			}
+			same := true
+			for _, c := range key.columns {
+				// 含 NULL 的唯一键不冲突
+				cmp, ok := compare(row[c], r[c])
+				if !ok || cmp != 0 {
+					same = false
+					break
+				}
+			}
+			if same {
+				return i, key
+			}
+		}
+	}
+	return -1, nil
+}
+
+func dupError(row map[string]interface{}, key *uniqueKey) error {
+	vals := make([]string, len(key.columns))
+	for i, c := range key.columns {
+		vals[i] = toString(row[c])
+	}
+	return &driver.MySQLError{Number: errDupEntry, Message: fmt.Sprintf("Duplicate entry '%s' for key '%s'", strings.Join(vals, "-"), key.name)}
+}
+
+func (f *Fake) table(name string) *table {
+	t, ok := f.tables[name]
+	if !ok {
+		t = &table{nextID: 1}
+		f.tables[name] = t
+	}
+	return t
+}
+
+func cloneRows(rows []map[string]interface{}) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(rows))
+	for i, r := range rows {
+		c := make(map[string]interface{}, len(r))
+		for k, v := range r {
+			c[k] = v
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// snapshot 复制所有表，用于事务回滚
+func (f *Fake) snapshot() map[string]*table {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]*table, len(f.tables))
+	for name, t := range f.tables {
+		c := *t
+		c.columns = append([]string(nil), t.columns...)
+		c.rows = cloneRows(t.rows)
+		out[name] = &c
+	}
+	return out
+}
+
+func (f *Fake) restore(tables map[string]*table) {
+	f.mu.Lock()
+	f.tables = tables
+	f.mu.Unlock()
+}
+
+// result sql.Result
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// Query 查询，data 的规则与 mysql.Client.Query 一致
+func (f *Fake) Query(ctx context.Context, tableName string, where map[string]interface{}, columns []string, data interface{}) error {
+	rows, err := f.query(ctx, tableName, where, columns)
+	if err != nil {
+		return err
+	}
+	return scanner.ScanClose(rows, data)
+}
+
+// QueryRows 同 Query，逐行读取
+func (f *Fake) QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*mysql.Rows, error) {
+	rows, err := f.query(ctx, tableName, where, columns)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.NewRows(ctx, rows.columns, rows.values), nil
+}
+
+// QueryPage 页码分页，规则与 mysql.Client.QueryPage 一致
+func (f *Fake) QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *mysql.Page, data interface{}) (*mysql.PageInfo, error) {
+	return mysql.QueryPage(ctx, f, tableName, where, columns, page, data)
+}
+
+// QueryCursor 游标分页，规则与 mysql.Client.QueryCursor 一致
+func (f *Fake) QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *mysql.CursorPage, data interface{}) (string, error) {
+	return mysql.QueryCursor(ctx, f, tableName, where, columns, page, data)
+}
+
+func (f *Fake) query(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*memRows, error) {
+	cond, args, err := builder.BuildSelect(tableName, where, columns)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, err := f.expect(ctx, kindQuery, cond, args); e != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		if e.err != nil {
+			return nil, e.err
+		}
+		return &memRows{columns: e.columns, values: e.rows, idx: -1}, nil
+	}
+
+	t := f.table(tableName)
+	cols, err := parseColumns(columns, t.columns)
+	if err != nil {
+		return nil, err
+	}
+	check := t.checkColumn("field list")
+	for _, c := range cols {
+		if c.field != "*" {
+			if err := check(c.field); err != nil {
+				return nil, err
+			}
+		}
+	}
+	rows, err := t.match(where, "where clause")
+	if err != nil {
+		return nil, err
+	}
+	groupBy, err := parseGroupBy(where)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := parseOrderBy(where)
+	if err != nil {
+		return nil, err
+	}
+	offset, n, err := parseLimit(where)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+
+	if len(groupBy) == 0 && !hasAggregate(cols) {
+		sortRows(rows, orders)
+		values := project(limitRows(rows, offset, n), cols, nil)
+		return &memRows{columns: names, values: values, idx: -1}, nil
+	}
+	// 分组后按结果中的字段计算 _having、_orderby
+	values := project(rows, cols, groupBy)
+	grouped := make([]map[string]interface{}, len(values))
+	for i, vals := range values {
+		grouped[i] = make(map[string]interface{}, len(names))
+		for j, name := range names {
+			grouped[i][name] = vals[j]
+		}
+	}
+	if having, ok := where["_having"].(map[string]interface{}); ok && len(groupBy) > 0 {
+		kept := grouped[:0]
+		for _, g := range grouped {
+			ok, err := matchWhere(g, having, func(string) error { return nil })
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				kept = append(kept, g)
+			}
+		}
+		grouped = kept
+	}
+	sortRows(grouped, orders)
+	grouped = limitRows(grouped, offset, n)
+	values = make([][]interface{}, len(grouped))
+	for i, g := range grouped {
+		values[i] = make([]interface{}, len(names))
+		for j, name := range names {
+			values[i][j] = g[name]
+		}
+	}
+	return &memRows{columns: names, values: values, idx: -1}, nil
+}
+
+// match 满足 where 的行的副本
+func (t *table) match(where map[string]interface{}, clause string) ([]map[string]interface{}, error) {
+	_, idx, err := t.matchIndex(where, clause)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]interface{}, len(idx))
+	for i, j := range idx {
+		rows[i] = t.rows[j]
+	}
+	return cloneRows(rows), nil
+}
+
+// matchIndex 满足 where 的行的下标，按 _orderby 排序并按 _limit 截取，用于更新、删除
+func (t *table) matchIndex(where map[string]interface{}, clause string) ([]map[string]interface{}, []int, error) {
+	var (
+		rows []map[string]interface{}
+		idx  []int
+	)
+	for i, r := range t.rows {
+		ok, err := matchWhere(r, where, t.checkColumn(clause))
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			rows = append(rows, r)
+			idx = append(idx, i)
+		}
+	}
+	return rows, idx, nil
+}
+
+// Insert 写入多行
+func (f *Fake) Insert(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	cond, args, err := builder.BuildInsert(tableName, data)
+	if err != nil {
+		return nil, err
+	}
+	return f.insert(ctx, tableName, data, nil, modeInsert, cond, args)
+}
+
+// InsertIgnore 写入多行，忽略唯一键冲突的行
+func (f *Fake) InsertIgnore(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	cond, args, err := builder.BuildInsertIgnore(tableName, data)
+	if err != nil {
+		return nil, err
+	}
+	return f.insert(ctx, tableName, data, nil, modeIgnore, cond, args)
+}
+
+// InsertReplace 写入多行，删除唯一键冲突的行后写入
+func (f *Fake) InsertReplace(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	cond, args, err := builder.BuildReplaceInsert(tableName, data)
+	if err != nil {
+		return nil, err
+	}
+	return f.insert(ctx, tableName, data, nil, modeReplace, cond, args)
+}
+
+// InsertOnDuplicate 写入多行，唯一键冲突时更新为 update
+func (f *Fake) InsertOnDuplicate(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}) (sql.Result, error) {
+	cond, args, err := builder.BuildInsertOnDuplicate(tableName, data, update)
+	if err != nil {
+		return nil, err
+	}
+	return f.insert(ctx, tableName, data, update, modeOnDuplicate, cond, args)
+}
+
+const (
+	modeInsert = iota
+	modeIgnore
+	modeReplace
+	modeOnDuplicate
+)
+
+// insert 影响的行数与 mysql 一致：写入为1，REPLACE 删除的行各加1，ON DUPLICATE KEY UPDATE 更新为2、未变化为0
+//
+//	LastInsertId 为第一个自动生成的 id；一行出错时整条语句不生效
+func (f *Fake) insert(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}, mode int, cond string, args []interface{}) (sql.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, err := f.expect(ctx, kindExec, cond, args); e != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return e.result, e.err
+	}
+	upd, err := normalizeRow(update)
+	if err != nil {
+		return nil, err
+	}
+
+	t := f.table(tableName)
+	// 在副本上执行，出错时不修改原表
+	work := *t
+	work.columns = append([]string(nil), t.columns...)
+	work.rows = append([]map[string]interface{}(nil), t.rows...)
+	check := work.checkColumn("field list")
+	var res result
+	for _, d := range data {
+		row, err := normalizeRow(d)
+		if err != nil {
+			return nil, err
+		}
+		for c := range row {
+			if err := check(c); err != nil {
+				return nil, err
+			}
+		}
+		generated := work.fillAutoIncr(row)
+
+		i, key := work.conflict(row, -1)
+		switch {
+		case i < 0:
+		case mode == modeIgnore:
+			continue
+		case mode == modeReplace:
+			for i >= 0 {
+				work.rows = append(work.rows[:i:i], work.rows[i+1:]...)
+				res.rowsAffected++
+				i, _ = work.conflict(row, -1)
+			}
+		case mode == modeOnDuplicate:
+			updated, changed := applyUpdate(work.rows[i], upd)
+			if j, key := work.conflict(updated, i); j >= 0 {
+				return nil, dupError(updated, key)
+			}
+			if changed {
+				work.rows[i] = updated
+				res.rowsAffected += 2
+			}
+			if work.autoIncr != "" && res.lastInsertID == 0 {
+				if id, ok := work.rows[i][work.autoIncr].(int64); ok {
+					res.lastInsertID = id
+				}
+			}
+			continue
+		default:
+			return nil, dupError(row, key)
+		}
+		if generated > 0 && res.lastInsertID == 0 {
+			res.lastInsertID = generated
+		}
+		work.addColumns(sortedKeys(row)...)
+		work.rows = append(work.rows, row)
+		res.rowsAffected++
+	}
+	*t = work
+	return res, nil
+}
+
+// fillAutoIncr 自增主键为空或零值时生成，返回生成的 id
+func (t *table) fillAutoIncr(row map[string]interface{}) int64 {
+	if t.autoIncr == "" {
+		return 0
+	}
+	if id, ok := row[t.autoIncr].(int64); ok && id != 0 {
+		if id >= t.nextID {
+			t.nextID = id + 1
+		}
+		return 0
+	}
+	id := t.nextID
+	t.nextID++
+	row[t.autoIncr] = id
+	return id
+}
+
+func normalizeRow(d map[string]interface{}) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		n, err := normalize(v)
+		if err != nil {
+			return nil, fmt.Errorf("mysqltest: column %s: %w", k, err)
+		}
+		row[k] = n
+	}
+	return row, nil
+}
+
+// applyUpdate 返回更新后的副本，以及是否有字段变化
+func applyUpdate(row, update map[string]interface{}) (map[string]interface{}, bool) {
+	out := make(map[string]interface{}, len(row)+len(update))
+	for k, v := range row {
+		out[k] = v
+	}
+	changed := false
+	for k, v := range update {
+		old, had := row[k]
+		if cmp, ok := compare(old, v); !had || (old == nil) != (v == nil) || (ok && cmp != 0) || !sameType(old, v) {
+			changed = true
+		}
+		out[k] = v
+	}
+	return out, changed
+}
+
+// sameType 用于判断更新是否有变化，如 'A' 更新为 'a' 在 _ci 下相等但仍是变化
+func sameType(a, b interface{}) bool {
+	if x, ok := a.([]byte); ok {
+		y, ok := b.([]byte)
+		return ok && string(x) == string(y)
+	}
+	return true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Update 更新满足 where 的行，影响的行数为有变化的行数，与 mysql 一致
+func (f *Fake) Update(ctx context.Context, tableName string, where map[string]interface{}, update map[string]interface{}) (sql.Result, error) {
+	cond, args, err := builder.BuildUpdate(tableName, where, update)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, err := f.expect(ctx, kindExec, cond, args); e != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return e.result, e.err
+	}
+	upd, err := normalizeRow(update)
+	if err != nil {
+		return nil, err
+	}
+	t := f.table(tableName)
+	check := t.checkColumn("field list")
+	for c := range upd {
+		if err := check(c); err != nil {
+			return nil, err
+		}
+	}
+	idx, err := t.target(where)
+	if err != nil {
+		return nil, err
+	}
+	rows := append([]map[string]interface{}(nil), t.rows...)
+	var res result
+	for _, i := range idx {
+		updated, changed := applyUpdate(rows[i], upd)
+		if !changed {
+			continue
+		}
+		rows[i] = updated
+		res.rowsAffected++
+	}
+	work := *t
+	work.rows = rows
+	for _, i := range idx {
+		if j, key := work.conflict(rows[i], i); j >= 0 {
+			return nil, dupError(rows[i], key)
+		}
+	}
+	t.rows = rows
+	return res, nil
+}
+
+// target 更新、删除的行的下标，按 _orderby 排序并按 _limit 截取
+func (t *table) target(where map[string]interface{}) ([]int, error) {
+	rows, idx, err := t.matchIndex(where, "where clause")
+	if err != nil {
+		return nil, err
+	}
+	orders, err := parseOrderBy(where)
+	if err != nil {
+		return nil, err
+	}
+	_, n, err := parseLimit(where)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) > 0 {
+		order := make([]int, len(idx))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return lessRow(rows[order[a]], rows[order[b]], orders)
+		})
+		sorted := make([]int, len(idx))
+		for i, o := range order {
+			sorted[i] = idx[o]
+		}
+		idx = sorted
+	}
+	return limitRows(idx, 0, n), nil
+}
+
+// Delete 删除满足 where 的行
+func (f *Fake) Delete(ctx context.Context, tableName string, where map[string]interface{}) (sql.Result, error) {
+	cond, args, err := builder.BuildDelete(tableName, where)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, err := f.expect(ctx, kindExec, cond, args); e != nil || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return e.result, e.err
+	}
+	t := f.table(tableName)
+	idx, err := t.target(where)
+	if err != nil {
+		return nil, err
+	}
+	del := make(map[int]bool, len(idx))
+	for _, i := range idx {
+		del[i] = true
+	}
+	rows := make([]map[string]interface{}, 0, len(t.rows)-len(del))
+	for i, r := range t.rows {
+		if !del[i] {
+			rows = append(rows, r)
+		}
+	}
+	t.rows = rows
+	return result{rowsAffected: int64(len(del))}, nil
+}
+
+// ExecRaw 只在期望模式下支持
+func (f *Fake) ExecRaw(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, err := f.expect(ctx, kindExec, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, fmt.Errorf("%w: ExecRaw without expectation: %s", ErrNotSupported, sql)
+	}
+	return e.result, e.err
+}
+
+// Conn 不支持
+func (f *Fake) Conn(ctx context.Context) (*sql.Conn, error) {
+	return nil, fmt.Errorf("%w: Conn", ErrNotSupported)
+}
+
+// Close 无操作
+func (f *Fake) Close() error {
+	return nil
+}
+
+// Transaction 在事务中执行 fn，fn 返回错误或 panic 时回滚，opts 不生效
+func (f *Fake) Transaction(ctx context.Context, fn func(tx mysql.Tx) error, opts ...mysql.TxOption) (err error) {
+	f.txMu.Lock()
+	defer f.txMu.Unlock()
+	if err := f.txStatement(ctx, kindBegin, "BEGIN"); err != nil {
+		return err
+	}
+	snap := f.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			f.restore(snap)
+			_ = f.txStatement(ctx, kindRollback, "ROLLBACK")
+			panic(p)
+		}
+	}()
+	if err = fn(&fakeTx{Fake: f}); err != nil {
+		f.restore(snap)
+		if rbErr := f.txStatement(ctx, kindRollback, "ROLLBACK"); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+	if err := f.txStatement(ctx, kindCommit, "COMMIT"); err != nil {
+		f.restore(snap)
+		return err
+	}
+	return nil
+}
+
+func (f *Fake) txStatement(ctx context.Context, kind, stmt string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, err := f.expect(ctx, kind, stmt, nil)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		return e.err
+	}
+	return nil
+}
+
+// fakeTx 事务内的操作直接修改 Fake 的数据，回滚时恢复
+type fakeTx struct {
+	*Fake
+	savepoints int
+}
+
+// Transaction 嵌套事务，与 mysql 一致使用 SAVEPOINT
+func (t *fakeTx) Transaction(ctx context.Context, fn func(tx mysql.Tx) error) (err error) {
+	t.savepoints++
+	name := fmt.Sprintf("sp_%d", t.savepoints)
+	if err := t.txStatement(ctx, kindExec, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	snap := t.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			t.restore(snap)
+			_ = t.txStatement(ctx, kindExec, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+	if err = fn(t); err != nil {
+		t.restore(snap)
+		if rbErr := t.txStatement(ctx, kindExec, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+	return t.txStatement(ctx, kindExec, "RELEASE SAVEPOINT "+name)
+}
+
+// QueryPage 见 Fake.QueryPage
+func (t *fakeTx) QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *mysql.Page, data interface{}) (*mysql.PageInfo, error) {
+	return mysql.QueryPage(ctx, t, tableName, where, columns, page, data)
+}
+
+// QueryCursor 见 Fake.QueryCursor
+func (t *fakeTx) QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *mysql.CursorPage, data interface{}) (string, error) {
+	return mysql.QueryCursor(ctx, t, tableName, where, columns, page, data)
+}
+
+// memRows scanner.Rows
+type memRows struct {
+	columns []string
+	values  [][]interface{}
+	idx     int
+}
+
+func (r *memRows) Close() error {
+	return nil
+}
+
+func (r *memRows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *memRows) Next() bool {
+	r.idx++
+	return r.idx < len(r.values)
+}
+
+func (r *memRows) Scan(dest ...interface{}) error {
+	for i := range dest {
+		*dest[i].(*interface{}) = r.values[r.idx][i]
+	}
+	return nil
+}
+
+func (r *memRows) Err() error {
+	return nil
+}
@@ -0,0 +1,373 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 23:58:31
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 23:58:31
+ * @Description: 内存 mysql 用例
+ */
+package mysqltest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/didi/gendry/builder"
+	driver "github.com/go-sql-driver/mysql"
+
+	"github.com/liziwei01/gin-lib/library/mysql"
+)
+
+type user struct {
+	ID    int64   `ddb:"id"`
+	Name  string  `ddb:"name"`
+	City  string  `ddb:"city"`
+	Score float64 `ddb:"score"`
+}
+
+func newUsers(t *testing.T) *Fake {
+	t.Helper()
+	f := New()
+	f.CreateTable("tb_user", WithAutoIncrement("id"), WithUniqueKey("name"), WithColumns("id", "name", "city", "score"))
+	_, err := f.Insert(context.Background(), "tb_user", []map[string]interface{}{
+		{"name": "alice", "city": "bj", "score": 90.0},
+		{"name": "bob", "city": "sh", "score": 75.5},
+		{"name": "carol", "city": "bj", "score": 60.0},
+		{"name": "dave", "city": "gz", "score": nil},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func names(users []user) []string {
+	out := make([]string, len(users))
+	for i, u := range users {
+		out[i] = u.Name
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+
+	cases := []struct {
+		where map[string]interface{}
+		want  []string
+	}{
+		{map[string]interface{}{"city": "BJ"}, []string{"alice", "carol"}},
+		{map[string]interface{}{"id in": []int{2, 4}}, []string{"bob", "dave"}},
+		{map[string]interface{}{"id not in": []int{2, 4}}, []string{"alice", "carol"}},
+		{map[string]interface{}{"score >": 70}, []string{"alice", "bob"}},
+		{map[string]interface{}{"score <=": 60}, []string{"carol"}},
+		{map[string]interface{}{"name like": "%o%"}, []string{"bob", "carol"}},
+		{map[string]interface{}{"score": builder.IsNull}, []string{"dave"}},
+		{map[string]interface{}{"_or": []map[string]interface{}{{"city": "gz"}, {"score >": 80}}}, []string{"alice", "dave"}},
+		{map[string]interface{}{"_orderby": "score desc, id"}, []string{"alice", "bob", "carol", "dave"}},
+		{map[string]interface{}{"_orderby": "id desc", "_limit": []uint{1, 2}}, []string{"carol", "bob"}},
+	}
+	for _, c := range cases {
+		var users []user
+		if err := f.Query(ctx, "tb_user", c.where, nil, &users); err != nil {
+			t.Fatal(c.where, err)
+		}
+		if got := names(users); !equal(got, c.want) {
+			t.Errorf("where %v: got %v, want %v", c.where, got, c.want)
+		}
+	}
+
+	var u user
+	if err := f.Query(ctx, "tb_user", map[string]interface{}{"id": 5}, nil, &u); !errors.Is(err, mysql.ErrNotFound) {
+		t.Errorf("expected empty result, got %v", err)
+	}
+	var mysqlErr *driver.MySQLError
+	if err := f.Query(ctx, "tb_user", map[string]interface{}{"age": 1}, nil, &u); !errors.As(err, &mysqlErr) || mysqlErr.Number != errUnknownColumn {
+		t.Errorf("expected unknown column, got %v", err)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+
+	res, err := f.Insert(ctx, "tb_user", []map[string]interface{}{{"name": "erin", "city": "sz"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := res.LastInsertId(); id != 5 {
+		t.Errorf("unexpected last insert id %d", id)
+	}
+
+	// 冲突时整条语句不生效
+	var mysqlErr *driver.MySQLError
+	_, err = f.Insert(ctx, "tb_user", []map[string]interface{}{{"name": "frank"}, {"name": "ALICE"}})
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != errDupEntry {
+		t.Fatalf("expected duplicate entry, got %v", err)
+	}
+	if n := len(f.Rows("tb_user")); n != 5 {
+		t.Errorf("unexpected rows %d", n)
+	}
+
+	res, err = f.InsertIgnore(ctx, "tb_user", []map[string]interface{}{{"name": "frank"}, {"name": "alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("insert ignore affected %d", n)
+	}
+
+	res, err = f.InsertReplace(ctx, "tb_user", []map[string]interface{}{{"id": 1, "name": "bob", "city": "sh"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 删除了 id 为1与 name 为 bob 的两行
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Errorf("replace affected %d", n)
+	}
+
+	update := map[string]interface{}{"city": "hz"}
+	res, err = f.InsertOnDuplicate(ctx, "tb_user", []map[string]interface{}{{"name": "carol"}}, update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := res.RowsAffected()
+	id, _ := res.LastInsertId()
+	if n != 2 || id != 3 {
+		t.Errorf("on duplicate affected %d, id %d", n, id)
+	}
+	res, _ = f.InsertOnDuplicate(ctx, "tb_user", []map[string]interface{}{{"name": "carol"}}, update)
+	if n, _ := res.RowsAffected(); n != 0 {
+		t.Errorf("unchanged on duplicate affected %d", n)
+	}
+
+	var carol user
+	if err := f.Query(ctx, "tb_user", map[string]interface{}{"name": "carol"}, nil, &carol); err != nil || carol.City != "hz" {
+		t.Errorf("unexpected row %+v %v", carol, err)
+	}
+}
+
+func TestUpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+
+	res, err := f.Update(ctx, "tb_user", map[string]interface{}{"city": "bj"}, map[string]interface{}{"city": "bj", "score": 60.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// carol 没有变化
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("update affected %d", n)
+	}
+
+	var mysqlErr *driver.MySQLError
+	if _, err := f.Update(ctx, "tb_user", map[string]interface{}{"id": 2}, map[string]interface{}{"name": "alice"}); !errors.As(err, &mysqlErr) || mysqlErr.Number != errDupEntry {
+		t.Errorf("expected duplicate entry, got %v", err)
+	}
+
+	res, err = f.Delete(ctx, "tb_user", map[string]interface{}{"id >": 1, "_orderby": "id desc", "_limit": []uint{2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("delete affected %d", n)
+	}
+	var users []user
+	if err := f.Query(ctx, "tb_user", nil, nil, &users); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(users); !equal(got, []string{"alice", "bob"}) {
+		t.Errorf("unexpected rows %v", got)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+
+	n, err := mysql.Count(ctx, f, "tb_user", map[string]interface{}{"city": "bj"})
+	if err != nil || n != 2 {
+		t.Errorf("unexpected count %d %v", n, err)
+	}
+	sum, err := mysql.Sum(ctx, f, "tb_user", nil, "score")
+	if err != nil || sum != 225.5 {
+		t.Errorf("unexpected sum %v %v", sum, err)
+	}
+
+	type cityCount struct {
+		City string `ddb:"city"`
+		N    int64  `ddb:"n"`
+	}
+	groups, err := mysql.GroupBy[cityCount](ctx, f, "tb_user", map[string]interface{}{
+		"_having":  map[string]interface{}{"n >=": 1},
+		"_orderby": "n desc, city",
+	}, "city", []string{"city", "COUNT(*) AS n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 || groups[0] != (cityCount{"bj", 2}) || groups[1].City != "gz" {
+		t.Errorf("unexpected groups %+v", groups)
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+
+	var users []user
+	info, err := f.QueryPage(ctx, "tb_user", nil, nil, &mysql.Page{Page: 2, Size: 3}, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Total != 4 || info.HasMore || !equal(names(users), []string{"dave"}) {
+		t.Errorf("unexpected page %+v %v", info, names(users))
+	}
+
+	it, err := mysql.QueryIter[user](ctx, f, "tb_user", map[string]interface{}{"city": "bj"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = it.Each(func(u user) error {
+		got = append(got, u.Name)
+		return nil
+	})
+	if err != nil || !equal(got, []string{"alice", "carol"}) {
+		t.Errorf("unexpected iter %v %v", got, err)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+	errAbort := errors.New("abort")
+
+	err := f.Transaction(ctx, func(tx mysql.Tx) error {
+		if _, err := tx.Delete(ctx, "tb_user", map[string]interface{}{"city": "bj"}); err != nil {
+			return err
+		}
+		// 嵌套事务回滚到 savepoint，外层继续提交
+		err := tx.Transaction(ctx, func(tx mysql.Tx) error {
+			if _, err := tx.Delete(ctx, "tb_user", nil); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Errorf("unexpected nested err %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Rows("tb_user")); n != 2 {
+		t.Errorf("unexpected rows %d", n)
+	}
+
+	err = f.Transaction(ctx, func(tx mysql.Tx) error {
+		if _, err := tx.Delete(ctx, "tb_user", nil); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) || len(f.Rows("tb_user")) != 2 {
+		t.Errorf("expected rollback, got %v %d", err, len(f.Rows("tb_user")))
+	}
+
+	var got []string
+	for _, s := range f.Statements() {
+		if !strings.Contains(s.SQL, "tb_user") {
+			got = append(got, s.SQL)
+		}
+	}
+	want := []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "COMMIT", "BEGIN", "ROLLBACK"}
+	if !equal(got, want) {
+		t.Errorf("unexpected statements %v", got)
+	}
+}
+
+func TestWithBuilder(t *testing.T) {
+	ctx := context.Background()
+	f := newUsers(t)
+
+	var users []user
+	err := mysql.QueryWithBuilder(ctx, f, mysql.NewSelectBuilder("tb_user", map[string]interface{}{"score >": 70}, nil), &users)
+	if err != nil || !equal(names(users), []string{"alice", "bob"}) {
+		t.Errorf("unexpected query %v %v", names(users), err)
+	}
+	res, err := mysql.ExecWithBuilder(ctx, f, mysql.NewUpdateBuilder("tb_user", map[string]interface{}{"id": 4}, map[string]interface{}{"score": 50.0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("update affected %d", n)
+	}
+}
+
+func TestExpectations(t *testing.T) {
+	ctx := context.Background()
+	f := New()
+	f.ExpectBegin()
+	f.ExpectQuery("SELECT id,name FROM tb_user WHERE (city=?)").
+		WithArgs("bj").
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "alice"})
+	f.ExpectExec("UPDATE tb_user SET name=? WHERE (id=?)").
+		WithArgs("ALICE", 1).
+		WillReturnResult(0, 1)
+	f.ExpectCommit()
+
+	err := f.Transaction(ctx, func(tx mysql.Tx) error {
+		var users []user
+		if err := tx.Query(ctx, "tb_user", map[string]interface{}{"city": "bj"}, []string{"id", "name"}, &users); err != nil {
+			return err
+		}
+		if len(users) != 1 || users[0].Name != "alice" {
+			t.Errorf("unexpected users %+v", users)
+		}
+		res, err := tx.Update(ctx, "tb_user", map[string]interface{}{"id": users[0].ID}, map[string]interface{}{"name": "ALICE"})
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			t.Errorf("unexpected affected %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.AssertExpectations(t)
+
+	// 不匹配的语句返回错误并在 ExpectationsWereMet 中报告
+	f = New()
+	f.ExpectExec("DELETE FROM tb_user WHERE (id=?)").WithArgs(1)
+	if _, err := f.Delete(ctx, "tb_user", map[string]interface{}{"id": 2}); err == nil {
+		t.Error("expected mismatch error")
+	}
+	if err := f.ExpectationsWereMet(); err == nil {
+		t.Error("expected unmet expectations")
+	}
+
+	f = New()
+	errDown := errors.New("down")
+	f.ExpectExec("TRUNCATE tb_user").WillReturnError(errDown)
+	if _, err := f.ExecRaw(ctx, "TRUNCATE  tb_user"); !errors.Is(err, errDown) {
+		t.Errorf("unexpected err %v", err)
+	}
+	f.AssertExpectations(t)
+}
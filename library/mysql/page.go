@@ -0,0 +1,262 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 18:30:14
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 11:18:32
+ * @Description: 分页、游标分页与聚合查询
+ */
+package mysql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize 未指定每页条数时的默认值
+	DefaultPageSize = 20
+	// MaxPageSize 每页条数的上限
+	MaxPageSize = 1000
+)
+
+// ErrInvalidCursor 游标无法解析
+var ErrInvalidCursor = errors.New("mysql: invalid cursor")
+
+// Page 页码分页的参数，可直接绑定请求参数
+type Page struct {
+	// 页码，从1开始
+	Page int `json:"page" form:"page"`
+	// 每页条数，默认 DefaultPageSize，最大 MaxPageSize
+	Size int `json:"size" form:"size"`
+}
+
+// PageInfo 分页结果，可与数据一起作为接口返回的 data
+type PageInfo struct {
+	Page    int   `json:"page"`
+	Size    int   `json:"size"`
+	Total   int64 `json:"total"`
+	HasMore bool  `json:"has_more"`
+}
+
+// CursorPage 游标分页的参数，适用于深分页与数据持续写入的列表
+type CursorPage struct {
+	// 排序字段，值需唯一，如自增主键
+	Column string `json:"-" form:"-"`
+	// 是否倒序
+	Desc bool `json:"-" form:"-"`
+	// 每页条数，默认 DefaultPageSize，最大 MaxPageSize
+	Size int `json:"size" form:"size"`
+	// 上一页返回的游标，第一页为空
+	Cursor string `json:"cursor" form:"cursor"`
+}
+
+func pageSize(size int) int {
+	switch {
+	case size <= 0:
+		return DefaultPageSize
+	case size > MaxPageSize:
+		return MaxPageSize
+	}
+	return size
+}
+
+// copyWhere 复制查询条件，去掉 ignore 中的特殊 key
+func copyWhere(where map[string]interface{}, ignore ...string) map[string]interface{} {
+	w := make(map[string]interface{}, len(where)+2)
+	for k, v := range where {
+		w[k] = v
+	}
+	for _, k := range ignore {
+		delete(w, k)
+	}
+	return w
+}
+
+// QueryPage 先查询总数，再查询当前页，页码超出范围时不查询数据
+//
+//	同 Client.QueryPage，供 Session 的其他实现（如 mysqltest.Fake）复用
+//	总数不能按分组统计，where 中有 _groupby、_having 时报错
+func QueryPage(ctx context.Context, s Session, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error) {
+	if page == nil {
+		page = &Page{}
+	}
+	info := &PageInfo{Page: page.Page, Size: pageSize(page.Size)}
+	if info.Page < 1 {
+		info.Page = 1
+	}
+	total, err := Count(ctx, s, tableName, where)
+	if err != nil {
+		return nil, err
+	}
+	info.Total = total
+	offset := (info.Page - 1) * info.Size
+	info.HasMore = int64(offset+info.Size) < total
+	if int64(offset) >= total {
+		resetSlice(data)
+		return info, nil
+	}
+	w := copyWhere(where)
+	w["_limit"] = []uint{uint(offset), uint(info.Size)}
+	if err := s.Query(ctx, tableName, w, columns, data); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// QueryCursor 按游标查询下一页，多查询一条判断是否还有下一页，没有时返回的游标为空
+//
+//	同 Client.QueryCursor，供 Session 的其他实现（如 mysqltest.Fake）复用
+//	data 需为 ddb tag 结构体切片的指针，且包含排序字段；where 中的 _orderby、_limit 被忽略
+func QueryCursor(ctx context.Context, s Session, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error) {
+	if page == nil || page.Column == "" {
+		return "", fmt.Errorf("mysql: cursor column is required")
+	}
+	size := pageSize(page.Size)
+	w := copyWhere(where)
+	order, op := "asc", " >"
+	if page.Desc {
+		order, op = "desc", " <"
+	}
+	if page.Cursor != "" {
+		v, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return "", err
+		}
+		w[page.Column+op] = v
+	}
+	w["_orderby"] = page.Column + " " + order
+	w["_limit"] = []uint{0, uint(size + 1)}
+	if err := s.Query(ctx, tableName, w, columns, data); err != nil {
+		return "", err
+	}
+
+	rows := reflect.ValueOf(data)
+	if rows.Kind() != reflect.Ptr || rows.Elem().Kind() != reflect.Slice {
+		return "", fmt.Errorf("mysql: cursor data must be a pointer to slice, got %T", data)
+	}
+	rows = rows.Elem()
+	if rows.Len() <= size {
+		return "", nil
+	}
+	rows.SetLen(size)
+	last := rows.Index(size - 1)
+	for last.Kind() == reflect.Ptr {
+		last = last.Elem()
+	}
+	si, err := getStructInfo(last.Type())
+	if err != nil {
+		return "", err
+	}
+	for _, f := range si.fields {
+		if f.column == page.Column {
+			return encodeCursor(last.Field(f.index).Interface())
+		}
+	}
+	return "", fmt.Errorf("mysql: cursor column %s not found in %s", page.Column, last.Type())
+}
+
+func resetSlice(data interface{}) {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+		v.Elem().Set(reflect.MakeSlice(v.Elem().Type(), 0, 0))
+	}
+}
+
+// cursorValue 游标中保存的排序字段的值
+type cursorValue struct {
+	V interface{} `json:"v"`
+}
+
+// encodeCursor 游标对调用方不透明，时间按 mysql 的格式保存
+func encodeCursor(v interface{}) (string, error) {
+	if t, ok := v.(time.Time); ok {
+		v = t.Format("2006-01-02 15:04:05.999999")
+	}
+	b, err := json.Marshal(cursorValue{V: v})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(cursor string) (interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cv cursorValue
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	// 保留整数的精度
+	dec.UseNumber()
+	if err := dec.Decode(&cv); err != nil || cv.V == nil {
+		return nil, ErrInvalidCursor
+	}
+	if n, ok := cv.V.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.String(), nil
+	}
+	return cv.V, nil
+}
+
+type aggregateRow[T any] struct {
+	V T `ddb:"v"`
+}
+
+// aggregate 查询单个聚合值，结果为 NULL 时返回零值
+//
+//	分组后有多个聚合值，where 中有 _groupby、_having 时报错，分组统计使用 GroupBy
+func aggregate[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}, expr string) (T, error) {
+	var row aggregateRow[T]
+	for _, k := range []string{"_groupby", "_having"} {
+		if _, ok := where[k]; ok {
+			return row.V, fmt.Errorf("mysql: %s of %s is not supported with %s, use GroupBy", expr, tableName, k)
+		}
+	}
+	w := copyWhere(where, "_orderby", "_limit", "_lockMode")
+	err := s.Query(ctx, tableName, w, []string{expr + " AS v"}, &row)
+	if err == ErrNotFound {
+		err = nil
+	}
+	return row.V, err
+}
+
+// Count 查询总数，忽略 where 中的 _orderby、_limit，不支持 _groupby、_having
+func Count(ctx context.Context, s Session, tableName string, where map[string]interface{}) (int64, error) {
+	return aggregate[int64](ctx, s, tableName, where, "COUNT(*)")
+}
+
+// Sum 字段的和，没有匹配的行时返回 0
+func Sum(ctx context.Context, s Session, tableName string, where map[string]interface{}, column string) (float64, error) {
+	return aggregate[float64](ctx, s, tableName, where, "SUM("+column+")")
+}
+
+// Max 字段的最大值，没有匹配的行时返回零值
+func Max[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}, column string) (T, error) {
+	return aggregate[T](ctx, s, tableName, where, "MAX("+column+")")
+}
+
+// Min 字段的最小值，没有匹配的行时返回零值
+func Min[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}, column string) (T, error) {
+	return aggregate[T](ctx, s, tableName, where, "MIN("+column+")")
+}
+
+// GroupBy 分组查询，groupBy 同 gendry 的 _groupby，where 中可使用 _having、_orderby、_limit
+//
+//	columns 可包含聚合表达式，别名与 T 的 ddb tag 对应，如 "city", "COUNT(*) AS n"
+func GroupBy[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}, groupBy string, columns []string) ([]T, error) {
+	w := copyWhere(where)
+	w["_groupby"] = groupBy
+	var data []T
+	if err := s.Query(ctx, tableName, w, columns, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,141 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 18:58:40
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 11:18:32
+ * @Description: 分页与聚合查询用例
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeTable 模拟一张 id 为 1..n 的表，支持 COUNT、id 比较与 LIMIT
+func fakeTable(n int64) func(query string, args []driver.NamedValue) *fakeRows {
+	return func(query string, args []driver.NamedValue) *fakeRows {
+		if strings.HasPrefix(query, "SELECT COUNT(*) AS v") {
+			return &fakeRows{columns: []string{"v"}, values: [][]driver.Value{{n}}}
+		}
+		if strings.HasPrefix(query, "SELECT SUM(score) AS v") {
+			return &fakeRows{columns: []string{"v"}, values: [][]driver.Value{{[]byte("12.5")}}}
+		}
+		if strings.HasPrefix(query, "SELECT MAX(id) AS v") {
+			return &fakeRows{columns: []string{"v"}, values: [][]driver.Value{{nil}}}
+		}
+		offset, size := args[len(args)-2].Value.(int64), args[len(args)-1].Value.(int64)
+		from, step := int64(1), int64(1)
+		if strings.Contains(query, "id>?") {
+			from = args[0].Value.(int64) + 1
+		}
+		if strings.Contains(query, "id<?") {
+			from, step = args[0].Value.(int64)-1, -1
+		} else if strings.Contains(query, "desc") {
+			from, step = n, -1
+		}
+		rows := &fakeRows{columns: []string{"id", "name"}}
+		for id := from + offset*step; id >= 1 && id <= n && int64(len(rows.values)) < size; id += step {
+			rows.values = append(rows.values, []driver.Value{id, []byte("u")})
+		}
+		return rows
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.rows = fakeTable(45)
+
+	var users []structUser
+	info, err := c.QueryPage(ctx, "tb_user", nil, []string{"id", "name"}, &Page{Page: 3}, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Total != 45 || info.Size != DefaultPageSize || info.HasMore || len(users) != 5 || users[0].ID != 41 {
+		t.Errorf("unexpected page %+v %d", info, len(users))
+	}
+	info, err = c.QueryPage(ctx, "tb_user", nil, []string{"id", "name"}, &Page{Page: 9, Size: 10}, &users)
+	if err != nil || info.HasMore || len(users) != 0 {
+		t.Errorf("out of range page should be empty: %+v %d %v", info, len(users), err)
+	}
+}
+
+func TestQueryCursor(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.rows = fakeTable(25)
+
+	for _, desc := range []bool{false, true} {
+		page := &CursorPage{Column: "id", Desc: desc, Size: 10}
+		var ids []int64
+		for i := 0; i < 5; i++ {
+			var users []*structUser
+			next, err := c.QueryCursor(ctx, "tb_user", nil, []string{"id", "name"}, page, &users)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range users {
+				ids = append(ids, u.ID)
+			}
+			if next == "" {
+				break
+			}
+			page.Cursor = next
+		}
+		if len(ids) != 25 || (!desc && ids[24] != 25) || (desc && ids[24] != 1) {
+			t.Errorf("desc=%v: unexpected ids %v", desc, ids)
+		}
+	}
+
+	var users []structUser
+	if _, err := c.QueryCursor(ctx, "tb_user", nil, nil, &CursorPage{Column: "id", Cursor: "!bad"}, &users); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("want ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.rows = fakeTable(7)
+
+	if n, err := Count(ctx, c, "tb_user", map[string]interface{}{"_orderby": "id", "_limit": []uint{1}}); err != nil || n != 7 {
+		t.Errorf("count: %d %v", n, err)
+	}
+	if got := lastStatement(fc); strings.Contains(got, "ORDER") || strings.Contains(got, "LIMIT") {
+		t.Errorf("count should ignore _orderby and _limit: %s", got)
+	}
+	// 分组后的总数不是单个值，报错而不是只返回第一组的数量
+	for _, where := range []map[string]interface{}{{"_groupby": "city"}, {"_having": map[string]interface{}{"n >": 1}}} {
+		if _, err := Count(ctx, c, "tb_user", where); err == nil {
+			t.Errorf("count with %v should fail", where)
+		}
+		if _, err := QueryPage(ctx, c, "tb_user", where, []string{"city"}, &Page{Page: 1}, &[]struct{}{}); err == nil {
+			t.Errorf("query page with %v should fail", where)
+		}
+	}
+	if sum, err := Sum(ctx, c, "tb_user", nil, "score"); err != nil || sum != 12.5 {
+		t.Errorf("sum: %v %v", sum, err)
+	}
+	if max, err := Max[int64](ctx, c, "tb_user", nil, "id"); err != nil || max != 0 {
+		t.Errorf("max of empty table should be zero: %v %v", max, err)
+	}
+
+	type cityCount struct {
+		City string `ddb:"city"`
+		N    int64  `ddb:"n"`
+	}
+	fc.rows = func(query string, args []driver.NamedValue) *fakeRows {
+		return &fakeRows{columns: []string{"city", "n"}, values: [][]driver.Value{{[]byte("bj"), int64(3)}}}
+	}
+	groups, err := GroupBy[cityCount](ctx, c, "tb_user", map[string]interface{}{"_having": map[string]interface{}{"n >": 1}}, "city", []string{"city", "COUNT(*) AS n"})
+	if err != nil || len(groups) != 1 || groups[0].N != 3 {
+		t.Errorf("group by: %+v %v", groups, err)
+	}
+	if got, want := lastStatement(fc), "SELECT city,COUNT(*) AS n FROM tb_user GROUP BY city HAVING (n>?)"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,234 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 22:10:35
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 14:31:09
+ * @Description: 连接池配置、后台健康检查与连接池 metrics
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// 连接池的默认配置
+const (
+	defaultMaxOpenConns        = 100
+	defaultMaxIdleConns        = 10
+	defaultConnMaxLifetime     = 30 * time.Minute
+	defaultHealthCheckInterval = 5 * time.Second
+)
+
+var (
+	instanceUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "mysql_instance_up",
+			Help: "Whether the last health check of the mysql instance succeeded.",
+		},
+		[]string{"servicer", "instance"},
+	)
+	pools = &poolCollector{}
+)
+
+func init() {
+	prometheus.MustRegister(instanceUp, pools)
+}
+
+// setupPool 按配置设置连接池
+func (c *client) setupPool(db *sql.DB) {
+	maxOpen, maxIdle := defaultMaxOpenConns, defaultMaxIdleConns
+	lifetime := defaultConnMaxLifetime
+	var idleTime time.Duration
+	if c.conf != nil {
+		p := c.conf.Pool
+		if p.MaxOpenConns > 0 {
+			maxOpen = p.MaxOpenConns
+		}
+		if p.MaxIdleConns > 0 {
+			maxIdle = p.MaxIdleConns
+		}
+		if p.ConnMaxLifetime > 0 {
+			lifetime = time.Duration(p.ConnMaxLifetime) * time.Millisecond
+		}
+		idleTime = time.Duration(p.ConnMaxIdleTime) * time.Millisecond
+	}
+	if maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(lifetime)
+	db.SetConnMaxIdleTime(idleTime)
+}
+
+func (c *client) healthCheckInterval() time.Duration {
+	if c.conf == nil || c.conf.Pool.HealthCheckInterval == 0 {
+		return defaultHealthCheckInterval
+	}
+	return time.Duration(c.conf.Pool.HealthCheckInterval) * time.Millisecond
+}
+
+// startProbe 首次获取连接时启动后台健康检查，Close 时停止
+func (c *client) startProbe() {
+	c.probeOnce.Do(func() {
+		interval := c.healthCheckInterval()
+		if interval < 0 {
+			return
+		}
+		c.stopProbe = make(chan struct{})
+		c.probeDone = make(chan struct{})
+		go func() {
+			defer close(c.probeDone)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.stopProbe:
+					return
+				case <-ticker.C:
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				c.checkHealth(ctx)
+				cancel()
+			}
+		}()
+	})
+}
+
+// checkHealth 检查主库与所有未被摘除的从库
+//
+//	主库检查失败只打印日志，database/sql 会自动重连；从库检查失败被摘除 EjectTime
+func (c *client) checkHealth(ctx context.Context) {
+	c.mu.RLock()
+	db := c.db
+	c.mu.RUnlock()
+	if db != nil {
+		err := db.PingContext(ctx)
+		c.reportHealth(ctx, c.dialect().instance(c, c.host(), c.port()), err)
+	}
+	now := time.Now()
+	for _, r := range c.replicas {
+		if r.ejected(now) {
+			continue
+		}
+		db, err := r.connect(c)
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err != nil {
+			r.eject(now.Add(c.ejectTime()))
+		}
+		c.reportHealth(ctx, c.dialect().instance(c, r.addr.Host, r.addr.Port), err)
+	}
+}
+
+func (c *client) reportHealth(ctx context.Context, addr string, err error) {
+	if err != nil {
+		instanceUp.WithLabelValues(c.name(), addr).Set(0)
+		logit.Svr().Warning(ctx, "mysql health check failed",
+			logit.String("servicer", c.name()),
+			logit.String("instance", addr),
+			logit.Error("err", err))
+		return
+	}
+	instanceUp.WithLabelValues(c.name(), addr).Set(1)
+}
+
+// Close 停止健康检查并关闭主库与从库的连接池
+func (c *client) Close() error {
+	c.probeOnce.Do(func() {})
+	if c.stopProbe != nil {
+		close(c.stopProbe)
+		<-c.probeDone
+		c.stopProbe = nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.db != nil {
+		pools.remove(c.name(), c.dialect().instance(c, c.host(), c.port()))
+		err = c.db.Close()
+		c.db = nil
+	}
+	for _, r := range c.replicas {
+		r.mu.Lock()
+		if r.db != nil {
+			pools.remove(c.name(), c.dialect().instance(c, r.addr.Host, r.addr.Port))
+			if cerr := r.db.Close(); err == nil {
+				err = cerr
+			}
+			r.db = nil
+		}
+		r.mu.Unlock()
+	}
+	return err
+}
+
+func instance(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// poolCollector 采集时读取各连接池的 sql.DBStats
+type poolCollector struct {
+	dbs sync.Map
+}
+
+type poolKey struct {
+	servicer string
+	instance string
+}
+
+var (
+	poolMaxOpenDesc = prometheus.NewDesc("mysql_pool_max_open_connections",
+		"Maximum number of open connections to the mysql instance.", []string{"servicer", "instance"}, nil)
+	poolOpenDesc = prometheus.NewDesc("mysql_pool_open_connections",
+		"Number of established connections, both in use and idle.", []string{"servicer", "instance"}, nil)
+	poolInUseDesc = prometheus.NewDesc("mysql_pool_in_use_connections",
+		"Number of connections currently in use.", []string{"servicer", "instance"}, nil)
+	poolIdleDesc = prometheus.NewDesc("mysql_pool_idle_connections",
+		"Number of idle connections.", []string{"servicer", "instance"}, nil)
+	poolWaitCountDesc = prometheus.NewDesc("mysql_pool_wait_count_total",
+		"Total number of connections waited for.", []string{"servicer", "instance"}, nil)
+	poolWaitDurationDesc = prometheus.NewDesc("mysql_pool_wait_duration_seconds_total",
+		"Total time blocked waiting for a new connection.", []string{"servicer", "instance"}, nil)
+)
+
+func (pc *poolCollector) add(servicer, instance string, db *sql.DB) {
+	pc.dbs.Store(poolKey{servicer: servicer, instance: instance}, db)
+}
+
+func (pc *poolCollector) remove(servicer, instance string) {
+	pc.dbs.Delete(poolKey{servicer: servicer, instance: instance})
+}
+
+func (pc *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolMaxOpenDesc
+	ch <- poolOpenDesc
+	ch <- poolInUseDesc
+	ch <- poolIdleDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitDurationDesc
+}
+
+func (pc *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	pc.dbs.Range(func(k, v interface{}) bool {
+		key, stats := k.(poolKey), v.(*sql.DB).Stats()
+		labels := []string{key.servicer, key.instance}
+		ch <- prometheus.MustNewConstMetric(poolMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(stats.InUse), labels...)
+		ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stats.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount), labels...)
+		ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds(), labels...)
+		return true
+	})
+}
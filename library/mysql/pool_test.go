@@ -0,0 +1,71 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 22:40:16
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 22:40:16
+ * @Description: 连接池用例
+ */
+package mysql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/logit/logittest"
+)
+
+func TestPool(t *testing.T) {
+	o := logittest.ReplaceSvrLogger(t)
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	c.conf.Pool.MaxOpenConns = 4
+	c.conf.Pool.MaxIdleConns = 8
+	c.conf.Pool.HealthCheckInterval = 10
+	c.setupPool(c.db)
+	if stats := c.db.Stats(); stats.MaxOpenConnections != 4 {
+		t.Errorf("want max open 4, got %d", stats.MaxOpenConnections)
+	}
+
+	pools.add("fake", "primary", c.db)
+	defer pools.remove("fake", "primary")
+	expected := `
+# HELP mysql_pool_max_open_connections Maximum number of open connections to the mysql instance.
+# TYPE mysql_pool_max_open_connections gauge
+mysql_pool_max_open_connections{instance="primary",servicer="fake"} 4
+`
+	if err := testutil.CollectAndCompare(pools, strings.NewReader(expected), "mysql_pool_max_open_connections"); err != nil {
+		t.Error(err)
+	}
+
+	// 获取连接时不再 ping，由后台健康检查发现异常
+	fc.pingErr = errors.New("down")
+	if _, err := c.connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	up := instanceUp.WithLabelValues("fake", instance("", 0))
+	time.Sleep(35 * time.Millisecond)
+	if got := testutil.ToFloat64(up); got != 0 {
+		t.Errorf("primary should be down, got %v", got)
+	}
+	o.AssertLogged(t, logit.WarningLevel, "mysql health check failed", "instance")
+	fc.mu.Lock()
+	fc.pingErr = nil
+	fc.mu.Unlock()
+	time.Sleep(35 * time.Millisecond)
+	if got := testutil.ToFloat64(up); got != 1 {
+		t.Errorf("primary should be up, got %v", got)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.db != nil {
+		t.Error("Close should close the primary pool")
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 15:12:40
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 22:10:35
+ * @Description: 读写分离，从库的负载均衡与摘除
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 资源使用策略
+const (
+	StrategyRoundRobin = "RoundRobin"
+	StrategyRandom     = "Random"
+)
+
+// 从库默认的摘除时长
+const defaultEjectTime = 30 * time.Second
+
+type ctxPrimaryKey struct{}
+
+// UsePrimary 标记 ctx 读主库，用于写入后需要立即读到最新数据的场景
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxPrimaryKey{}, true)
+}
+
+func isPrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxPrimaryKey{}).(bool)
+	return v
+}
+
+type replica struct {
+	addr Addr
+
+	mu sync.Mutex
+	db *sql.DB
+
+	// 摘除到期的时间，UnixNano
+	ejectedUntil int64
+}
+
+func (r *replica) ejected(now time.Time) bool {
+	return now.UnixNano() < atomic.LoadInt64(&r.ejectedUntil)
+}
+
+func (r *replica) eject(until time.Time) {
+	atomic.StoreInt64(&r.ejectedUntil, until.UnixNano())
+}
+
+// connect 获取从库的连接池，首次使用时创建
+func (r *replica) connect(c *client) (*sql.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.db == nil {
+		db, err := c.openAddr(r.addr.Host, r.addr.Port)
+		if err != nil {
+			return nil, err
+		}
+		r.db = db
+	}
+	return r.db, nil
+}
+
+// connectRead 按策略选择一个健康的从库，没有从库、ctx 要求读主库、从库都不可用时使用主库
+//
+//	后台健康检查失败或无法连接的从库被摘除 EjectTime，到期后再次尝试
+func (c *client) connectRead(ctx context.Context) (*sql.DB, error) {
+	n := len(c.replicas)
+	if n == 0 || isPrimary(ctx) {
+		return c.connect(ctx)
+	}
+	c.startProbe()
+	start := c.pick(n)
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		r := c.replicas[(start+i)%n]
+		if r.ejected(now) {
+			continue
+		}
+		db, err := r.connect(c)
+		if err == nil {
+			return db, nil
+		}
+		r.eject(now.Add(c.ejectTime()))
+	}
+	return c.connect(ctx)
+}
+
+// pick 按策略选择第一个尝试的从库
+func (c *client) pick(n int) int {
+	if c.strategy() == StrategyRandom {
+		return rand.Intn(n)
+	}
+	return int((atomic.AddUint32(&c.rr, 1) - 1) % uint32(n))
+}
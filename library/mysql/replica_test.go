@@ -0,0 +1,76 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 15:30:18
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 22:10:35
+ * @Description: 读写分离用例
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/logit/logittest"
+)
+
+func newFakeReplicas(c *client, n int) []*fakeConnector {
+	fcs := make([]*fakeConnector, n)
+	for i := range fcs {
+		fcs[i] = &fakeConnector{}
+		c.replicas = append(c.replicas, &replica{db: sql.OpenDB(fcs[i])})
+	}
+	return fcs
+}
+
+func TestReadReplicas(t *testing.T) {
+	o := logittest.ReplaceSvrLogger(t)
+	ctx := context.Background()
+	c, _ := newFakeClient(0)
+	c.conf.Strategy.EjectTime = 20
+	fcs := newFakeReplicas(c, 2)
+	defer c.Close()
+
+	// 轮询
+	seen := make([]*sql.DB, 4)
+	for i := range seen {
+		db, err := c.connectRead(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[i] = db
+	}
+	if seen[0] != c.replicas[0].db || seen[1] != c.replicas[1].db || seen[2] != seen[0] || seen[3] != seen[1] {
+		t.Error("reads should round robin over replicas")
+	}
+
+	// 要求读主库
+	if db, _ := c.connectRead(UsePrimary(ctx)); db != c.db {
+		t.Error("UsePrimary should read from primary")
+	}
+
+	// 健康检查失败的从库被摘除，到期后恢复
+	fcs[0].pingErr = errors.New("down")
+	c.checkHealth(ctx)
+	o.AssertLogged(t, logit.WarningLevel, "mysql health check failed", "servicer", "instance", "err")
+	for i := 0; i < 4; i++ {
+		if db, _ := c.connectRead(ctx); db != c.replicas[1].db {
+			t.Fatal("unhealthy replica should be ejected")
+		}
+	}
+	fcs[1].pingErr = errors.New("down")
+	c.checkHealth(ctx)
+	if db, _ := c.connectRead(ctx); db != c.db {
+		t.Error("should fall back to primary when all replicas are down")
+	}
+	fcs[0].pingErr = nil
+	time.Sleep(30 * time.Millisecond)
+	c.checkHealth(ctx)
+	if db, _ := c.connectRead(ctx); db != c.replicas[0].db {
+		t.Error("ejected replica should be retried after EjectTime")
+	}
+}
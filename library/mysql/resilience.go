@@ -0,0 +1,87 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 21:20:18
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 21:20:18
+ * @Description: 重试与熔断对 mysql 错误的判断
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// mysql、postgres 连接数过多、服务关闭的错误码
+const (
+	errTooManyConns    = 1040
+	errServerShutdown  = 1053
+	errPgTooManyConns  = "53300"
+	errPgAdminShutdown = "57P01"
+	errPgConnException = "08"
+)
+
+// unavailable 数据库不可用的错误，计入熔断
+//
+//	sql 错误、记录不存在、唯一键冲突等说明数据库可用，不计入；调用方取消 ctx 不计入
+func unavailable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysqldriver.ErrInvalidConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var (
+		ne net.Error
+		me *mysqldriver.MySQLError
+		pe *pq.Error
+	)
+	switch {
+	case errors.As(err, &ne):
+		return true
+	case errors.As(err, &me):
+		return me.Number == errTooManyConns || me.Number == errServerShutdown
+	case errors.As(err, &pe):
+		return pe.Code.Class() == errPgConnException || pe.Code == errPgTooManyConns || pe.Code == errPgAdminShutdown
+	}
+	return false
+}
+
+// notExecuted 确定语句没有执行的错误：连接失败、连接数过多与死锁（语句已回滚）
+func notExecuted(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || IsDeadlock(err) {
+		return true
+	}
+	var (
+		oe *net.OpError
+		me *mysqldriver.MySQLError
+		pe *pq.Error
+	)
+	switch {
+	case errors.As(err, &oe):
+		return oe.Op == "dial"
+	case errors.As(err, &me):
+		return me.Number == errTooManyConns
+	case errors.As(err, &pe):
+		return pe.Code == errPgTooManyConns
+	}
+	return false
+}
+
+// retryableRead 读可以重试数据库不可用的错误与死锁，ctx 超时不重试
+func retryableRead(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return unavailable(err) || notExecuted(err)
+}
+
+// retryableWrite 写不是幂等的，只重试确定没有执行的错误
+func retryableWrite(err error) bool {
+	return notExecuted(err)
+}
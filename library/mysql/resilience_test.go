@@ -0,0 +1,92 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 21:46:12
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 21:46:12
+ * @Description: 重试与熔断用例
+ */
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+
+	"github.com/liziwei01/gin-lib/library/resilience"
+)
+
+func TestResilience(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(2)
+	c.policy = resilience.New("resilience_test", 2, resilience.Config{RetryBackoff: 1, BreakerFailures: 2, BreakerOpenTime: 60000}, resilience.WithFailure(unavailable))
+	defer c.Close()
+
+	// 死锁时语句已回滚，读写都重试
+	calls := 0
+	fc.execErr = func(query string) error {
+		calls++
+		if calls < 3 {
+			return &mysqldriver.MySQLError{Number: errDeadlock}
+		}
+		return nil
+	}
+	if _, err := c.Update(ctx, "tb_user", map[string]interface{}{"id": 1}, map[string]interface{}{"name": "a"}); err != nil || calls != 3 {
+		t.Errorf("want success after 3 calls, got %d %v", calls, err)
+	}
+
+	// 写不重试可能已执行的错误，业务错误不计入熔断
+	calls = 0
+	fc.execErr = func(query string) error {
+		calls++
+		return &mysqldriver.MySQLError{Number: 1062}
+	}
+	for i := 0; i < 3; i++ {
+		c.Insert(ctx, "tb_user", []map[string]interface{}{{"id": 1}})
+	}
+	if calls != 3 || c.policy.State() != resilience.StateClosed {
+		t.Errorf("duplicate key should not be retried or open the breaker, got %d %s", calls, c.policy.State())
+	}
+	calls = 0
+	fc.execErr = func(query string) error {
+		calls++
+		return mysqldriver.ErrInvalidConn
+	}
+	if _, err := c.Delete(ctx, "tb_user", map[string]interface{}{"id": 1}); !errors.Is(err, mysqldriver.ErrInvalidConn) || calls != 1 {
+		t.Errorf("invalid conn write should not be retried, got %d %v", calls, err)
+	}
+
+	// 读重试数据库不可用的错误，连续失败后熔断
+	calls = 0
+	if _, err := Count(ctx, c, "tb_user", nil); !errors.Is(err, resilience.ErrOpen) || calls != 1 {
+		t.Errorf("want breaker open after 2 failures, got %d %v", calls, err)
+	}
+	if c.policy.State() != resilience.StateOpen {
+		t.Fatalf("want open, got %s", c.policy.State())
+	}
+	if _, err := Count(ctx, c, "tb_user", nil); !errors.Is(err, resilience.ErrOpen) || calls != 1 {
+		t.Errorf("open breaker should reject without querying, got %d %v", calls, err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		err         error
+		read, write bool
+		unavailable bool
+	}{
+		{&mysqldriver.MySQLError{Number: errDeadlock}, true, true, false},
+		{&mysqldriver.MySQLError{Number: errTooManyConns}, true, true, true},
+		{&mysqldriver.MySQLError{Number: 1062}, false, false, false},
+		{mysqldriver.ErrInvalidConn, true, false, true},
+		{context.DeadlineExceeded, false, false, true},
+		{context.Canceled, false, false, false},
+		{ErrNotFound, false, false, false},
+	}
+	for _, tc := range cases {
+		if retryableRead(tc.err) != tc.read || retryableWrite(tc.err) != tc.write || unavailable(tc.err) != tc.unavailable {
+			t.Errorf("%v: got read %v write %v unavailable %v", tc.err, retryableRead(tc.err), retryableWrite(tc.err), unavailable(tc.err))
+		}
+	}
+}
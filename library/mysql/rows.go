@@ -0,0 +1,305 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 19:40:22
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 14:40:16
+ * @Description: 逐行读取查询结果，用于导出等大结果集
+ */
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/didi/gendry/scanner"
+
+	"github.com/liziwei01/gin-lib/library/trace"
+)
+
+// Rows 逐行读取的查询结果，不会一次性加载到内存，用完需 Close
+//
+//	ctx 取消时 Next 返回 false，Err 返回 ctx 的错误
+type Rows struct {
+	ctx    context.Context
+	client *client
+	rows   scanner.Rows
+	span   *trace.Span
+	cond   string
+	values []interface{}
+	start  time.Time
+
+	columns []string
+	// 当前行的值
+	current []interface{}
+	err     error
+	closed  bool
+}
+
+func queryRows(ctx context.Context, client *client, db executor, builder Builder) (*Rows, error) {
+	cond, values, err := builder.CompileContext(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startSpan(ctx, client, cond)
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, client.dialect().rebind(cond), values...)
+	if err != nil {
+		log(ctx, client, cond, values, time.Since(start), err)
+		endSpan(span, err)
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		log(ctx, client, cond, values, time.Since(start), err)
+		endSpan(span, err)
+		return nil, err
+	}
+	r := &Rows{
+		ctx:     ctx,
+		client:  client,
+		rows:    rows,
+		span:    span,
+		cond:    cond,
+		values:  values,
+		start:   start,
+		columns: columns,
+		current: make([]interface{}, len(columns)),
+	}
+	return r, nil
+}
+
+// NewRows 由已有数据构造的 Rows，用于 Client 的其他实现（如 mysqltest.Fake）
+//
+//	values 的每个元素为一行，值的类型与 mysql 驱动返回的一致：int64、float64、[]byte、time.Time 或 nil
+func NewRows(ctx context.Context, columns []string, values [][]interface{}) *Rows {
+	return &Rows{
+		ctx:     ctx,
+		rows:    &staticRows{columns: columns, values: values, idx: -1},
+		columns: columns,
+		current: make([]interface{}, len(columns)),
+	}
+}
+
+// Columns 查询的字段名
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Next 读取下一行，没有更多数据或出错时返回 false，并自动 Close
+func (r *Rows) Next() bool {
+	if r.closed {
+		return false
+	}
+	// database/sql 在 ctx 取消后异步关闭 rows，这里主动检查，保证取消后立即停止
+	if r.err = r.ctx.Err(); r.err != nil {
+		r.Close()
+		return false
+	}
+	if !r.rows.Next() {
+		r.Close()
+		return false
+	}
+	dest := make([]interface{}, len(r.columns))
+	for i := range dest {
+		dest[i] = &r.current[i]
+	}
+	if r.err = r.rows.Scan(dest...); r.err != nil {
+		r.Close()
+		return false
+	}
+	return true
+}
+
+// Scan 将当前行写入 ddb tag 结构体的指针，字段转换规则与 Query 一致
+func (r *Rows) Scan(dest interface{}) error {
+	return scanner.Scan(&singleRow{r: r}, dest)
+}
+
+// Strings 当前行转换为字符串，NULL 为空字符串，用于写入 csv、xlsx
+func (r *Rows) Strings() ([]string, error) {
+	out := make([]string, len(r.current))
+	for i, v := range r.current {
+		out[i] = stringify(v)
+	}
+	return out, nil
+}
+
+// Err 读取过程中的错误
+func (r *Rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Err()
+}
+
+// Close 关闭结果集，归还连接，可重复调用
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.rows.Close()
+	if err == nil {
+		err = r.Err()
+	}
+	if r.client != nil {
+		log(r.ctx, r.client, r.cond, r.values, time.Since(r.start), err)
+		endSpan(r.span, err)
+	}
+	return err
+}
+
+func stringify(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(val)
+	case string:
+		return val
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// singleRow 只包含当前行的 scanner.Rows，复用 gendry 的字段转换
+type singleRow struct {
+	r    *Rows
+	read bool
+}
+
+func (s *singleRow) Close() error {
+	return nil
+}
+
+func (s *singleRow) Columns() ([]string, error) {
+	return s.r.columns, nil
+}
+
+func (s *singleRow) Next() bool {
+	if s.read {
+		return false
+	}
+	s.read = true
+	return true
+}
+
+func (s *singleRow) Scan(dest ...interface{}) error {
+	for i := range dest {
+		*dest[i].(*interface{}) = s.r.current[i]
+	}
+	return nil
+}
+
+func (s *singleRow) Err() error {
+	return nil
+}
+
+// staticRows NewRows 的数据
+type staticRows struct {
+	columns []string
+	values  [][]interface{}
+	idx     int
+}
+
+func (s *staticRows) Close() error {
+	return nil
+}
+
+func (s *staticRows) Columns() ([]string, error) {
+	return s.columns, nil
+}
+
+func (s *staticRows) Next() bool {
+	s.idx++
+	return s.idx < len(s.values)
+}
+
+func (s *staticRows) Scan(dest ...interface{}) error {
+	if len(dest) != len(s.columns) {
+		return fmt.Errorf("mysql: expected %d destination arguments in Scan, not %d", len(s.columns), len(dest))
+	}
+	for i := range dest {
+		*dest[i].(*interface{}) = s.values[s.idx][i]
+	}
+	return nil
+}
+
+func (s *staticRows) Err() error {
+	return nil
+}
+
+// Iter 逐行读取的 ddb tag 结构体
+type Iter[T any] struct {
+	rows *Rows
+}
+
+// QueryIter 逐行查询，查询的字段由 T 的 ddb tag 确定
+//
+//	for it.Next() {
+//		row, err := it.Scan()
+//	}
+//	err := it.Err()
+func QueryIter[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}) (*Iter[T], error) {
+	info, err := getStructInfo(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.QueryRows(ctx, tableName, where, info.columns)
+	if err != nil {
+		return nil, err
+	}
+	return &Iter[T]{rows: rows}, nil
+}
+
+// Next 读取下一行
+func (it *Iter[T]) Next() bool {
+	return it.rows.Next()
+}
+
+// Scan 当前行
+func (it *Iter[T]) Scan() (T, error) {
+	var row T
+	err := it.rows.Scan(&row)
+	return row, err
+}
+
+// Err 读取过程中的错误
+func (it *Iter[T]) Err() error {
+	return it.rows.Err()
+}
+
+// Close 关闭结果集，可重复调用
+func (it *Iter[T]) Close() error {
+	return it.rows.Close()
+}
+
+// Rows 底层的结果集，可用于写入 csv、xlsx
+func (it *Iter[T]) Rows() *Rows {
+	return it.rows
+}
+
+// Each 依次处理每一行，fn 返回错误时停止，结束后自动 Close
+func (it *Iter[T]) Each(fn func(row T) error) error {
+	defer it.Close()
+	for it.Next() {
+		row, err := it.Scan()
+		if err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,95 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 20:20:45
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 20:20:45
+ * @Description: 逐行读取用例
+ */
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/liziwei01/gin-lib/library/utils"
+)
+
+func TestQueryIter(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.rows = fakeTable(5)
+
+	it, err := QueryIter[structUser](ctx, c, "tb_user", map[string]interface{}{"_limit": []uint{0, 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for it.Next() {
+		u, err := it.Scan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, u.ID)
+	}
+	if err := it.Err(); err != nil || len(ids) != 5 || ids[4] != 5 {
+		t.Errorf("unexpected ids %v %v", ids, err)
+	}
+	if it.Next() || it.Close() != nil {
+		t.Error("closed iterator should stay closed")
+	}
+
+	// fn 返回错误时停止
+	it, _ = QueryIter[structUser](ctx, c, "tb_user", map[string]interface{}{"_limit": []uint{0, 100}})
+	stop := errors.New("stop")
+	n := 0
+	err = it.Each(func(u structUser) error {
+		n++
+		if u.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || n != 2 {
+		t.Errorf("Each should stop on error: n=%d err=%v", n, err)
+	}
+
+	// ctx 取消后停止读取
+	cctx, cancel := context.WithCancel(ctx)
+	it, _ = QueryIter[structUser](cctx, c, "tb_user", map[string]interface{}{"_limit": []uint{0, 100}})
+	it.Next()
+	cancel()
+	for it.Next() {
+	}
+	if !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("want context canceled, got %v", it.Err())
+	}
+}
+
+func TestRowsCSV(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.rows = func(query string, args []driver.NamedValue) *fakeRows {
+		return &fakeRows{
+			columns: []string{"id", "name", "note"},
+			values: [][]driver.Value{
+				{int64(1), []byte("a,b"), nil},
+				{int64(2), []byte("c"), []byte("x")},
+			},
+		}
+	}
+	rows, err := c.QueryRows(ctx, "tb_user", nil, []string{"id", "name", "note"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var buf bytes.Buffer
+	if err := utils.Csv.WriteCsv(ctx, &buf, rows.Columns(), rows); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "id,name,note\n1,\"a,b\",\n2,c,x\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
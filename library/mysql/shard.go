@@ -0,0 +1,563 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 16:35:52
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 14:11:08
+ * @Description: 分库分表，按分片规则改写表名并路由到分库，缺少分片字段时并发查询各分表
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 分片算法
+const (
+	ShardHash  = "hash"
+	ShardMod   = "mod"
+	ShardRange = "range"
+)
+
+// 缺少分片字段时并发查询的默认并发数
+const defaultShardConcurrency = 8
+
+var (
+	// ErrNoShardKey 写入的数据缺少分片字段，或不支持跨分表的操作缺少分片字段
+	ErrNoShardKey = errors.New("mysql: shard key is required")
+	// ErrCrossShard 事务、独占连接不能跨分库，需通过 ShardClient.Route 获取分库
+	ErrCrossShard = errors.New("mysql: operation can not span shards, use ShardClient.Route")
+)
+
+// ShardClient 分库分表的 Client，表名为逻辑表名，按分片规则改写为物理表名并路由到分库
+//
+//	where 中有分片字段的等值或 in 条件时只查询对应的分表，否则并发查询所有分表，
+//	按 _orderby 归并排序后再取 _limit，COUNT、SUM、MAX、MIN 的结果按分组合并
+//	写入的每行数据都需包含分片字段；Update、Delete 缺少分片字段时在所有分表执行
+//	ExecRaw 不改写表名，在第一个分库执行；事务需通过 Route 获取分库后在分库上开启
+type ShardClient struct {
+	conf  *Config
+	rules map[string]*ShardRule
+	// 表的约定，key 为逻辑表名
+	conventions map[string]*TableConvention
+
+	mu  sync.Mutex
+	dbs []Client
+}
+
+// NewShardClient 创建分库分表的 Client
+//
+//	dbs 为空时按 Sharding.Databases 在首次使用时通过 GetClient 获取分库，
+//	Databases 也为空时所有分表都在 conf 对应的库；dbs 不为空时按顺序作为各分库
+func NewShardClient(conf *Config, dbs ...Client) (*ShardClient, error) {
+	s := &ShardClient{
+		conf:        conf,
+		rules:       make(map[string]*ShardRule, len(conf.Sharding.Tables)),
+		conventions: make(map[string]*TableConvention, len(conf.Conventions)),
+	}
+	for i := range conf.Conventions {
+		s.conventions[conf.Conventions[i].Table] = &conf.Conventions[i]
+	}
+	switch {
+	case len(dbs) > 0:
+		s.dbs = dbs
+	case len(conf.Sharding.Databases) > 0:
+		s.dbs = make([]Client, len(conf.Sharding.Databases))
+	default:
+		s.dbs = []Client{New(conf)}
+	}
+	for i := range conf.Sharding.Tables {
+		r := conf.Sharding.Tables[i]
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := s.rules[r.Table]; ok {
+			return nil, fmt.Errorf("mysql: duplicate shard rule for %s", r.Table)
+		}
+		s.rules[r.Table] = &r
+	}
+	return s, nil
+}
+
+func (r *ShardRule) validate() error {
+	if r.Table == "" || r.Column == "" {
+		return fmt.Errorf("mysql: shard rule needs Table and Column")
+	}
+	r.Algorithm = strings.ToLower(r.Algorithm)
+	switch r.Algorithm {
+	case "":
+		r.Algorithm = ShardHash
+	case ShardHash, ShardMod:
+	case ShardRange:
+		if r.Count == 0 {
+			r.Count = len(r.Ranges)
+		}
+		if len(r.Ranges) != r.Count {
+			return fmt.Errorf("mysql: shard rule %s needs %d Ranges, got %d", r.Table, r.Count, len(r.Ranges))
+		}
+		for i := 1; i < len(r.Ranges); i++ {
+			if r.Ranges[i] <= r.Ranges[i-1] {
+				return fmt.Errorf("mysql: shard rule %s Ranges must be ascending", r.Table)
+			}
+		}
+	default:
+		return fmt.Errorf("mysql: unsupported shard algorithm %q", r.Algorithm)
+	}
+	if r.Count <= 0 {
+		return fmt.Errorf("mysql: shard rule %s needs a positive Count", r.Table)
+	}
+	return nil
+}
+
+// index 分片字段的值对应的分表编号
+func (r *ShardRule) index(v interface{}) (int, error) {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+	if r.Algorithm == ShardHash {
+		return int(crc32.ChecksumIEEE([]byte(fmt.Sprint(v))) % uint32(r.Count)), nil
+	}
+	n, err := shardInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("mysql: shard key %s of %s: %w", r.Column, r.Table, err)
+	}
+	if r.Algorithm == ShardMod {
+		return int((n%int64(r.Count) + int64(r.Count)) % int64(r.Count)), nil
+	}
+	i := sort.Search(len(r.Ranges), func(i int) bool { return r.Ranges[i] > n })
+	if i == len(r.Ranges) {
+		return 0, fmt.Errorf("mysql: shard key %s=%d of %s is out of range", r.Column, n, r.Table)
+	}
+	return i, nil
+}
+
+// tableName 分表的物理表名
+func (r *ShardRule) tableName(i int) string {
+	width := len(strconv.Itoa(r.Count - 1))
+	return fmt.Sprintf("%s_%0*d", r.Table, width, i)
+}
+
+func shardInt(v interface{}) (int64, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.String:
+		return strconv.ParseInt(rv.String(), 10, 64)
+	}
+	return 0, fmt.Errorf("%T is not an integer", v)
+}
+
+// shardTarget 一次路由的结果
+type shardTarget struct {
+	db    int
+	table string
+	where map[string]interface{}
+	// 逻辑表的约定，按物理表名查找不到
+	convention *TableConvention
+}
+
+// database 第 i 个分库
+//
+//	GetClient 会连接数据库，不能持有 s.mu 调用，否则一个分库连接慢时所有分库的访问都要等待
+func (s *ShardClient) database(ctx context.Context, i int) (Client, error) {
+	s.mu.Lock()
+	c := s.dbs[i]
+	s.mu.Unlock()
+	if c != nil {
+		return c, nil
+	}
+	c, err := GetClient(ctx, s.conf.Sharding.Databases[i])
+	if err != nil {
+		return nil, fmt.Errorf("mysql: shard database %s: %w", s.conf.Sharding.Databases[i], err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dbs[i] == nil {
+		s.dbs[i] = c
+	}
+	return s.dbs[i], nil
+}
+
+// dbIndex 分表所在的分库，分表按编号顺序均匀分布
+func (s *ShardClient) dbIndex(r *ShardRule, i int) int {
+	return i * len(s.dbs) / r.Count
+}
+
+// Route 分片字段的值对应的分库与物理表名，用于在分库上开启事务等不能跨分片的操作
+func (s *ShardClient) Route(ctx context.Context, tableName string, key interface{}) (Client, string, error) {
+	r, ok := s.rules[tableName]
+	if !ok {
+		c, err := s.database(ctx, 0)
+		return c, tableName, err
+	}
+	i, err := r.index(key)
+	if err != nil {
+		return nil, "", err
+	}
+	c, err := s.database(ctx, s.dbIndex(r, i))
+	return c, r.tableName(i), err
+}
+
+// targets where 对应的分表，没有分片字段的条件时为所有分表
+//
+//	in 条件按分表拆分，每个分表只查询属于自己的值
+func (s *ShardClient) targets(tableName string, where map[string]interface{}) ([]*shardTarget, error) {
+	tc := s.conventions[tableName]
+	r, ok := s.rules[tableName]
+	if !ok {
+		return []*shardTarget{{table: tableName, where: where, convention: tc}}, nil
+	}
+	key, values := shardValues(where, r.Column)
+	if key == "" {
+		targets := make([]*shardTarget, r.Count)
+		for i := range targets {
+			targets[i] = &shardTarget{db: s.dbIndex(r, i), table: r.tableName(i), where: where, convention: tc}
+		}
+		return targets, nil
+	}
+	if values == nil {
+		i, err := r.index(where[key])
+		if err != nil {
+			return nil, err
+		}
+		return []*shardTarget{{db: s.dbIndex(r, i), table: r.tableName(i), where: where, convention: tc}}, nil
+	}
+	var (
+		order  []int
+		groups = make(map[int][]interface{})
+	)
+	for _, v := range values {
+		i, err := r.index(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := groups[i]; !ok {
+			order = append(order, i)
+		}
+		groups[i] = append(groups[i], v)
+	}
+	targets := make([]*shardTarget, len(order))
+	for n, i := range order {
+		w := copyWhere(where)
+		w[key] = groups[i]
+		targets[n] = &shardTarget{db: s.dbIndex(r, i), table: r.tableName(i), where: w, convention: tc}
+	}
+	return targets, nil
+}
+
+// selectBuilder 分表的查询
+func (t *shardTarget) selectBuilder(where map[string]interface{}, columns []string) *SelectBuilder {
+	b := NewSelectBuilder(t.table, where, columns)
+	b.convention = t.convention
+	return b
+}
+
+// shardValues where 中分片字段的条件，等值条件返回 key 与 nil，in 条件返回 key 与各个值，没有时 key 为空
+func shardValues(where map[string]interface{}, column string) (key string, values []interface{}) {
+	for _, k := range sortedKeys(where) {
+		fields := strings.Fields(k)
+		if len(fields) == 0 || strings.Trim(fields[0], "`\"") != column {
+			continue
+		}
+		switch op := strings.ToLower(strings.Join(fields[1:], " ")); op {
+		case "", "=":
+			return k, nil
+		case "in":
+			rv := reflect.ValueOf(where[k])
+			if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+				continue
+			}
+			values = make([]interface{}, rv.Len())
+			for i := range values {
+				values[i] = rv.Index(i).Interface()
+			}
+			key = k
+		}
+	}
+	return key, values
+}
+
+func (s *ShardClient) concurrency() int {
+	if s.conf.Sharding.Concurrency > 0 {
+		return s.conf.Sharding.Concurrency
+	}
+	return defaultShardConcurrency
+}
+
+// each 在各分表并发执行 fn，返回第一个错误
+func (s *ShardClient) each(ctx context.Context, targets []*shardTarget, fn func(i int, c Client, t *shardTarget) error) error {
+	clients := make([]Client, len(targets))
+	for i, t := range targets {
+		c, err := s.database(ctx, t.db)
+		if err != nil {
+			return err
+		}
+		clients[i] = c
+	}
+	if len(targets) == 1 {
+		return fn(0, clients[0], targets[0])
+	}
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+		sem   = make(chan struct{}, s.concurrency())
+	)
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t *shardTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i, clients[i], t); err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	return first
+}
+
+// shardResult 多个分表执行结果的合并，RowsAffected 为总和，LastInsertId 为最后一个分表的结果
+type shardResult struct {
+	lastID   int64
+	affected int64
+}
+
+func (r *shardResult) LastInsertId() (int64, error) {
+	return r.lastID, nil
+}
+
+func (r *shardResult) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+func (r *shardResult) add(res sql.Result) {
+	if id, err := res.LastInsertId(); err == nil {
+		r.lastID = id
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		r.affected += n
+	}
+}
+
+// exec 在各分表执行 builder 生成的 Builder
+func (s *ShardClient) exec(ctx context.Context, targets []*shardTarget, builder func(t *shardTarget) Builder) (sql.Result, error) {
+	if len(targets) == 1 {
+		c, err := s.database(ctx, targets[0].db)
+		if err != nil {
+			return nil, err
+		}
+		return ExecWithBuilder(ctx, c, builder(targets[0]))
+	}
+	var (
+		mu       sync.Mutex
+		res      = &shardResult{}
+		conflict error
+	)
+	err := s.each(ctx, targets, func(_ int, c Client, t *shardTarget) error {
+		r, err := ExecWithBuilder(ctx, c, builder(t))
+		// 带版本号的 Update 只在一个分表命中，按所有分表的结果判断冲突
+		if errors.Is(err, ErrConflict) {
+			mu.Lock()
+			conflict = err
+			mu.Unlock()
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		res.add(r)
+		mu.Unlock()
+		return nil
+	})
+	if err == nil && conflict != nil && res.affected == 0 {
+		err = conflict
+	}
+	return res, err
+}
+
+func (s *ShardClient) Query(ctx context.Context, tableName string, where map[string]interface{}, columns []string, data interface{}) error {
+	targets, err := s.targets(tableName, where)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 1 {
+		c, err := s.database(ctx, targets[0].db)
+		if err != nil {
+			return err
+		}
+		return QueryWithBuilder(ctx, c, targets[0].selectBuilder(targets[0].where, columns), data)
+	}
+	return s.gather(ctx, targets, where, columns, data)
+}
+
+func (s *ShardClient) Insert(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	return s.insert(ctx, tableName, data, insertCommon, nil)
+}
+
+func (s *ShardClient) InsertIgnore(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	return s.insert(ctx, tableName, data, insertIgnore, nil)
+}
+
+func (s *ShardClient) InsertReplace(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	return s.insert(ctx, tableName, data, insertReplace, nil)
+}
+
+func (s *ShardClient) InsertOnDuplicate(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}) (sql.Result, error) {
+	return s.insert(ctx, tableName, data, insertOnDuplicate, update)
+}
+
+// insert 按分片字段将数据拆分到各分表，按分表依次写入
+func (s *ShardClient) insert(ctx context.Context, tableName string, data []map[string]interface{}, typ int, update map[string]interface{}) (sql.Result, error) {
+	r, ok := s.rules[tableName]
+	if !ok {
+		return s.exec(ctx, []*shardTarget{{table: tableName}}, func(t *shardTarget) Builder {
+			return NewInsertBuilder(t.table, data, typ, update)
+		})
+	}
+	if _, ok := update[r.Column]; ok {
+		return nil, fmt.Errorf("mysql: shard key %s of %s can not be updated", r.Column, tableName)
+	}
+	var (
+		order  []int
+		groups = make(map[int][]map[string]interface{})
+	)
+	for _, row := range data {
+		v, ok := row[r.Column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s of %s", ErrNoShardKey, r.Column, tableName)
+		}
+		i, err := r.index(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := groups[i]; !ok {
+			order = append(order, i)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	res := &shardResult{}
+	for _, i := range order {
+		c, err := s.database(ctx, s.dbIndex(r, i))
+		if err != nil {
+			return res, err
+		}
+		ir, err := ExecWithBuilder(ctx, c, NewInsertBuilder(r.tableName(i), groups[i], typ, update))
+		if err != nil {
+			return res, err
+		}
+		if len(order) == 1 {
+			return ir, nil
+		}
+		res.add(ir)
+	}
+	return res, nil
+}
+
+func (s *ShardClient) Update(ctx context.Context, tableName string, where map[string]interface{}, update map[string]interface{}) (sql.Result, error) {
+	if r, ok := s.rules[tableName]; ok {
+		if _, ok := update[r.Column]; ok {
+			return nil, fmt.Errorf("mysql: shard key %s of %s can not be updated", r.Column, tableName)
+		}
+	}
+	targets, err := s.targets(tableName, where)
+	if err != nil {
+		return nil, err
+	}
+	return s.exec(ctx, targets, func(t *shardTarget) Builder {
+		b := NewUpdateBuilder(t.table, t.where, update)
+		b.convention = t.convention
+		return b
+	})
+}
+
+func (s *ShardClient) Delete(ctx context.Context, tableName string, where map[string]interface{}) (sql.Result, error) {
+	targets, err := s.targets(tableName, where)
+	if err != nil {
+		return nil, err
+	}
+	return s.exec(ctx, targets, func(t *shardTarget) Builder {
+		b := NewDeleteBuilder(t.table, t.where)
+		b.convention = t.convention
+		return b
+	})
+}
+
+// ExecRaw 不改写表名，在第一个分库执行
+func (s *ShardClient) ExecRaw(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	c, err := s.database(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return c.ExecRaw(ctx, sql, args...)
+}
+
+// QueryRows 只支持单个分表，缺少分片字段时返回 ErrNoShardKey
+func (s *ShardClient) QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*Rows, error) {
+	targets, err := s.targets(tableName, where)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) != 1 {
+		return nil, fmt.Errorf("%w: QueryRows of %s spans %d shards", ErrNoShardKey, tableName, len(targets))
+	}
+	c, err := s.database(ctx, targets[0].db)
+	if err != nil {
+		return nil, err
+	}
+	return c.QueryRows(ctx, targets[0].table, targets[0].convention.filter(ctx, targets[0].where), columns)
+}
+
+// QueryPage 跨分表时各分表查询 offset+size 条后归并，深分页建议使用 QueryCursor
+func (s *ShardClient) QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error) {
+	return QueryPage(ctx, s, tableName, where, columns, page, data)
+}
+
+func (s *ShardClient) QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error) {
+	return QueryCursor(ctx, s, tableName, where, columns, page, data)
+}
+
+// Transaction 不支持跨分库的事务，返回 ErrCrossShard
+func (s *ShardClient) Transaction(ctx context.Context, fn func(tx Tx) error, opts ...TxOption) error {
+	return ErrCrossShard
+}
+
+// Conn 返回 ErrCrossShard
+func (s *ShardClient) Conn(ctx context.Context) (*sql.Conn, error) {
+	return nil, ErrCrossShard
+}
+
+// Close 关闭已使用的分库
+func (s *ShardClient) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for _, c := range s.dbs {
+		if c == nil {
+			continue
+		}
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+var _ Client = (*ShardClient)(nil)
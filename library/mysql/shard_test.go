@@ -0,0 +1,256 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 17:48:31
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 10:05:44
+ * @Description: 分库分表用例
+ */
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type shardOrderRow struct {
+	UserID int64  `ddb:"user_id"`
+	Amount int64  `ddb:"amount"`
+	Status string `ddb:"status"`
+}
+
+// newShardClient 两个 sqlite 内存库作为分库，tb_order 按 user_id 取模分为 4 张表
+func newShardClient(t *testing.T) (*ShardClient, []Client) {
+	t.Helper()
+	ctx := context.Background()
+	conf := &Config{Name: "shard_test"}
+	conf.Sharding.Tables = []ShardRule{{Table: "tb_order", Column: "user_id", Algorithm: ShardMod, Count: 4}}
+	var dbs []Client
+	for i, tables := range [][]string{{"tb_order_0", "tb_order_1", "tb_config"}, {"tb_order_2", "tb_order_3"}} {
+		db := newDialectClient(DriverSQLite)
+		db.conf.Name += "_" + string(rune('a'+i))
+		for _, table := range tables {
+			if _, err := db.ExecRaw(ctx, "CREATE TABLE "+table+" (user_id INTEGER, amount INTEGER, status TEXT)"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dbs = append(dbs, db)
+	}
+	sc, err := NewShardClient(conf, dbs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sc.Close() })
+	return sc, dbs
+}
+
+func TestShardRule(t *testing.T) {
+	r := &ShardRule{Table: "tb_order", Column: "user_id", Count: 64}
+	if err := r.validate(); err != nil || r.Algorithm != ShardHash {
+		t.Fatalf("unexpected rule %+v %v", r, err)
+	}
+	if r.tableName(0) != "tb_order_00" || r.tableName(63) != "tb_order_63" {
+		t.Errorf("unexpected table names %s %s", r.tableName(0), r.tableName(63))
+	}
+	// 整数与字符串的 hash 一致
+	a, _ := r.index(int64(10086))
+	b, _ := r.index("10086")
+	if a != b {
+		t.Errorf("hash of int and string differ: %d %d", a, b)
+	}
+
+	r = &ShardRule{Table: "tb_log", Column: "id", Algorithm: "range", Ranges: []int64{100, 200, 300}}
+	if err := r.validate(); err != nil || r.Count != 3 {
+		t.Fatalf("unexpected rule %+v %v", r, err)
+	}
+	for v, want := range map[int64]int{0: 0, 99: 0, 100: 1, 299: 2} {
+		if got, err := r.index(v); err != nil || got != want {
+			t.Errorf("range index of %d: got %d %v, want %d", v, got, err, want)
+		}
+	}
+	if _, err := r.index(300); err == nil {
+		t.Error("expected out of range")
+	}
+	if err := (&ShardRule{Table: "t", Column: "c", Algorithm: "range", Ranges: []int64{2, 1}}).validate(); err == nil {
+		t.Error("expected ascending error")
+	}
+}
+
+func TestShardClient(t *testing.T) {
+	ctx := context.Background()
+	sc, dbs := newShardClient(t)
+
+	var data []map[string]interface{}
+	for uid := int64(1); uid <= 8; uid++ {
+		status := "paid"
+		if uid%3 == 0 {
+			status = "new"
+		}
+		data = append(data, map[string]interface{}{"user_id": uid, "amount": uid * 10, "status": status})
+	}
+	res, err := sc.Insert(ctx, "tb_order", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 8 {
+		t.Errorf("unexpected affected %d", n)
+	}
+	// user_id 1、5 在第一个分库的 tb_order_1
+	var rows []shardOrderRow
+	if err := dbs[0].Query(ctx, "tb_order_1", nil, []string{"user_id", "amount", "status"}, &rows); err != nil || len(rows) != 2 || rows[1].UserID != 5 {
+		t.Fatalf("unexpected tb_order_1 %v %v", rows, err)
+	}
+	if _, err := sc.Insert(ctx, "tb_order", []map[string]interface{}{{"amount": 1}}); !errors.Is(err, ErrNoShardKey) {
+		t.Errorf("expected ErrNoShardKey, got %v", err)
+	}
+
+	columns := []string{"user_id", "amount", "status"}
+	var row shardOrderRow
+	if err := sc.Query(ctx, "tb_order", map[string]interface{}{"user_id": 6}, columns, &row); err != nil || row.Amount != 60 {
+		t.Errorf("unexpected row %v %v", row, err)
+	}
+	rows = nil
+	if err := sc.Query(ctx, "tb_order", map[string]interface{}{"user_id in": []int64{1, 2, 6}, "_orderby": "user_id"}, columns, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if got := orderUsers(rows); !reflect.DeepEqual(got, []int64{1, 2, 6}) {
+		t.Errorf("unexpected in query %v", got)
+	}
+
+	// 缺少分片字段时查询所有分表，归并排序后分页
+	rows = nil
+	where := map[string]interface{}{"status": "paid", "_orderby": "amount desc", "_limit": []uint{1, 3}}
+	if err := sc.Query(ctx, "tb_order", where, columns, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if got := orderUsers(rows); !reflect.DeepEqual(got, []int64{7, 5, 4}) {
+		t.Errorf("unexpected scatter query %v", got)
+	}
+	var pageRows []*shardOrderRow
+	info, err := sc.QueryPage(ctx, "tb_order", map[string]interface{}{"_orderby": "user_id"}, columns, &Page{Page: 2, Size: 3}, &pageRows)
+	if err != nil || info.Total != 8 || !info.HasMore || len(pageRows) != 3 || pageRows[0].UserID != 4 {
+		t.Errorf("unexpected page %+v %v", info, err)
+	}
+
+	largest, err := Max[int64](ctx, sc, "tb_order", map[string]interface{}{"status": "new"}, "amount")
+	if err != nil || largest != 60 {
+		t.Errorf("unexpected max %v %v", largest, err)
+	}
+	type statusCount struct {
+		Status string `ddb:"status"`
+		N      int64  `ddb:"n"`
+		Max    int64  `ddb:"m"`
+	}
+	groups, err := GroupBy[statusCount](ctx, sc, "tb_order", map[string]interface{}{"_orderby": "n desc"}, "status", []string{"status", "COUNT(*) AS n", "MAX(amount) AS m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups, []statusCount{{"paid", 6, 80}, {"new", 2, 60}}) {
+		t.Errorf("unexpected groups %v", groups)
+	}
+	if _, err := GroupBy[statusCount](ctx, sc, "tb_order", nil, "status", []string{"status", "AVG(amount) AS n"}); err == nil {
+		t.Error("expected AVG error")
+	}
+
+	res, err = sc.Update(ctx, "tb_order", map[string]interface{}{"status": "new"}, map[string]interface{}{"status": "closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("unexpected updated %d", n)
+	}
+	if _, err := sc.Update(ctx, "tb_order", nil, map[string]interface{}{"user_id": 1}); err == nil {
+		t.Error("expected shard key update error")
+	}
+	if res, err = sc.Delete(ctx, "tb_order", map[string]interface{}{"user_id in": []int64{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("unexpected deleted %d", n)
+	}
+
+	// 未配置规则的表使用第一个分库
+	if _, err := sc.Insert(ctx, "tb_config", []map[string]interface{}{{"user_id": 0, "amount": 0, "status": "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sc.Transaction(ctx, func(tx Tx) error { return nil }); !errors.Is(err, ErrCrossShard) {
+		t.Errorf("expected ErrCrossShard, got %v", err)
+	}
+	c, table, err := sc.Route(ctx, "tb_order", 6)
+	if err != nil || c != dbs[1] || table != "tb_order_2" {
+		t.Errorf("unexpected route %s %v", table, err)
+	}
+	if _, err := sc.QueryRows(ctx, "tb_order", nil, columns); !errors.Is(err, ErrNoShardKey) {
+		t.Errorf("expected ErrNoShardKey, got %v", err)
+	}
+}
+
+func TestShardConvention(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{Name: "shard_convention", Conventions: []TableConvention{{Table: "tb_user", Version: "version", SoftDelete: "deleted_at"}}}
+	conf.Sharding.Tables = []ShardRule{{Table: "tb_user", Column: "user_id", Algorithm: ShardMod, Count: 2}}
+	db := newDialectClient(DriverSQLite)
+	db.conf.Name = "shard_convention_db"
+	for _, table := range []string{"tb_user_0", "tb_user_1"} {
+		if _, err := db.ExecRaw(ctx, "CREATE TABLE "+table+" (user_id INTEGER, name TEXT, version INTEGER NOT NULL DEFAULT 0, deleted_at DATETIME NULL)"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sc, err := NewShardClient(conf, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sc.Close()
+	if _, err := sc.Insert(ctx, "tb_user", []map[string]interface{}{{"user_id": 1, "name": "a"}, {"user_id": 2, "name": "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按逻辑表名的约定检查版本号，缺少分片字段时按所有分表的结果判断冲突
+	where := map[string]interface{}{"user_id": 1, "version": 0}
+	if _, err := sc.Update(ctx, "tb_user", where, map[string]interface{}{"name": "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sc.Update(ctx, "tb_user", where, map[string]interface{}{"name": "a2"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict, got %v", err)
+	}
+	if _, err := sc.Update(ctx, "tb_user", map[string]interface{}{"name": "b", "version": 0}, map[string]interface{}{"name": "b1"}); err != nil {
+		t.Errorf("version hit on one shard should not conflict, got %v", err)
+	}
+	if _, err := sc.Update(ctx, "tb_user", map[string]interface{}{"name": "b", "version": 0}, map[string]interface{}{"name": "b2"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected ErrConflict across shards, got %v", err)
+	}
+
+	// 软删除后查询不再包含该行，物理表中仍保留
+	if _, err := sc.Delete(ctx, "tb_user", map[string]interface{}{"user_id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Count(ctx, sc, "tb_user", nil); err != nil || n != 1 {
+		t.Errorf("unexpected count %d %v", n, err)
+	}
+	if n, err := Count(ctx, sc, "tb_user", map[string]interface{}{"user_id": 1}); err != nil || n != 0 {
+		t.Errorf("unexpected count of deleted user %d %v", n, err)
+	}
+	if n, err := Count(WithDeleted(ctx), sc, "tb_user", nil); err != nil || n != 2 {
+		t.Errorf("unexpected count with deleted %d %v", n, err)
+	}
+	if n, err := Count(ctx, db, "tb_user_1", nil); err != nil || n != 1 {
+		t.Errorf("soft deleted row should stay in tb_user_1, got %d %v", n, err)
+	}
+	rows, err := sc.QueryRows(ctx, "tb_user", map[string]interface{}{"user_id": 1}, []string{"user_id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		t.Error("QueryRows should skip soft deleted rows")
+	}
+}
+
+func orderUsers(rows []shardOrderRow) []int64 {
+	var users []int64
+	for _, r := range rows {
+		users = append(users, r.UserID)
+	}
+	return users
+}
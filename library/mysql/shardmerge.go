@@ -0,0 +1,338 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 17:10:26
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 10:05:44
+ * @Description: 跨分表查询结果的归并、排序、聚合与分页
+ */
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 可以跨分表合并的聚合函数，COUNT(DISTINCT) 与 AVG 不能合并
+var shardAggregateRe = regexp.MustCompile("(?i)^\\s*(COUNT|SUM|MAX|MIN|AVG)\\s*\\((.*)\\)\\s+(?:AS\\s+)?[`\"]?(\\w+)[`\"]?\\s*$")
+
+// shardOrder _orderby 中的一个排序字段
+type shardOrder struct {
+	column string
+	desc   bool
+}
+
+// gather 并发查询各分表，合并后按 _orderby 排序再取 _limit
+//
+//	有 _limit 时每个分表查询 offset+count 条；有聚合函数时按非聚合字段分组合并，
+//	分组查询的分表不限制条数，_having 只能在单个分表内生效，跨分表时报错
+func (s *ShardClient) gather(ctx context.Context, targets []*shardTarget, where map[string]interface{}, columns []string, data interface{}) error {
+	dest := reflect.ValueOf(data)
+	if dest.Kind() != reflect.Ptr || dest.IsNil() {
+		return fmt.Errorf("mysql: data must be a non-nil pointer, got %T", data)
+	}
+	sliceType := dest.Elem().Type()
+	single := sliceType.Kind() != reflect.Slice
+	if single {
+		sliceType = reflect.SliceOf(sliceType)
+	}
+	elemType := sliceType.Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	info, err := getStructInfo(elemType)
+	if err != nil {
+		return err
+	}
+
+	aggs, err := shardAggregates(columns)
+	if err != nil {
+		return err
+	}
+	if _, ok := where["_having"]; ok {
+		return fmt.Errorf("mysql: _having can not be merged across %d shards", len(targets))
+	}
+	_, grouped := where["_groupby"]
+	offset, count, limited, err := shardLimit(where)
+	if err != nil {
+		return err
+	}
+	// 每个分表需要查询的条数
+	var limit []uint
+	if limited && !(grouped && len(aggs) > 0) {
+		limit = []uint{0, offset + count}
+	}
+
+	parts := make([]reflect.Value, len(targets))
+	err = s.each(ctx, targets, func(i int, c Client, t *shardTarget) error {
+		tw := copyWhere(t.where, "_limit")
+		if limit != nil {
+			tw["_limit"] = limit
+		}
+		part := reflect.New(sliceType)
+		if err := QueryWithBuilder(ctx, c, t.selectBuilder(tw, columns), part.Interface()); err != nil && err != ErrNotFound {
+			return err
+		}
+		parts[i] = part.Elem()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rows := reflect.MakeSlice(sliceType, 0, 0)
+	for _, part := range parts {
+		rows = reflect.AppendSlice(rows, part)
+	}
+
+	fieldOf := shardFieldIndex(info)
+	if len(aggs) > 0 {
+		if rows, err = mergeAggregates(rows, columns, aggs, fieldOf); err != nil {
+			return err
+		}
+	}
+	if orderby, ok := where["_orderby"].(string); ok && orderby != "" {
+		if err := sortRows(rows, orderby, fieldOf); err != nil {
+			return err
+		}
+	}
+	if limited {
+		start, end := int(offset), int(offset+count)
+		if start > rows.Len() {
+			start = rows.Len()
+		}
+		if end > rows.Len() {
+			end = rows.Len()
+		}
+		rows = rows.Slice(start, end)
+	}
+
+	if !single {
+		dest.Elem().Set(rows)
+		return nil
+	}
+	if rows.Len() == 0 {
+		return ErrNotFound
+	}
+	dest.Elem().Set(rows.Index(0))
+	return nil
+}
+
+// shardLimit where 中的 _limit，一个值时为 count
+func shardLimit(where map[string]interface{}) (offset, count uint, ok bool, err error) {
+	v, ok := where["_limit"]
+	if !ok {
+		return 0, 0, false, nil
+	}
+	limit, _ := v.([]uint)
+	switch len(limit) {
+	case 1:
+		return 0, limit[0], true, nil
+	case 2:
+		return limit[0], limit[1], true, nil
+	}
+	return 0, 0, false, fmt.Errorf(`mysql: the value of "_limit" must be []uint with one or two elements`)
+}
+
+// shardAggregates columns 中的聚合函数，key 为别名，value 为函数名
+func shardAggregates(columns []string) (map[string]string, error) {
+	aggs := make(map[string]string)
+	for _, col := range columns {
+		m := shardAggregateRe.FindStringSubmatch(col)
+		if m == nil {
+			continue
+		}
+		fn := strings.ToUpper(m[1])
+		if fn == "AVG" || strings.HasPrefix(strings.ToUpper(strings.TrimSpace(m[2])), "DISTINCT") {
+			return nil, fmt.Errorf("mysql: %s can not be merged across shards", col)
+		}
+		aggs[m[3]] = fn
+	}
+	return aggs, nil
+}
+
+// shardColumnName 查询字段对应的结果字段名，去掉表名与引号，有别名时为别名
+func shardColumnName(col string) string {
+	fields := strings.Fields(col)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := fields[len(fields)-1]
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Trim(name, "`\"")
+}
+
+// shardFieldIndex 结果字段名对应的结构体字段
+func shardFieldIndex(info *structInfo) func(column string) (int, bool) {
+	index := make(map[string]int, len(info.fields))
+	for _, f := range info.fields {
+		index[f.column] = f.index
+	}
+	return func(column string) (int, bool) {
+		i, ok := index[column]
+		return i, ok
+	}
+}
+
+func shardField(row reflect.Value, i int) reflect.Value {
+	for row.Kind() == reflect.Ptr {
+		row = row.Elem()
+	}
+	return row.Field(i)
+}
+
+// mergeAggregates 按非聚合字段分组，COUNT、SUM 相加，MAX、MIN 取最大、最小值
+func mergeAggregates(rows reflect.Value, columns []string, aggs map[string]string, fieldOf func(string) (int, bool)) (reflect.Value, error) {
+	var keys []int
+	for _, col := range columns {
+		name := shardColumnName(col)
+		if _, ok := aggs[name]; ok {
+			continue
+		}
+		if i, ok := fieldOf(name); ok {
+			keys = append(keys, i)
+		}
+	}
+	merged := reflect.MakeSlice(rows.Type(), 0, rows.Len())
+	groups := make(map[string]int)
+	for n := 0; n < rows.Len(); n++ {
+		row := rows.Index(n)
+		var key strings.Builder
+		for _, i := range keys {
+			fmt.Fprintf(&key, "%v\x00", shardField(row, i).Interface())
+		}
+		g, ok := groups[key.String()]
+		if !ok {
+			groups[key.String()] = merged.Len()
+			merged = reflect.Append(merged, row)
+			continue
+		}
+		acc := merged.Index(g)
+		for alias, fn := range aggs {
+			i, ok := fieldOf(alias)
+			if !ok {
+				return rows, fmt.Errorf("mysql: aggregate %s not found in %s", alias, rows.Type().Elem())
+			}
+			to, from := shardField(acc, i), shardField(row, i)
+			switch fn {
+			case "COUNT", "SUM":
+				if err := addValue(to, from); err != nil {
+					return rows, err
+				}
+			case "MAX":
+				if compareValue(from, to) > 0 {
+					to.Set(from)
+				}
+			case "MIN":
+				if compareValue(from, to) < 0 {
+					to.Set(from)
+				}
+			}
+		}
+	}
+	return merged, nil
+}
+
+func addValue(to, from reflect.Value) error {
+	switch to.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		to.SetInt(to.Int() + from.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		to.SetUint(to.Uint() + from.Uint())
+	case reflect.Float32, reflect.Float64:
+		to.SetFloat(to.Float() + from.Float())
+	default:
+		return fmt.Errorf("mysql: can not sum %s across shards", to.Type())
+	}
+	return nil
+}
+
+// sortRows 按 _orderby 稳定排序，排序字段需在结构体中
+func sortRows(rows reflect.Value, orderby string, fieldOf func(string) (int, bool)) error {
+	var orders []shardOrder
+	for _, item := range strings.Split(orderby, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+		name := shardColumnName(fields[0])
+		if _, ok := fieldOf(name); !ok {
+			return fmt.Errorf("mysql: order by %s not found in %s", name, rows.Type().Elem())
+		}
+		orders = append(orders, shardOrder{
+			column: name,
+			desc:   len(fields) > 1 && strings.EqualFold(fields[1], "desc"),
+		})
+	}
+	sort.SliceStable(rows.Interface(), func(a, b int) bool {
+		for _, o := range orders {
+			i, _ := fieldOf(o.column)
+			c := compareValue(shardField(rows.Index(a), i), shardField(rows.Index(b), i))
+			if c == 0 {
+				continue
+			}
+			return (c < 0) != o.desc
+		}
+		return false
+	})
+	return nil
+}
+
+// compareValue 比较两个同类型的值，nil 指针最小
+func compareValue(a, b reflect.Value) int {
+	for a.Kind() == reflect.Ptr {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareOrdered(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		return compareOrdered(boolInt(a.Bool()), boolInt(b.Bool()))
+	case reflect.Slice:
+		if a.Type().Elem().Kind() == reflect.Uint8 {
+			return bytes.Compare(a.Bytes(), b.Bytes())
+		}
+	case reflect.Struct:
+		if ta, ok := a.Interface().(time.Time); ok {
+			return ta.Compare(b.Interface().(time.Time))
+		}
+	}
+	return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,286 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 16:05:44
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 14:31:09
+ * @Description: sql 日志、慢查询与 metrics
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// 默认打码的字段名
+const defaultRedactColumns = "password|passwd|secret|token"
+
+// 打码后的参数
+const redactedValue = "'******'"
+
+var (
+	queryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mysql_query_duration_seconds",
+			Help:    "Duration of mysql queries.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+		},
+		[]string{"servicer", "table", "operation"},
+	)
+	queryErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mysql_query_errors_total",
+			Help: "Number of failed mysql queries by mysql error number.",
+		},
+		[]string{"servicer", "errno"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration, queryErrors)
+}
+
+// log 记录一次 sql 执行
+//
+//	SQLLogLen 为 0 时不打印，-1 时打印完整的 sql，否则截断到 SQLLogLen
+//	失败、超过 SlowThreshold 的 sql 打印 WARNING，慢查询打印完整的 sql，可直接用于 EXPLAIN
+//	参数填入 sql 后打印，字段名匹配 RedactColumns 的参数被打码
+func log(ctx context.Context, c *client, cond string, values []interface{}, cost time.Duration, err error) {
+	op, table := sqlOperation(cond)
+	queryDuration.WithLabelValues(c.name(), table, op).Observe(cost.Seconds())
+	if err != nil {
+		queryErrors.WithLabelValues(c.name(), errorNumber(err)).Inc()
+	}
+
+	slow := c.slowThreshold() > 0 && cost >= c.slowThreshold()
+	n := c.sqlloglen()
+	if err == nil && !slow && n == 0 {
+		return
+	}
+	query := interpolate(cond, values, c.redactColumn, c.dialect().identQuote())
+	fields := []logit.Field{
+		logit.String("servicer", c.name()),
+		logit.String("table", table),
+		logit.Duration("cost", cost),
+	}
+	switch {
+	case err != nil:
+		logit.Svr().Warning(ctx, "mysql query failed", append(fields, logit.String("sql", truncate(query, n)), logit.Error("err", err))...)
+	case slow:
+		logit.Svr().Warning(ctx, "mysql slow query", append(fields, logit.String("sql", query))...)
+	default:
+		logit.Svr().Notice(ctx, "mysql query", append(fields, logit.String("sql", truncate(query, n)))...)
+	}
+}
+
+func truncate(s string, n int) string {
+	if n > 0 && len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
+
+// errorNumber mysql 的错误码、postgres 的 SQLSTATE，其他错误为 other
+func errorNumber(err error) string {
+	var (
+		me *mysqldriver.MySQLError
+		pe *pq.Error
+	)
+	if errors.As(err, &me) {
+		return strconv.Itoa(int(me.Number))
+	}
+	if errors.As(err, &pe) {
+		return string(pe.Code)
+	}
+	return "other"
+}
+
+var sqlTableRe = regexp.MustCompile("(?i)\\b(?:FROM|INTO|UPDATE)\\s+([`\"\\w.]+)")
+
+// sqlOperation sql 的操作与表名，表名无法识别时为空
+func sqlOperation(cond string) (op string, table string) {
+	op = "QUERY"
+	if fields := strings.Fields(cond); len(fields) > 0 {
+		op = strings.ToUpper(fields[0])
+	}
+	if m := sqlTableRe.FindStringSubmatch(cond); m != nil {
+		table = strings.NewReplacer("`", "", `"`, "").Replace(m[1])
+	}
+	return op, table
+}
+
+// 不是字段名的关键字
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"IN": true, "IS": true, "NULL": true, "LIKE": true, "BETWEEN": true, "REGEXP": true,
+	"INSERT": true, "IGNORE": true, "REPLACE": true, "INTO": true, "VALUES": true, "VALUE": true,
+	"UPDATE": true, "SET": true, "DELETE": true, "ON": true, "DUPLICATE": true, "KEY": true,
+	"ORDER": true, "GROUP": true, "BY": true, "HAVING": true, "ASC": true, "DESC": true,
+	"LIMIT": true, "OFFSET": true, "AS": true, "EXISTS": true,
+}
+
+// interpolate 将参数填入 sql 的占位符，redact 返回 true 的字段对应的参数被打码
+//
+//	占位符对应的字段为其之前最近的字段名，INSERT 的 VALUES 中按字段列表的位置对应
+//	identQuote 为标识符的引号，mysql 为 `，sqlite、postgres 为 "
+func interpolate(cond string, values []interface{}, redact func(column string) bool, identQuote byte) string {
+	var (
+		b      strings.Builder
+		idx    int
+		column string
+
+		isInsert   bool
+		collecting bool
+		insertCols []string
+		inValues   bool
+		depth      int
+		pos        int
+	)
+	b.Grow(len(cond) + len(values)*8)
+	if fields := strings.Fields(cond); len(fields) > 0 {
+		first := strings.ToUpper(fields[0])
+		isInsert = first == "INSERT" || first == "REPLACE"
+	}
+	ident := func(name string) {
+		column = name
+		if collecting {
+			insertCols = append(insertCols, name)
+		}
+	}
+
+	for i := 0; i < len(cond); {
+		ch := cond[i]
+		switch {
+		case ch != identQuote && (ch == '\'' || ch == '"'):
+			// 字符串常量原样输出
+			j := i + 1
+			for j < len(cond) && cond[j] != ch {
+				if cond[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j < len(cond) {
+				j++
+			}
+			b.WriteString(cond[i:j])
+			i = j
+		case ch == identQuote:
+			j := strings.IndexByte(cond[i+1:], identQuote)
+			if j < 0 {
+				b.WriteString(cond[i:])
+				i = len(cond)
+				continue
+			}
+			ident(cond[i+1 : i+1+j])
+			b.WriteString(cond[i : i+2+j])
+			i += j + 2
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+			j := i + 1
+			for j < len(cond) && (cond[j] == '_' || cond[j] >= 'a' && cond[j] <= 'z' || cond[j] >= 'A' && cond[j] <= 'Z' || cond[j] >= '0' && cond[j] <= '9') {
+				j++
+			}
+			word := cond[i:j]
+			switch upper := strings.ToUpper(word); {
+			case upper == "VALUES" || upper == "VALUE":
+				inValues = isInsert && len(insertCols) > 0
+			case upper == "ON":
+				inValues = false
+			case upper == "LIMIT" || upper == "OFFSET":
+				column = ""
+			case !sqlKeywords[upper]:
+				ident(word)
+			}
+			b.WriteString(word)
+			i = j
+		case ch == '(':
+			if isInsert && !inValues && insertCols == nil && depth == 0 {
+				collecting = true
+			}
+			depth++
+			if inValues && depth == 1 {
+				pos = 0
+			}
+			b.WriteByte(ch)
+			i++
+		case ch == ')':
+			depth--
+			collecting = false
+			b.WriteByte(ch)
+			i++
+		case ch == '?' && idx < len(values):
+			col := column
+			if inValues && depth == 1 {
+				col = insertCols[pos%len(insertCols)]
+				pos++
+			}
+			if redact != nil && col != "" && redact(col) {
+				b.WriteString(redactedValue)
+			} else {
+				b.WriteString(sqlLiteral(values[idx]))
+			}
+			idx++
+			i++
+		default:
+			b.WriteByte(ch)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// sqlLiteral 参数转换为 sql 字面量
+func sqlLiteral(v interface{}) string {
+	if valuer, ok := v.(driver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return "?"
+		}
+		v = dv
+	}
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quote(val)
+	case []byte:
+		if utf8.Valid(val) {
+			return quote(string(val))
+		}
+		return "X'" + hex.EncodeToString(val) + "'"
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		if val.IsZero() {
+			return "'0000-00-00'"
+		}
+		return "'" + val.Format("2006-01-02 15:04:05.999999") + "'"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(val)
+	default:
+		return quote(fmt.Sprint(val))
+	}
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+
+func quote(s string) string {
+	return "'" + quoteReplacer.Replace(s) + "'"
+}
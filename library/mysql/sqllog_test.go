@@ -0,0 +1,90 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 16:40:12
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 16:40:12
+ * @Description: sql 日志用例
+ */
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/didi/gendry/builder"
+	mysqldriver "github.com/go-sql-driver/mysql"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/logit/logittest"
+)
+
+func TestInterpolate(t *testing.T) {
+	redact := compileRedact("").MatchString
+	insert, insertArgs, _ := builder.BuildInsert("tb_user", []map[string]interface{}{
+		{"name": "a'b", "password": "p1"},
+		{"name": nil, "password": "p2"},
+	})
+	update, updateArgs, _ := builder.BuildUpdate("tb_user",
+		map[string]interface{}{"id in": []interface{}{1, 2}, "token": "t"},
+		map[string]interface{}{"ok": true, "updated_at": time.Date(2026, 10, 20, 16, 0, 0, 0, time.UTC)})
+	cases := []struct {
+		cond   string
+		values []interface{}
+		want   string
+	}{
+		{insert, insertArgs, "INSERT INTO tb_user (name,password) VALUES ('a\\'b','******'),(NULL,'******')"},
+		{update, updateArgs, "UPDATE tb_user SET ok=1,updated_at='2026-10-20 16:00:00' WHERE (token='******' AND id IN (1,2))"},
+		{"SELECT * FROM t WHERE note = '?' AND passwd = ? LIMIT ?", []interface{}{"x", 10}, "SELECT * FROM t WHERE note = '?' AND passwd = '******' LIMIT 10"},
+		{"SELECT ? , ?", []interface{}{[]byte{0xff}}, "SELECT X'ff' , ?"},
+	}
+	for _, c := range cases {
+		if got := interpolate(c.cond, c.values, redact, '`'); got != c.want {
+			t.Errorf("want %s\ngot  %s", c.want, got)
+		}
+	}
+}
+
+func TestSQLLog(t *testing.T) {
+	o := logittest.ReplaceSvrLogger(t)
+	ctx := logit.WithContext(context.Background())
+	logit.SetRequestID(ctx, "req-1")
+	c, fc := newFakeClient(0)
+	c.conf.MySQL.SQLLogLen = 30
+	c.conf.MySQL.SlowThreshold = 20
+
+	if _, err := c.Delete(ctx, "tb_user", map[string]interface{}{"id": 1, "name": strings.Repeat("x", 40)}); err != nil {
+		t.Fatal(err)
+	}
+	e := o.AssertLogged(t, logit.NoticeLevel, "mysql query", "sql", "cost", logit.FieldRequestID)
+	if sql := e.Value("sql").(string); sql != "DELETE FROM tb_user WHERE (id=..." {
+		t.Errorf("sql should be interpolated and truncated, got %q", sql)
+	}
+	if e.Value("table") != "tb_user" {
+		t.Errorf("unexpected table %v", e.Value("table"))
+	}
+
+	fc.execErr = func(query string) error {
+		time.Sleep(25 * time.Millisecond)
+		return nil
+	}
+	c.ExecRaw(ctx, "UPDATE tb_user SET name = ? WHERE id = ?", "n", 1)
+	e = o.AssertLogged(t, logit.WarningLevel, "mysql slow query")
+	if sql := e.Value("sql").(string); sql != "UPDATE tb_user SET name = 'n' WHERE id = 1" {
+		t.Errorf("slow query should log the full sql, got %q", sql)
+	}
+
+	fc.execErr = func(query string) error {
+		return &mysqldriver.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	}
+	c.conf.MySQL.SQLLogLen = 0
+	c.ExecRaw(ctx, "INSERT INTO tb_user (id) VALUES (?)", 1)
+	o.AssertLogged(t, logit.WarningLevel, "mysql query failed", "err")
+	if op, table := sqlOperation("INSERT INTO `db`.`tb_user` (id) VALUES (?)"); op != "INSERT" || table != "db.tb_user" {
+		t.Errorf("unexpected operation %s %s", op, table)
+	}
+	if n := errorNumber(fc.execErr("")); n != "1062" {
+		t.Errorf("unexpected errno %s", n)
+	}
+}
@@ -0,0 +1,389 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 17:25:51
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 11:14:52
+ * @Description: 基于 ddb tag 的结构体增删改查
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/didi/gendry/scanner"
+)
+
+// Session Client 与 Tx 共同的方法，结构体的增删改查在事务内外都可以使用
+type Session interface {
+	Query(ctx context.Context, tableName string, where map[string]interface{}, columns []string, data interface{}) error
+	Insert(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error)
+	InsertIgnore(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error)
+	InsertReplace(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error)
+	InsertOnDuplicate(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}) (sql.Result, error)
+	Update(ctx context.Context, tableName string, where map[string]interface{}, update map[string]interface{}) (sql.Result, error)
+	Delete(ctx context.Context, tableName string, where map[string]interface{}) (sql.Result, error)
+	ExecRaw(ctx context.Context, sql string, args ...interface{}) (sql.Result, error)
+	QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*Rows, error)
+	QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error)
+	QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error)
+}
+
+var (
+	_ Session = (*client)(nil)
+	_ Session = (*tx)(nil)
+)
+
+// ErrNotFound Get 查询结果为空
+var ErrNotFound = scanner.ErrEmptyResult
+
+// 自动填充的时间字段，支持 time.Time 与整数（unix 秒）
+const (
+	ColumnCreatedAt = "created_at"
+	ColumnUpdatedAt = "updated_at"
+)
+
+// ddb tag 的选项，如 `ddb:"id,autoincr"`
+const (
+	// tagOmitEmpty 零值时不写入
+	tagOmitEmpty = "omitempty"
+	// tagReadOnly 只读，不写入，如数据库生成的字段
+	tagReadOnly = "readonly"
+	// tagAutoIncr 自增主键，零值时不写入，写入后回填 LastInsertId，不参与更新
+	tagAutoIncr = "autoincr"
+)
+
+type structField struct {
+	index     int
+	column    string
+	omitEmpty bool
+	readOnly  bool
+	autoIncr  bool
+}
+
+type structInfo struct {
+	fields  []*structField
+	columns []string
+	// 自增主键，没有时为空
+	autoIncr *structField
+}
+
+var structInfos sync.Map
+
+// getStructInfo 解析结构体的 ddb tag，没有 ddb tag 的字段被忽略
+func getStructInfo(typ reflect.Type) (*structInfo, error) {
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mysql: %s is not a struct", typ)
+	}
+	if info, ok := structInfos.Load(typ); ok {
+		return info.(*structInfo), nil
+	}
+	info := &structInfo{}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag, has := f.Tag.Lookup(scanner.DefaultTagName)
+		if !has || f.PkgPath != "" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		if opts[0] == "" || opts[0] == "-" {
+			continue
+		}
+		sf := &structField{index: i, column: opts[0]}
+		for _, opt := range opts[1:] {
+			switch strings.TrimSpace(opt) {
+			case tagOmitEmpty:
+				sf.omitEmpty = true
+			case tagReadOnly:
+				sf.readOnly = true
+			case tagAutoIncr:
+				sf.autoIncr = true
+			}
+		}
+		if sf.autoIncr {
+			info.autoIncr = sf
+		}
+		info.fields = append(info.fields, sf)
+		info.columns = append(info.columns, sf.column)
+	}
+	structInfos.Store(typ, info)
+	return info, nil
+}
+
+// structValue 结构体或结构体指针的值
+func structValue(data interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return v, fmt.Errorf("mysql: nil %s", v.Type())
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return v, fmt.Errorf("mysql: %s is not a struct", v.Type())
+	}
+	return v, nil
+}
+
+// setNow 填充时间字段，不支持的类型不处理
+func setNow(v reflect.Value, now time.Time) {
+	if !v.CanSet() {
+		return
+	}
+	switch {
+	case v.Type() == reflect.TypeOf(now):
+		v.Set(reflect.ValueOf(now))
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		v.SetInt(now.Unix())
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		v.SetUint(uint64(now.Unix()))
+	}
+}
+
+// setID 回填自增主键
+func setID(v reflect.Value, id int64) {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		v.SetInt(id)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		v.SetUint(uint64(id))
+	}
+}
+
+// insertColumns 批量写入的字段：omitempty、自增主键在全部行都为零值时才不写入，使每一行的字段一致
+func (info *structInfo) insertColumns(rows []reflect.Value) []*structField {
+	fields := make([]*structField, 0, len(info.fields))
+	for _, f := range info.fields {
+		if f.readOnly {
+			continue
+		}
+		if f.omitEmpty || f.autoIncr {
+			empty := true
+			for _, row := range rows {
+				if !row.Field(f.index).IsZero() {
+					empty = false
+					break
+				}
+			}
+			if empty {
+				continue
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// fillTimes 写入时填充 created_at、updated_at
+func (info *structInfo) fillTimes(row reflect.Value, now time.Time, insert bool) {
+	for _, f := range info.fields {
+		v := row.Field(f.index)
+		switch {
+		case f.column == ColumnUpdatedAt:
+			setNow(v, now)
+		case f.column == ColumnCreatedAt && insert && v.IsZero():
+			setNow(v, now)
+		}
+	}
+}
+
+func rowMap(row reflect.Value, fields []*structField) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.column] = row.Field(f.index).Interface()
+	}
+	return m
+}
+
+// InsertStructs 写入多行，字段由 ddb tag 确定
+//
+//	created_at 为零值时、updated_at 总是填充为当前时间
+//	自增主键全部为零值时不写入，mysql 写入后按 LastInsertId 依次回填，
+//	批量写入要求 innodb_autoinc_lock_mode 不为 2，否则 id 可能不连续；sqlite、postgres 不回填
+func InsertStructs[T any](ctx context.Context, s Session, tableName string, rows []*T) (sql.Result, error) {
+	info, values, fields, data, err := structsInsertData(rows)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Insert(ctx, tableName, data)
+	if err != nil {
+		return res, err
+	}
+	if firstInsertID(s) {
+		backfillIDs(info, fields, values, res)
+	}
+	return res, nil
+}
+
+// structsInsertData 结构体转换为写入的数据
+func structsInsertData[T any](rows []*T) (*structInfo, []reflect.Value, []*structField, []map[string]interface{}, error) {
+	if len(rows) == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("mysql: no rows to insert")
+	}
+	info, err := getStructInfo(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	now := time.Now()
+	values := make([]reflect.Value, len(rows))
+	for i, row := range rows {
+		if row == nil {
+			return nil, nil, nil, nil, fmt.Errorf("mysql: nil row %d", i)
+		}
+		values[i] = reflect.ValueOf(row).Elem()
+		info.fillTimes(values[i], now, true)
+	}
+	fields := info.insertColumns(values)
+	data := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		data[i] = rowMap(v, fields)
+	}
+	return info, values, fields, data, nil
+}
+
+// firstInsertID 写入多行时 LastInsertId 是否为第一行的 id，只有 mysql 如此
+//
+//	sqlite 为最后一行的 id，且 upsert 更新已有行时返回的是之前的 id；postgres 不支持 LastInsertId
+//	其他 Session 的实现（如 mysqltest.Fake）按 mysql 处理
+func firstInsertID(s Session) bool {
+	switch v := s.(type) {
+	case *client:
+		return v.dialect().name() == DriverMySQL
+	case *tx:
+		return v.c.dialect().name() == DriverMySQL
+	}
+	return true
+}
+
+// backfillIDs 自增主键未写入时，按 LastInsertId 依次回填
+func backfillIDs(info *structInfo, fields []*structField, values []reflect.Value, res sql.Result) {
+	if info.autoIncr == nil || containsField(fields, info.autoIncr) {
+		return
+	}
+	id, err := res.LastInsertId()
+	if err != nil || id <= 0 {
+		return
+	}
+	for i, v := range values {
+		setID(v.Field(info.autoIncr.index), id+int64(i))
+	}
+}
+
+func containsField(fields []*structField, f *structField) bool {
+	for _, ff := range fields {
+		if ff == f {
+			return true
+		}
+	}
+	return false
+}
+
+// updateFields 更新的字段：不含只读、自增主键、created_at，omitempty 的零值字段不更新
+func (info *structInfo) updateFields(row reflect.Value) []*structField {
+	fields := make([]*structField, 0, len(info.fields))
+	for _, f := range info.fields {
+		if f.readOnly || f.autoIncr || f.column == ColumnCreatedAt {
+			continue
+		}
+		if f.omitEmpty && row.Field(f.index).IsZero() {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// UpdateStruct 使用结构体的字段更新 where 匹配的行，updated_at 填充为当前时间
+//
+//	data 为指针时回填 updated_at
+func UpdateStruct(ctx context.Context, s Session, tableName string, where map[string]interface{}, data interface{}) (sql.Result, error) {
+	row, err := structValue(data)
+	if err != nil {
+		return nil, err
+	}
+	info, err := getStructInfo(row.Type())
+	if err != nil {
+		return nil, err
+	}
+	if !row.CanSet() {
+		// 非指针时复制一份，用于填充时间
+		cp := reflect.New(row.Type()).Elem()
+		cp.Set(row)
+		row = cp
+	}
+	info.fillTimes(row, time.Now(), false)
+	update := rowMap(row, info.updateFields(row))
+	if len(update) == 0 {
+		return nil, fmt.Errorf("mysql: no columns to update")
+	}
+	return s.Update(ctx, tableName, where, update)
+}
+
+// Upsert 写入一行，唯一键冲突时更新，更新的字段同 UpdateStruct
+//
+//	data 需为结构体指针，mysql 新写入时回填自增主键
+func Upsert(ctx context.Context, s Session, tableName string, data interface{}) (sql.Result, error) {
+	if reflect.ValueOf(data).Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("mysql: Upsert needs a pointer, got %T", data)
+	}
+	row, err := structValue(data)
+	if err != nil {
+		return nil, err
+	}
+	info, err := getStructInfo(row.Type())
+	if err != nil {
+		return nil, err
+	}
+	info.fillTimes(row, time.Now(), true)
+	fields := info.insertColumns([]reflect.Value{row})
+	update := rowMap(row, info.updateFields(row))
+	res, err := s.InsertOnDuplicate(ctx, tableName, []map[string]interface{}{rowMap(row, fields)}, update)
+	if err != nil {
+		return res, err
+	}
+	// 影响行数为 1 表示新写入，2 表示更新了已有的行；sqlite 两种情况都为 1，不能区分
+	if info.autoIncr != nil && !containsField(fields, info.autoIncr) && firstInsertID(s) {
+		if n, err := res.RowsAffected(); err == nil && n == 1 {
+			if id, err := res.LastInsertId(); err == nil && id > 0 {
+				setID(row.Field(info.autoIncr.index), id)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Get 查询一行，查询的字段由 ddb tag 确定，结果为空时返回 ErrNotFound
+func Get[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}) (*T, error) {
+	info, err := getStructInfo(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	w := make(map[string]interface{}, len(where)+1)
+	for k, v := range where {
+		w[k] = v
+	}
+	w["_limit"] = []uint{1}
+	data := new(T)
+	if err := s.Query(ctx, tableName, w, info.columns, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Select 查询多行，查询的字段由 ddb tag 确定，结果为空时返回空的切片
+func Select[T any](ctx context.Context, s Session, tableName string, where map[string]interface{}) ([]T, error) {
+	info, err := getStructInfo(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	var data []T
+	if err := s.Query(ctx, tableName, where, info.columns, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
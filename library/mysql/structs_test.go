@@ -0,0 +1,123 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 17:52:03
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 17:52:03
+ * @Description: 结构体增删改查用例
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type structUser struct {
+	ID        int64     `ddb:"id,autoincr"`
+	Name      string    `ddb:"name"`
+	Email     string    `ddb:"email,omitempty"`
+	Score     int       `ddb:"score,readonly"`
+	CreatedAt time.Time `ddb:"created_at"`
+	UpdatedAt int64     `ddb:"updated_at"`
+	Ignored   string
+}
+
+func lastStatement(fc *fakeConnector) string {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.log[len(fc.log)-1]
+}
+
+func TestStructWrite(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	fc.lastInsertID = 10
+
+	users := []*structUser{{Name: "a"}, {Name: "b"}}
+	if _, err := InsertStructs(ctx, c, "tb_user", users); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lastStatement(fc), "INSERT INTO tb_user (created_at,name,updated_at) VALUES (?,?,?),(?,?,?)"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if users[0].ID != 10 || users[1].ID != 11 || users[0].CreatedAt.IsZero() || users[1].UpdatedAt == 0 {
+		t.Errorf("ids and timestamps should be filled: %+v %+v", users[0], users[1])
+	}
+
+	// omitempty 的零值、自增主键、created_at 不更新
+	u := &structUser{ID: 10, Name: "c", CreatedAt: time.Now()}
+	if _, err := UpdateStruct(ctx, c, "tb_user", map[string]interface{}{"id": u.ID}, u); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lastStatement(fc), "UPDATE tb_user SET name=?,updated_at=? WHERE (id=?)"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if _, err := UpdateStruct(ctx, c, "tb_user", nil, structUser{Name: "d", Email: "d@x"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := lastStatement(fc); !strings.Contains(got, "email=?") {
+		t.Errorf("non empty omitempty column should be updated: %s", got)
+	}
+
+	fc.lastInsertID = 20
+	u = &structUser{Name: "e"}
+	if _, err := Upsert(ctx, c, "tb_user", u); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lastStatement(fc), "INSERT INTO tb_user (created_at,name,updated_at) VALUES (?,?,?) ON DUPLICATE KEY UPDATE name=?,updated_at=?"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if u.ID != 20 {
+		t.Errorf("inserted id should be filled, got %d", u.ID)
+	}
+	if _, err := Upsert(ctx, c, "tb_user", *u); err == nil {
+		t.Error("Upsert should require a pointer")
+	}
+}
+
+func TestStructRead(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	created := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	fc.rows = func(query string, args []driver.NamedValue) *fakeRows {
+		if args[0].Value == int64(404) {
+			return &fakeRows{}
+		}
+		return &fakeRows{
+			columns: []string{"id", "name", "created_at"},
+			values: [][]driver.Value{
+				{int64(1), []byte("a"), created},
+				{int64(2), []byte("b"), created},
+			},
+		}
+	}
+
+	u, err := Get[structUser](ctx, c, "tb_user", map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 || u.Name != "a" || !u.CreatedAt.Equal(created) {
+		t.Errorf("unexpected user %+v", u)
+	}
+	if got, want := lastStatement(fc), "SELECT id,name,email,score,created_at,updated_at FROM tb_user WHERE (id=?) LIMIT ?,?"; got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if _, err := Get[structUser](ctx, c, "tb_user", map[string]interface{}{"id": 404}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound, got %v", err)
+	}
+
+	users, err := Select[structUser](ctx, c, "tb_user", map[string]interface{}{"id in": []interface{}{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Name != "b" {
+		t.Errorf("unexpected users %+v", users)
+	}
+	if _, err := Select[string](ctx, c, "tb_user", nil); err == nil {
+		t.Error("non struct type should fail")
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 19:55:06
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 14:40:16
+ * @Description: sql 执行的链路追踪
+ */
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/liziwei01/gin-lib/library/trace"
+)
+
+// startSpan 为一次 sql 执行创建 client span，名称形如 mysql SELECT
+//
+//	只记录带占位符的 sql，不记录参数；sql 长度受 SQLLogLen 限制
+func startSpan(ctx context.Context, c *client, sql string) (context.Context, *trace.Span) {
+	op := "QUERY"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		op = strings.ToUpper(fields[0])
+	}
+	statement := sql
+	if n := c.sqlloglen(); n > 0 && len(statement) > n {
+		statement = statement[:n]
+	}
+	return trace.Start(ctx, "mysql "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			trace.String("db.system", c.dialect().name()),
+			trace.String("db.name", c.dbname()),
+			trace.String("db.operation", op),
+			trace.String("db.statement", statement),
+			trace.String("net.peer.name", c.host()),
+			trace.Int("net.peer.port", c.port()),
+		),
+	)
+}
+
+// endSpan 结束 span 并记录错误
+func endSpan(span *trace.Span, err error) {
+	span.RecordError(err)
+	span.End()
+}
@@ -0,0 +1,285 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 14:03:27
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 14:02:37
+ * @Description: 事务，支持隔离级别、只读、嵌套 savepoint 与死锁重试
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// mysql、postgres 死锁的错误码
+const (
+	errDeadlock   = 1213
+	errPgDeadlock = "40P01"
+)
+
+// 死锁重试的等待，之后每次翻倍
+const (
+	txDeadlockBackoff    = 10 * time.Millisecond
+	txDeadlockMaxBackoff = 200 * time.Millisecond
+)
+
+// Tx 事务，方法与 Client 一致，只能在 Transaction 的回调内使用，不可并发使用
+type Tx interface {
+	Query(ctx context.Context, tableName string, where map[string]interface{}, columns []string, data interface{}) error
+
+	Insert(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error)
+
+	InsertIgnore(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error)
+
+	InsertReplace(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error)
+
+	InsertOnDuplicate(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}) (sql.Result, error)
+
+	Update(ctx context.Context, tableName string, where map[string]interface{}, update map[string]interface{}) (sql.Result, error)
+
+	Delete(ctx context.Context, tableName string, where map[string]interface{}) (sql.Result, error)
+
+	ExecRaw(ctx context.Context, sql string, args ...interface{}) (sql.Result, error)
+
+	QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*Rows, error)
+
+	QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error)
+
+	QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error)
+
+	// Transaction 嵌套事务，使用 SAVEPOINT 实现
+	// 回调返回错误或 panic 时只回滚嵌套事务内的修改，外层事务可继续执行
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// TxOption 事务的配置选项
+type TxOption interface {
+	apply(*txOptions)
+}
+
+type txOptions struct {
+	isolation     sql.IsolationLevel
+	readOnly      bool
+	retryDeadlock bool
+}
+
+type txFuncOption struct {
+	f func(*txOptions)
+}
+
+func (fo *txFuncOption) apply(o *txOptions) {
+	fo.f(o)
+}
+
+func newTxFuncOption(f func(*txOptions)) *txFuncOption {
+	return &txFuncOption{
+		f: f,
+	}
+}
+
+// WithIsolation 事务的隔离级别，默认使用数据库的配置
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return newTxFuncOption(func(o *txOptions) {
+		o.isolation = level
+	})
+}
+
+// WithReadOnly 只读事务
+func WithReadOnly() TxOption {
+	return newTxFuncOption(func(o *txOptions) {
+		o.readOnly = true
+	})
+}
+
+// WithDeadlockRetry 发生死锁时重新执行整个事务，最多重试配置中的 Retry 次
+//
+//	回调可能被执行多次，回调内不应有数据库之外的副作用
+func WithDeadlockRetry() TxOption {
+	return newTxFuncOption(func(o *txOptions) {
+		o.retryDeadlock = true
+	})
+}
+
+// IsDeadlock 是否为死锁错误
+func IsDeadlock(err error) bool {
+	var (
+		me *driver.MySQLError
+		pe *pq.Error
+	)
+	if errors.As(err, &pe) {
+		return pe.Code == errPgDeadlock
+	}
+	return errors.As(err, &me) && me.Number == errDeadlock
+}
+
+// Transaction 在事务中执行 fn，fn 返回 nil 时提交，返回错误或 panic 时回滚
+func (dao *client) Transaction(ctx context.Context, fn func(tx Tx) error, opts ...TxOption) error {
+	o := &txOptions{}
+	for _, opt := range opts {
+		opt.apply(o)
+	}
+	retries := 0
+	if o.retryDeadlock {
+		retries = dao.retry()
+	}
+	for i := 0; ; i++ {
+		err := dao.transaction(ctx, fn, o)
+		if i >= retries || !IsDeadlock(err) || ctx.Err() != nil {
+			return err
+		}
+		// 立即重试很可能与对方事务再次死锁，等待一段随机的时间错开
+		timer := time.NewTimer(deadlockBackoff(i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// deadlockBackoff 第 attempt 次死锁重试前的等待，在 [d/2, d] 之间随机
+func deadlockBackoff(attempt int) time.Duration {
+	d := txDeadlockBackoff << uint(attempt)
+	if d <= 0 || d > txDeadlockMaxBackoff {
+		d = txDeadlockMaxBackoff
+	}
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func (dao *client) transaction(ctx context.Context, fn func(tx Tx) error, o *txOptions) (err error) {
+	db, err := dao.connect(ctx)
+	if err != nil {
+		return err
+	}
+	stx, err := db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: o.isolation,
+		ReadOnly:  o.readOnly,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = stx.Rollback()
+			panic(p)
+		}
+	}()
+	if err = fn(&tx{c: dao, tx: stx}); err != nil {
+		// ctx 取消时事务已自动回滚
+		if rbErr := stx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+	return stx.Commit()
+}
+
+type tx struct {
+	c  *client
+	tx *sql.Tx
+
+	// 已创建的 savepoint 数量，用于生成不重复的名称
+	savepoints int
+}
+
+var _ Tx = (*tx)(nil)
+
+func (t *tx) Query(ctx context.Context, tableName string, where map[string]interface{}, columns []string, data interface{}) error {
+	builder := NewSelectBuilder(tableName, where, columns)
+	return TxQueryWithBuilder(ctx, t, builder, data)
+}
+
+func (t *tx) Insert(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	builder := NewInsertBuilder(tableName, data, insertCommon)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) InsertIgnore(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	builder := NewInsertBuilder(tableName, data, insertIgnore)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) InsertReplace(ctx context.Context, tableName string, data []map[string]interface{}) (sql.Result, error) {
+	builder := NewInsertBuilder(tableName, data, insertReplace)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) InsertOnDuplicate(ctx context.Context, tableName string, data []map[string]interface{}, update map[string]interface{}) (sql.Result, error) {
+	builder := NewInsertBuilder(tableName, data, insertOnDuplicate, update)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) Update(ctx context.Context, tableName string, where map[string]interface{}, update map[string]interface{}) (sql.Result, error) {
+	builder := NewUpdateBuilder(tableName, where, update)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) Delete(ctx context.Context, tableName string, where map[string]interface{}) (sql.Result, error) {
+	builder := NewDeleteBuilder(tableName, where)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) ExecRaw(ctx context.Context, sql string, args ...interface{}) (sql.Result, error) {
+	builder := NewRawBuilder(sql, args)
+	return TxExecWithBuilder(ctx, t, builder)
+}
+
+func (t *tx) QueryRows(ctx context.Context, tableName string, where map[string]interface{}, columns []string) (*Rows, error) {
+	return queryRows(ctx, t.c, t.tx, NewSelectBuilder(tableName, where, columns))
+}
+
+func (t *tx) QueryPage(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *Page, data interface{}) (*PageInfo, error) {
+	return QueryPage(ctx, t, tableName, where, columns, page, data)
+}
+
+func (t *tx) QueryCursor(ctx context.Context, tableName string, where map[string]interface{}, columns []string, page *CursorPage, data interface{}) (string, error) {
+	return QueryCursor(ctx, t, tableName, where, columns, page, data)
+}
+
+func (t *tx) Transaction(ctx context.Context, fn func(tx Tx) error) (err error) {
+	t.savepoints++
+	name := fmt.Sprintf("sp_%d", t.savepoints)
+	if _, err = t.ExecRaw(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = t.ExecRaw(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+	if err = fn(t); err != nil {
+		if _, rbErr := t.ExecRaw(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+	_, err = t.ExecRaw(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// TxQueryWithBuilder 在事务中传入一个 SQLBuilder 并执行 QueryContext
+func TxQueryWithBuilder(ctx context.Context, t Tx, builder Builder, data interface{}) error {
+	if st, ok := t.(*tx); ok {
+		return queryWithBuilder(ctx, st.c, st.tx, builder, data)
+	}
+	return sessionQuery(ctx, t, builder, data)
+}
+
+// TxExecWithBuilder 在事务中传入一个 SQLBuilder 并执行 ExecContext
+func TxExecWithBuilder(ctx context.Context, t Tx, builder Builder) (sql.Result, error) {
+	if st, ok := t.(*tx); ok {
+		return execWithBuilder(ctx, st.c, st.tx, builder)
+	}
+	return sessionExec(ctx, t, builder)
+}
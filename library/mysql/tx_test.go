@@ -0,0 +1,143 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 14:31:05
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 14:02:37
+ * @Description: 事务用例
+ */
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestTransaction(t *testing.T) {
+	ctx := context.Background()
+	c, fc := newFakeClient(0)
+	user := []map[string]interface{}{{"user_id": 1}}
+	where := map[string]interface{}{"user_id": 1}
+	update := map[string]interface{}{"nickname": "a"}
+
+	err := c.Transaction(ctx, func(tx Tx) error {
+		if _, err := tx.Insert(ctx, test_table_name, user); err != nil {
+			return err
+		}
+		// 嵌套事务失败只回滚到 savepoint
+		err := tx.Transaction(ctx, func(tx Tx) error {
+			tx.Update(ctx, test_table_name, where, update)
+			return errors.New("inner")
+		})
+		if err == nil {
+			return errors.New("inner error should be returned")
+		}
+		return tx.Transaction(ctx, func(tx Tx) error {
+			_, err := tx.Delete(ctx, test_table_name, where)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN; INSERT; SAVEPOINT sp_1; UPDATE; ROLLBACK TO SAVEPOINT sp_1; SAVEPOINT sp_2; DELETE; RELEASE SAVEPOINT sp_2; COMMIT"
+	if got := fc.statements(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	errBiz := errors.New("biz")
+	err = c.Transaction(ctx, func(tx Tx) error {
+		tx.Insert(ctx, test_table_name, user)
+		return errBiz
+	}, WithIsolation(sql.LevelSerializable), WithReadOnly())
+	if !errors.Is(err, errBiz) {
+		t.Errorf("want biz error, got %v", err)
+	}
+	if got, want := fc.statements(), "BEGIN Serializable READONLY; INSERT; ROLLBACK"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic should be re-raised")
+			}
+		}()
+		c.Transaction(ctx, func(tx Tx) error {
+			panic("boom")
+		})
+	}()
+	if got, want := fc.statements(), "BEGIN; ROLLBACK"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestTransactionDeadlockRetry(t *testing.T) {
+	ctx := context.Background()
+	deadlock := &mysqldriver.MySQLError{Number: errDeadlock, Message: "Deadlock found"}
+	cases := []struct {
+		retry    int
+		opts     []TxOption
+		failures int
+		attempts int
+		ok       bool
+	}{
+		{retry: 2, opts: []TxOption{WithDeadlockRetry()}, failures: 2, attempts: 3, ok: true},
+		{retry: 2, opts: []TxOption{WithDeadlockRetry()}, failures: 5, attempts: 3},
+		{retry: 2, failures: 1, attempts: 1},
+	}
+	for i, cs := range cases {
+		c, fc := newFakeClient(cs.retry)
+		failures := cs.failures
+		fc.execErr = func(query string) error {
+			if failures > 0 {
+				failures--
+				return deadlock
+			}
+			return nil
+		}
+		attempts := 0
+		err := c.Transaction(ctx, func(tx Tx) error {
+			attempts++
+			_, err := tx.ExecRaw(ctx, "UPDATE t SET n = n + 1")
+			return err
+		}, cs.opts...)
+		if attempts != cs.attempts || (err == nil) != cs.ok {
+			t.Errorf("case %d: attempts=%d err=%v", i, attempts, err)
+		}
+		if err != nil && !IsDeadlock(err) {
+			t.Errorf("case %d: want deadlock error, got %v", i, err)
+		}
+	}
+	if IsDeadlock(fmt.Errorf("wrap: %w", &mysqldriver.MySQLError{Number: 1062})) {
+		t.Error("duplicate entry is not a deadlock")
+	}
+}
+
+func TestTransactionDeadlockBackoff(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		if d := deadlockBackoff(i); d < txDeadlockBackoff/2 || d > txDeadlockMaxBackoff {
+			t.Errorf("attempt %d: backoff %v out of range", i, d)
+		}
+	}
+	// ctx 结束后不再重试，直接返回死锁错误
+	ctx, cancel := context.WithCancel(context.Background())
+	c, fc := newFakeClient(5)
+	fc.execErr = func(query string) error {
+		return &mysqldriver.MySQLError{Number: errDeadlock, Message: "Deadlock found"}
+	}
+	attempts := 0
+	err := c.Transaction(ctx, func(tx Tx) error {
+		attempts++
+		_, err := tx.ExecRaw(ctx, "UPDATE t SET n = n + 1")
+		cancel()
+		return err
+	}, WithDeadlockRetry())
+	if attempts != 1 || !IsDeadlock(err) {
+		t.Errorf("attempts=%d err=%v", attempts, err)
+	}
+}
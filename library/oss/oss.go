@@ -15,16 +15,18 @@ import (
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
-func (c *client) Get(ctx context.Context, bucket string, objectKey string) (*bytes.Reader, error) {
+func (c *client) Get(ctx context.Context, bucket string, objectKey string) (reader *bytes.Reader, err error) {
+	ctx, span := c.startSpan(ctx, "GetObject", bucket, objectKey)
+	defer func() { endSpan(span, err) }()
 	ossBucket, err := c.connect(ctx, bucket)
 	if err != nil {
 		return nil, err
 	}
 	file, err := ossBucket.GetObject(objectKey)
-	defer file.Close()
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
 		return nil, err
@@ -32,7 +34,9 @@ func (c *client) Get(ctx context.Context, bucket string, objectKey string) (*byt
 	return bytes.NewReader(fileBytes), nil
 }
 
-func (c *client) Put(ctx context.Context, bucket string, objectKey string, fileReader *bytes.Reader) error {
+func (c *client) Put(ctx context.Context, bucket string, objectKey string, fileReader *bytes.Reader) (err error) {
+	ctx, span := c.startSpan(ctx, "PutObject", bucket, objectKey)
+	defer func() { endSpan(span, err) }()
 	ossBucket, err := c.connect(ctx, bucket)
 	if err != nil {
 		return err
@@ -44,7 +48,9 @@ func (c *client) Put(ctx context.Context, bucket string, objectKey string, fileR
 	return nil
 }
 
-func (c *client) Del(ctx context.Context, bucket string, objectKey string) error {
+func (c *client) Del(ctx context.Context, bucket string, objectKey string) (err error) {
+	ctx, span := c.startSpan(ctx, "DeleteObject", bucket, objectKey)
+	defer func() { endSpan(span, err) }()
 	ossBucket, err := c.connect(ctx, bucket)
 	if err != nil {
 		return err
@@ -56,12 +62,14 @@ func (c *client) Del(ctx context.Context, bucket string, objectKey string) error
 	return nil
 }
 
-func (c *client) GetURL(ctx context.Context, bucket string, objectKey string) (string, error) {
+func (c *client) GetURL(ctx context.Context, bucket string, objectKey string) (url string, err error) {
+	ctx, span := c.startSpan(ctx, "SignURL", bucket, objectKey)
+	defer func() { endSpan(span, err) }()
 	ossBucket, err := c.connect(ctx, bucket)
 	if err != nil {
 		return "", err
 	}
-	url, err := ossBucket.SignURL(objectKey, oss.HTTPGet, 60)
+	url, err = ossBucket.SignURL(objectKey, oss.HTTPGet, 60)
 	if err != nil {
 		return "", err
 	}
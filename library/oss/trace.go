@@ -0,0 +1,34 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 20:14:21
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 20:14:21
+ * @Description: oss 调用的链路追踪
+ */
+package oss
+
+import (
+	"context"
+
+	"github.com/liziwei01/gin-lib/library/trace"
+)
+
+// startSpan 为一次 oss 调用创建 client span，名称形如 oss GetObject
+func (c *client) startSpan(ctx context.Context, op string, bucket string, objectKey string) (context.Context, *trace.Span) {
+	return trace.Start(ctx, "oss "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			trace.String("rpc.system", "oss"),
+			trace.String("rpc.method", op),
+			trace.String("oss.endpoint", c.conf.OSS.Endpoint),
+			trace.String("oss.bucket", bucket),
+			trace.String("oss.object_key", objectKey),
+		),
+	)
+}
+
+// endSpan 结束 span 并记录错误
+func endSpan(span *trace.Span, err error) {
+	span.RecordError(err)
+	span.End()
+}
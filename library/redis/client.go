@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-04 13:52:11
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-29 11:33:44
+ * @LastEditTime: 2026-10-21 21:34:51
  * @Description: file content
  */
 package redis
@@ -14,6 +14,8 @@ import (
 
 	r "github.com/go-redis/redis"
 	"github.com/gogf/gf/util/gconv"
+
+	"github.com/liziwei01/gin-lib/library/resilience"
 )
 
 var (
@@ -32,6 +34,8 @@ type Client interface {
 	Del(ctx context.Context, keys ...string) error
 	// Determine if a key exists
 	Exists(ctx context.Context, keys ...string) (int64, error)
+	// Eval 执行 lua 脚本，脚本内的多个命令原子执行
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
 	// Expired
 	// Expired(ctx context.Context, key string) (bool, error)
 
@@ -47,12 +51,17 @@ type Client interface {
 type client struct {
 	conf *Config
 	db   *r.Client
+	// 命令的重试、熔断与并发隔离
+	policy *resilience.Policy
 }
 
 func New(config *Config) Client {
 	c := &client{
 		conf: config,
 	}
+	if config != nil {
+		c.policy = resilience.New(config.Name, config.Retry, config.Resilience, resilience.WithFailure(unavailable))
+	}
 	return c
 }
 
@@ -72,16 +81,13 @@ func (c *client) open() (*r.Client, error) {
 		db  *r.Client
 		err error
 	)
-	// 内含 retry 2
+	// 重试由 policy 负责，go-redis 自身不重试
 	db = r.NewClient(&r.Options{
-		Addr:            c.host() + ":" + c.port(),
-		Password:        c.password(),
-		DB:              c.dbname(),
-		WriteTimeout:    time.Duration(c.writeTimeOut()) * time.Millisecond,
-		ReadTimeout:     time.Duration(c.readTimeOut()) * time.Millisecond,
-		MaxRetries:      c.conf.Retry,
-		MinRetryBackoff: 8 * time.Millisecond,
-		MaxRetryBackoff: 512 * time.Millisecond,
+		Addr:         c.host() + ":" + c.port(),
+		Password:     c.password(),
+		DB:           c.dbname(),
+		WriteTimeout: time.Duration(c.writeTimeOut()) * time.Millisecond,
+		ReadTimeout:  time.Duration(c.readTimeOut()) * time.Millisecond,
 	})
 	return db, err
 }
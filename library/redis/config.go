@@ -2,11 +2,13 @@
  * @Author: liziwei01
  * @Date: 2022-03-04 15:42:58
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-03-30 23:16:33
+ * @LastEditTime: 2026-10-21 21:34:51
  * @Description: file content
  */
 package redis
 
+import "github.com/liziwei01/gin-lib/library/resilience"
+
 // Config 配置
 type Config struct {
 	// Service的名字, 必选
@@ -17,9 +19,12 @@ type Config struct {
 	WriteTimeOut int
 	// 读数据超时
 	ReadTimeOut int
-	// 请求失败后的重试次数: 总请求次数 = Retry + 1
+	// 请求失败后的重试次数: 总请求次数 = Retry + 1, 只重试 redis 不可用的错误, EVAL 不重试
 	Retry int
 
+	// 重试的退避、熔断与并发隔离, 全部非必选
+	Resilience resilience.Config
+
 	// 资源定位: 手动配置 - 使用IP、端口
 	Resource struct {
 		Manual struct {
@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-21 22:36:04
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-29 12:44:24
+ * @LastEditTime: 2026-10-21 21:34:51
  * @Description: file content
  */
 package redis
@@ -10,56 +10,84 @@ package redis
 import (
 	"context"
 	"time"
+
+	r "github.com/go-redis/redis"
 )
 
 func (c *client) Get(ctx context.Context, key string) (value string, err error) {
-	db, err := c.connect(ctx)
-	if err != nil {
-		return "", err
-	}
-	ret, err := db.Get(key).Result()
+	ctx, span := c.startSpan(ctx, "GET")
+	defer func() { endSpan(span, err) }()
+	err = c.policy.Do(ctx, unavailable, func(ctx context.Context) error {
+		db, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+		value, err = db.Get(key).Result()
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
-	return ret, nil
+	return value, nil
 }
 
-func (c *client) Set(ctx context.Context, key string, value string, expireTime ...time.Duration) error {
+func (c *client) Set(ctx context.Context, key string, value string, expireTime ...time.Duration) (err error) {
+	ctx, span := c.startSpan(ctx, "SET")
+	defer func() { endSpan(span, err) }()
 	var exp time.Duration = time.Hour
-	db, err := c.connect(ctx)
-	if err != nil {
-		return err
-	}
 	if len(expireTime) > 0 {
 		exp = expireTime[0]
 	}
-	err = db.Set(key, value, exp).Err()
-	if err != nil {
-		return err
-	}
-	return err
+	return c.policy.Do(ctx, unavailable, func(ctx context.Context) error {
+		db, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+		return db.Set(key, value, exp).Err()
+	})
 }
 
-func (c *client) Del(ctx context.Context, keys ...string) error {
-	db, err := c.connect(ctx)
-	if err != nil {
-		return err
-	}
-	err = db.Del(keys...).Err()
-	if err != nil {
-		return err
-	}
-	return err
+func (c *client) Del(ctx context.Context, keys ...string) (err error) {
+	ctx, span := c.startSpan(ctx, "DEL")
+	defer func() { endSpan(span, err) }()
+	return c.policy.Do(ctx, unavailable, func(ctx context.Context) error {
+		db, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+		return db.Del(keys...).Err()
+	})
 }
 
-func (c *client) Exists(ctx context.Context, keys ...string) (int64, error) {
-	db, err := c.connect(ctx)
-	if err != nil {
-		return 0, err
-	}
-	ret, err := db.Exists(keys...).Result()
+func (c *client) Exists(ctx context.Context, keys ...string) (n int64, err error) {
+	ctx, span := c.startSpan(ctx, "EXISTS")
+	defer func() { endSpan(span, err) }()
+	err = c.policy.Do(ctx, unavailable, func(ctx context.Context) error {
+		db, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+		n, err = db.Exists(keys...).Result()
+		return err
+	})
 	if err != nil {
 		return 0, err
 	}
-	return ret, nil
+	return n, nil
+}
+
+// Eval 脚本可能已部分执行，失败后不重试
+func (c *client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (ret interface{}, err error) {
+	ctx, span := c.startSpan(ctx, "EVAL")
+	defer func() { endSpan(span, err) }()
+	err = c.policy.Do(ctx, nil, func(ctx context.Context) error {
+		db, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+		// 优先使用 EVALSHA，脚本未缓存时回退为 EVAL
+		ret, err = r.NewScript(script).Run(db, keys, args...).Result()
+		return err
+	})
+	return ret, err
 }
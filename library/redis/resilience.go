@@ -0,0 +1,48 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 21:34:51
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-21 21:34:51
+ * @Description: 重试与熔断对 redis 错误的判断
+ */
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	r "github.com/go-redis/redis"
+)
+
+// redis 不可用时的错误回复前缀：加载数据、只读副本、集群或主节点下线
+var unavailableReplies = []string{"LOADING ", "READONLY ", "CLUSTERDOWN ", "MASTERDOWN "}
+
+// unavailable redis 不可用的错误，计入熔断，GET、SET、DEL、EXISTS 可以重试
+//
+//	key 不存在(redis.Nil)、类型错误、脚本错误等说明 redis 可用，不计入
+func unavailable(err error) bool {
+	if err == nil || err == r.Nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	// go-redis 的错误回复类型不导出，只能按内容判断
+	msg := err.Error()
+	if msg == "redis: connection pool timeout" || msg == "ERR max number of clients reached" {
+		return true
+	}
+	for _, prefix := range unavailableReplies {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 20:06:44
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 20:06:44
+ * @Description: redis 命令的链路追踪
+ */
+package redis
+
+import (
+	"context"
+
+	r "github.com/go-redis/redis"
+	"github.com/liziwei01/gin-lib/library/trace"
+)
+
+// startSpan 为一次 redis 命令创建 client span，名称形如 redis GET
+func (c *client) startSpan(ctx context.Context, cmd string) (context.Context, *trace.Span) {
+	return trace.Start(ctx, "redis "+cmd,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			trace.String("db.system", "redis"),
+			trace.String("db.operation", cmd),
+			trace.Int("db.redis.database_index", c.dbname()),
+			trace.String("net.peer.name", c.host()),
+			trace.String("net.peer.port", c.port()),
+		),
+	)
+}
+
+// endSpan 结束 span 并记录错误，key 不存在(redis.Nil)不视为错误
+func endSpan(span *trace.Span, err error) {
+	if err != r.Nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -2,24 +2,80 @@
  * @Author: liziwei01
  * @Date: 2023-10-28 12:26:10
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-11-01 09:48:50
- * @Description: file content
+ * @LastEditTime: 2026-10-19 21:31:52
+ * @Description: requestID 中间件，gin.Context、请求 ctx 与响应头中的 requestID 以此为准
  */
 package request
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/liziwei01/gin-lib/library/logit"
 )
 
+const (
+	// HeaderRequestID 上下游传递 requestID 的请求头
+	HeaderRequestID = "X-Request-ID"
+
+	// 上游传入的 requestID 最大长度，超出或包含不可见字符时重新生成，避免污染日志
+	maxRequestIDLen = 128
+)
+
+// RequestIDMiddleware 为每个请求确定唯一的 requestID
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := c.Request.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = logit.NewRequestID()
-		}
-		c.Writer.Header().Set("X-Request-ID", requestID)
-		c.Set("requestID", requestID)
+		EnsureRequestID(c)
 		c.Next()
 	}
 }
+
+// EnsureRequestID 获取当前请求的 requestID，不存在时确定一个并写入各处，重复调用返回同一个值
+//
+//	优先使用上游传入的 X-Request-ID，否则使用 logit.NewRequestID 生成
+//	写入 gin.Context(key 为 requestID)、请求 ctx 的 logit 字段以及响应头
+func EnsureRequestID(c *gin.Context) string {
+	if v, ok := c.Get(logit.FieldRequestID); ok {
+		if requestID, ok := v.(string); ok && requestID != "" {
+			return requestID
+		}
+	}
+
+	requestID := c.Request.Header.Get(HeaderRequestID)
+	if !validRequestID(requestID) {
+		requestID = logit.NewRequestID()
+	}
+
+	c.Request = c.Request.WithContext(logit.WithContext(c.Request.Context()))
+	logit.SetRequestID(c.Request.Context(), requestID)
+	c.Set(logit.FieldRequestID, requestID)
+	c.Writer.Header().Set(HeaderRequestID, requestID)
+	return requestID
+}
+
+// GetRequestID 获取 ctx 中的 requestID，不存在时返回空串
+//
+//	ctx 可以是 *gin.Context，也可以是请求的 ctx 或由其派生的 ctx
+func GetRequestID(ctx context.Context) string {
+	if f := logit.FindRequestIDField(ctx); f != nil {
+		if requestID, ok := f.Value().(string); ok {
+			return requestID
+		}
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return c.GetString(logit.FieldRequestID)
+	}
+	return ""
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 20:15:33
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 10:26:05
+ * @Description: 熔断器与并发隔离
+ */
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOpen 熔断中，调用未执行
+	ErrOpen = errors.New("resilience: circuit breaker is open")
+
+	// ErrBulkheadFull 并发请求数已满，调用未执行
+	ErrBulkheadFull = errors.New("resilience: too many concurrent calls")
+)
+
+// State 熔断器的状态
+type State int
+
+const (
+	// StateClosed 正常放行
+	StateClosed State = iota
+	// StateHalfOpen 放行少量探测请求
+	StateHalfOpen
+	// StateOpen 拒绝所有请求
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// breaker 连续失败 failures 次后熔断 openTime，之后半开放行 probes 个探测请求
+//
+//	探测请求全部成功后恢复，任一失败重新熔断
+//	每次状态变化 generation 加 1，之前状态下发出的请求的结果被忽略
+type breaker struct {
+	failures int
+	openTime time.Duration
+	probes   int
+	onChange func(ctx context.Context, from, to State)
+
+	mu          sync.Mutex
+	state       State
+	generation  uint64
+	consecutive int
+	openedAt    time.Time
+	// 半开状态已放行、已成功的探测请求数
+	probing   int
+	succeeded int
+}
+
+func newBreaker(failures int, openTime time.Duration, probes int, onChange func(ctx context.Context, from, to State)) *breaker {
+	return &breaker{
+		failures: failures,
+		openTime: openTime,
+		probes:   probes,
+		onChange: onChange,
+	}
+}
+
+// transition 一次状态变化，释放 b.mu 后再通知 onChange
+type transition struct {
+	from, to State
+}
+
+// allow 是否放行，返回请求所属的 generation
+func (b *breaker) allow(ctx context.Context) (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	gen, changed, err := b.admit()
+	b.notify(ctx, changed)
+	return gen, err
+}
+
+func (b *breaker) admit() (uint64, *transition, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var changed *transition
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openTime {
+		changed = b.setState(StateHalfOpen)
+	}
+	switch b.state {
+	case StateOpen:
+		return 0, changed, ErrOpen
+	case StateHalfOpen:
+		if b.probing >= b.probes {
+			return 0, changed, ErrOpen
+		}
+		b.probing++
+	}
+	return b.generation, changed, nil
+}
+
+// done 记录请求的结果
+func (b *breaker) done(ctx context.Context, gen uint64, failed bool) {
+	if b == nil {
+		return
+	}
+	b.notify(ctx, b.record(gen, failed))
+}
+
+func (b *breaker) record(gen uint64, failed bool) *transition {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.generation {
+		return nil
+	}
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.consecutive = 0
+			return nil
+		}
+		b.consecutive++
+		if b.consecutive >= b.failures {
+			return b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		if failed {
+			return b.setState(StateOpen)
+		}
+		b.succeeded++
+		if b.succeeded >= b.probes {
+			return b.setState(StateClosed)
+		}
+	}
+	return nil
+}
+
+// setState 需持有 b.mu，返回的状态变化由调用方在释放锁后通知
+func (b *breaker) setState(to State) *transition {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.consecutive, b.probing, b.succeeded = 0, 0, 0
+	if to == StateOpen {
+		b.openedAt = time.Now()
+	}
+	return &transition{from: from, to: to}
+}
+
+// notify 通知状态变化，onChange 会打印日志，不能持有 b.mu 调用
+func (b *breaker) notify(ctx context.Context, changed *transition) {
+	if changed != nil && b.onChange != nil {
+		b.onChange(ctx, changed.from, changed.to)
+	}
+}
+
+func (b *breaker) current() State {
+	if b == nil {
+		return StateClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.openTime {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// bulkhead 限制同时执行的请求数，避免慢依赖占满调用方的 goroutine 与连接
+type bulkhead struct {
+	sem  chan struct{}
+	wait time.Duration
+}
+
+func newBulkhead(n int, wait time.Duration) *bulkhead {
+	return &bulkhead{
+		sem:  make(chan struct{}, n),
+		wait: wait,
+	}
+}
+
+func (b *bulkhead) acquire(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if b.wait <= 0 {
+		return ErrBulkheadFull
+	}
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+	select {
+	case b.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return ErrBulkheadFull
+	}
+}
+
+func (b *bulkhead) release() {
+	if b != nil {
+		<-b.sem
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 20:15:33
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 10:26:05
+ * @Description: 依赖调用的容错：重试、熔断与并发隔离
+ */
+package resilience
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+// 默认配置
+const (
+	defaultRetryBackoff    = 20 * time.Millisecond
+	defaultRetryMaxBackoff = time.Second
+	defaultBreakerFailures = 5
+	defaultBreakerOpenTime = 10 * time.Second
+	defaultBreakerProbes   = 1
+)
+
+// Config 容错配置，位于 servicer 配置的 [Resilience]，全部非必选
+//
+//	重试次数使用 servicer 配置的 Retry
+type Config struct {
+	// 首次重试前的等待(ms)，之后每次翻倍并加上随机抖动，默认 20
+	RetryBackoff int
+	// 重试等待的上限(ms)，默认 1000
+	RetryMaxBackoff int
+	// 连续失败多少次后熔断，默认 5，-1 不熔断
+	BreakerFailures int
+	// 熔断的时长(ms)，之后进入半开状态放行探测请求，默认 10000
+	BreakerOpenTime int
+	// 半开状态放行的探测请求数，全部成功后恢复，任一失败重新熔断，默认 1
+	BreakerProbes int
+	// 最大并发请求数，默认 0 不限制
+	MaxConcurrent int
+	// 并发已满时等待的最长时间(ms)，默认 0 不等待
+	MaxWait int
+}
+
+var (
+	breakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "resilience_breaker_state",
+			Help: "Circuit breaker state of the servicer: 0 closed, 1 half-open, 2 open.",
+		},
+		[]string{"servicer"},
+	)
+	breakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_breaker_transitions_total",
+			Help: "Number of circuit breaker state changes by new state.",
+		},
+		[]string{"servicer", "state"},
+	)
+	rejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_rejected_total",
+			Help: "Number of calls rejected by the circuit breaker or the bulkhead.",
+		},
+		[]string{"servicer", "reason"},
+	)
+	retries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "resilience_retries_total",
+			Help: "Number of retried calls.",
+		},
+		[]string{"servicer"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(breakerState, breakerTransitions, rejected, retries)
+}
+
+// Policy 一个依赖（servicer）的容错策略，并发安全
+//
+//	nil 的 Policy 直接执行调用，不做任何处理
+type Policy struct {
+	name       string
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	failure    func(err error) bool
+
+	breaker  *breaker
+	bulkhead *bulkhead
+}
+
+// Option 容错策略的配置选项
+type Option interface {
+	apply(*Policy)
+}
+
+type funcOption struct {
+	f func(*Policy)
+}
+
+func (fo *funcOption) apply(p *Policy) {
+	fo.f(p)
+}
+
+func newFuncOption(f func(*Policy)) *funcOption {
+	return &funcOption{
+		f: f,
+	}
+}
+
+// WithFailure 计入熔断的错误，默认所有错误
+//
+//	记录不存在、唯一键冲突这类业务错误说明依赖可用，不应计入
+func WithFailure(failure func(err error) bool) Option {
+	return newFuncOption(func(p *Policy) {
+		p.failure = failure
+	})
+}
+
+// New 创建 servicer 的容错策略，retries 为失败后的重试次数
+func New(name string, retries int, conf Config, opts ...Option) *Policy {
+	p := &Policy{
+		name:       name,
+		retries:    retries,
+		backoff:    millis(conf.RetryBackoff, defaultRetryBackoff),
+		maxBackoff: millis(conf.RetryMaxBackoff, defaultRetryMaxBackoff),
+		failure:    func(err error) bool { return err != nil },
+	}
+	for _, opt := range opts {
+		opt.apply(p)
+	}
+	if conf.BreakerFailures >= 0 {
+		failures := conf.BreakerFailures
+		if failures == 0 {
+			failures = defaultBreakerFailures
+		}
+		probes := conf.BreakerProbes
+		if probes <= 0 {
+			probes = defaultBreakerProbes
+		}
+		p.breaker = newBreaker(failures, millis(conf.BreakerOpenTime, defaultBreakerOpenTime), probes, p.stateChanged)
+		breakerState.WithLabelValues(name).Set(float64(StateClosed))
+	}
+	if conf.MaxConcurrent > 0 {
+		p.bulkhead = newBulkhead(conf.MaxConcurrent, time.Duration(conf.MaxWait)*time.Millisecond)
+	}
+	return p
+}
+
+func millis(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// Do 执行 fn，失败时 retryable 返回 true 的错误按指数退避重试，retryable 为 nil 时不重试
+//
+//	熔断时返回 ErrOpen，并发已满时返回 ErrBulkheadFull，这两种错误不重试
+//	非幂等的写操作只应重试确定没有执行的错误
+func (p *Policy) Do(ctx context.Context, retryable func(err error) bool, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+	for attempt := 0; ; attempt++ {
+		err := p.call(ctx, fn)
+		if err == nil || retryable == nil || attempt >= p.retries || !retryable(err) {
+			return err
+		}
+		retries.WithLabelValues(p.name).Inc()
+		timer := time.NewTimer(p.wait(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// call 执行一次调用
+func (p *Policy) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := p.bulkhead.acquire(ctx); err != nil {
+		rejected.WithLabelValues(p.name, "bulkhead_full").Inc()
+		return err
+	}
+	defer p.bulkhead.release()
+	gen, err := p.breaker.allow(ctx)
+	if err != nil {
+		rejected.WithLabelValues(p.name, "breaker_open").Inc()
+		return err
+	}
+	// fn panic 时记为失败，否则半开状态占用的探测名额不会归还，熔断器无法恢复
+	defer func() {
+		if r := recover(); r != nil {
+			p.breaker.done(ctx, gen, true)
+			panic(r)
+		}
+	}()
+	err = fn(ctx)
+	p.breaker.done(ctx, gen, p.failure(err))
+	return err
+}
+
+// wait 第 attempt 次重试前的等待，在 [d/2, d] 之间随机，避免多个实例同时重试
+func (p *Policy) wait(attempt int) time.Duration {
+	d := p.backoff << uint(attempt)
+	if d <= 0 || d > p.maxBackoff {
+		d = p.maxBackoff
+	}
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// State 熔断器的状态，不熔断时总是 StateClosed
+func (p *Policy) State() State {
+	if p == nil {
+		return StateClosed
+	}
+	return p.breaker.current()
+}
+
+// stateChanged 熔断器状态变化时打印日志并更新 metrics
+func (p *Policy) stateChanged(ctx context.Context, from, to State) {
+	breakerState.WithLabelValues(p.name).Set(float64(to))
+	breakerTransitions.WithLabelValues(p.name, to.String()).Inc()
+	fields := []logit.Field{
+		logit.String("servicer", p.name),
+		logit.String("from", from.String()),
+		logit.String("to", to.String()),
+	}
+	if to == StateOpen {
+		logit.Svr().Warning(ctx, "resilience breaker opened", fields...)
+		return
+	}
+	logit.Svr().Notice(ctx, "resilience breaker state changed", fields...)
+}
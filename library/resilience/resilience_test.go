@@ -0,0 +1,220 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-21 20:52:40
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-23 10:26:05
+ * @Description: 重试、熔断与并发隔离用例
+ */
+package resilience
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/logit/logittest"
+)
+
+var (
+	errTemporary = errors.New("temporary")
+	errBusiness  = errors.New("business")
+)
+
+func isTemporary(err error) bool {
+	return errors.Is(err, errTemporary)
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	p := New("retry_test", 2, Config{RetryBackoff: 1, BreakerFailures: -1})
+
+	before := testutil.ToFloat64(retries.WithLabelValues("retry_test"))
+	calls := 0
+	err := p.Do(ctx, isTemporary, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("want success after 3 calls, got %d %v", calls, err)
+	}
+	if got := testutil.ToFloat64(retries.WithLabelValues("retry_test")) - before; got != 2 {
+		t.Errorf("want 2 retries, got %v", got)
+	}
+
+	calls = 0
+	err = p.Do(ctx, isTemporary, func(ctx context.Context) error {
+		calls++
+		return errBusiness
+	})
+	if !errors.Is(err, errBusiness) || calls != 1 {
+		t.Errorf("business error should not be retried, got %d %v", calls, err)
+	}
+
+	calls = 0
+	err = p.Do(ctx, nil, func(ctx context.Context) error {
+		calls++
+		return errTemporary
+	})
+	if calls != 1 {
+		t.Errorf("nil retryable should not retry, got %d", calls)
+	}
+
+	// 等待重试时 ctx 取消
+	p = New("retry_cancel", 5, Config{RetryBackoff: 1000, BreakerFailures: -1})
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := p.Do(ctx, isTemporary, func(ctx context.Context) error { return errTemporary }); !errors.Is(err, errTemporary) || time.Since(start) > 300*time.Millisecond {
+		t.Errorf("unexpected %v after %s", err, time.Since(start))
+	}
+
+	var nilPolicy *Policy
+	if err := nilPolicy.Do(context.Background(), isTemporary, func(ctx context.Context) error { return nil }); err != nil || nilPolicy.State() != StateClosed {
+		t.Errorf("nil policy: %v", err)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	o := logittest.ReplaceSvrLogger(t)
+	ctx := context.Background()
+	p := New("breaker_test", 0, Config{BreakerFailures: 2, BreakerOpenTime: 30}, WithFailure(isTemporary))
+	fail := func(ctx context.Context) error { return errTemporary }
+	ok := func(ctx context.Context) error { return nil }
+	state := breakerState.WithLabelValues("breaker_test")
+	rejectedBefore := testutil.ToFloat64(rejected.WithLabelValues("breaker_test", "breaker_open"))
+
+	// 业务错误不计入熔断
+	for i := 0; i < 3; i++ {
+		p.Do(ctx, nil, func(ctx context.Context) error { return errBusiness })
+	}
+	p.Do(ctx, nil, fail)
+	p.Do(ctx, nil, ok)
+	p.Do(ctx, nil, fail)
+	if p.State() != StateClosed {
+		t.Fatalf("failures are not consecutive, got %s", p.State())
+	}
+	p.Do(ctx, nil, fail)
+	if p.State() != StateOpen || testutil.ToFloat64(state) != float64(StateOpen) {
+		t.Fatalf("want open, got %s", p.State())
+	}
+	o.AssertLogged(t, logit.WarningLevel, "resilience breaker opened", "servicer", "from", "to")
+	called := false
+	if err := p.Do(ctx, nil, func(ctx context.Context) error { called = true; return nil }); !errors.Is(err, ErrOpen) || called {
+		t.Errorf("open breaker should reject, got %v %v", err, called)
+	}
+
+	// 半开时探测失败重新熔断
+	time.Sleep(35 * time.Millisecond)
+	if p.State() != StateHalfOpen {
+		t.Fatalf("want half-open, got %s", p.State())
+	}
+	p.Do(ctx, nil, fail)
+	if p.State() != StateOpen {
+		t.Fatalf("failed probe should reopen, got %s", p.State())
+	}
+
+	// 半开时只放行一个探测请求，成功后恢复
+	time.Sleep(35 * time.Millisecond)
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- p.Do(ctx, nil, func(ctx context.Context) error { <-release; return nil })
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if err := p.Do(ctx, nil, ok); !errors.Is(err, ErrOpen) {
+		t.Errorf("second probe should be rejected, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if p.State() != StateClosed || testutil.ToFloat64(state) != float64(StateClosed) {
+		t.Errorf("want closed, got %s", p.State())
+	}
+	o.AssertLogged(t, logit.NoticeLevel, "resilience breaker state changed")
+	if got := testutil.ToFloat64(rejected.WithLabelValues("breaker_test", "breaker_open")) - rejectedBefore; got != 2 {
+		t.Errorf("want 2 rejected, got %v", got)
+	}
+}
+
+func TestBreakerPanic(t *testing.T) {
+	logittest.ReplaceSvrLogger(t)
+	ctx := context.Background()
+	p := New("breaker_panic", 0, Config{BreakerFailures: 1, BreakerOpenTime: 20})
+	p.Do(ctx, nil, func(ctx context.Context) error { return errTemporary })
+	time.Sleep(25 * time.Millisecond)
+
+	// 半开时探测请求 panic，计为失败并重新熔断
+	func() {
+		defer func() {
+			if r := recover(); r != "probe" {
+				t.Errorf("panic should be passed on, got %v", r)
+			}
+		}()
+		p.Do(ctx, nil, func(ctx context.Context) error { panic("probe") })
+	}()
+	if p.State() != StateOpen {
+		t.Fatalf("panicked probe should reopen, got %s", p.State())
+	}
+	time.Sleep(25 * time.Millisecond)
+	if err := p.Do(ctx, nil, func(ctx context.Context) error { return nil }); err != nil || p.State() != StateClosed {
+		t.Errorf("want closed after probe, got %s %v", p.State(), err)
+	}
+}
+
+// onChange 在释放锁后调用，可以读取熔断器状态，ctx 为调用方的 ctx
+func TestBreakerNotify(t *testing.T) {
+	type ctxKey struct{}
+	var b *breaker
+	var states []State
+	b = newBreaker(1, 10*time.Millisecond, 1, func(ctx context.Context, from, to State) {
+		if ctx.Value(ctxKey{}) != "caller" {
+			t.Errorf("want caller ctx in onChange")
+		}
+		states = append(states, b.current())
+	})
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller")
+	gen, _ := b.allow(ctx)
+	b.done(ctx, gen, true)
+	time.Sleep(15 * time.Millisecond)
+	gen, _ = b.allow(ctx)
+	b.done(ctx, gen, false)
+	if want := []State{StateOpen, StateHalfOpen, StateClosed}; !slices.Equal(states, want) {
+		t.Errorf("want %v, got %v", want, states)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	ctx := context.Background()
+	p := New("bulkhead_test", 0, Config{MaxConcurrent: 1, BreakerFailures: -1})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- p.Do(ctx, nil, func(ctx context.Context) error { <-release; return nil })
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if err := p.Do(ctx, nil, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrBulkheadFull) {
+		t.Errorf("want ErrBulkheadFull, got %v", err)
+	}
+
+	// 等待并发名额
+	p.bulkhead.wait = time.Second
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	if err := p.Do(ctx, nil, func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("should get a slot after waiting, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,12 +2,13 @@
  * @Author: liziwei01
  * @Date: 2022-03-03 19:50:47
  * @LastEditors: liziwei01
- * @LastEditTime: 2022-06-30 04:47:26
+ * @LastEditTime: 2026-10-22 10:31:09
  * @Description: 标准错误函数
  */
 package response
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/liziwei01/gin-lib/library/utils"
@@ -56,6 +57,23 @@ func StdWithCode(ctx *gin.Context, code int, data ...interface{}) {
 	StdResponse(ctx, code, CodeMsgMap[code], data...)
 }
 
+// data可不传.
+// 根据错误返回, 错误码取自错误链中实现了 ErrorCode 方法的错误(如 library/errors 创建的错误), 没有设置错误码时按失败返回.
+func StdError(ctx *gin.Context, err error, data ...interface{}) {
+	if err == nil {
+		StdSuccess(ctx, data...)
+		return
+	}
+	code := Failed
+	var coder interface{ ErrorCode() (int, bool) }
+	if errors.As(err, &coder) {
+		if c, ok := coder.ErrorCode(); ok {
+			code = c
+		}
+	}
+	StdWithCode(ctx, code, data...)
+}
+
 // data可不传.
 // 传入完整错误码、错误信息拼凑返回信息返回.
 func StdResponse(ctx *gin.Context, code int, msg string, data ...interface{}) {
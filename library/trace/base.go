@@ -0,0 +1,187 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 19:20:18
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 19:20:18
+ * @Description: 链路追踪的全局配置：采样、导出器注册与初始化
+ */
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/conf"
+)
+
+const (
+	// 配置文件，conf/trace/trace.toml
+	traceConfName = "trace/trace.toml"
+
+	exporterNameNone     = "none"
+	exporterNameOTLPHTTP = "otlp_http"
+)
+
+// Config 链路追踪配置
+type Config struct {
+	// 上报的 service.name
+	ServiceName string
+
+	// 采样率，0~1，上游已决定是否采样时遵循上游
+	// 配置文件中未配置时为1
+	SampleRatio *float64
+
+	// 导出器名称，可选 none、otlp_http，可通过 RegisterExporter 自定义
+	Exporter string
+
+	OTLP OTLPOptions
+
+	Batch BatchOptions
+}
+
+// ExporterFunc 根据配置创建导出器
+type ExporterFunc func(cfg *Config) (Exporter, error)
+
+var (
+	exporterFuncs = map[string]ExporterFunc{
+		exporterNameOTLPHTTP: func(cfg *Config) (Exporter, error) {
+			opts := cfg.OTLP
+			if opts.ServiceName == "" {
+				opts.ServiceName = cfg.ServiceName
+			}
+			return NewOTLPHTTPExporter(&opts), nil
+		},
+	}
+	exporterMux sync.RWMutex
+)
+
+// RegisterExporter 注册自定义导出器，在配置文件中通过 Exporter 使用
+func RegisterExporter(name string, fn ExporterFunc) error {
+	exporterMux.Lock()
+	defer exporterMux.Unlock()
+	if _, has := exporterFuncs[name]; has || name == exporterNameNone {
+		return fmt.Errorf("exporter %q already exists", name)
+	}
+	exporterFuncs[name] = fn
+	return nil
+}
+
+// GetExporterFunc 获取已注册的导出器
+func GetExporterFunc(name string) ExporterFunc {
+	exporterMux.RLock()
+	defer exporterMux.RUnlock()
+	return exporterFuncs[name]
+}
+
+// provider 全局的采样与导出
+type provider struct {
+	processor Processor
+	// 采样阈值，trace-id 后8字节右移1位后小于该值时采样
+	threshold uint64
+}
+
+var (
+	globalProvider = &provider{threshold: ratioThreshold(1)}
+	providerMux    sync.RWMutex
+)
+
+func getProvider() *provider {
+	providerMux.RLock()
+	defer providerMux.RUnlock()
+	return globalProvider
+}
+
+func (p *provider) shouldSample(id TraceID) bool {
+	return binary.BigEndian.Uint64(id[8:])>>1 < p.threshold
+}
+
+func (p *provider) onEnd(s *SpanData) {
+	if p.processor != nil {
+		p.processor.OnEnd(s)
+	}
+}
+
+func ratioThreshold(ratio float64) uint64 {
+	if ratio >= 1 {
+		return math.MaxUint64
+	}
+	if ratio <= 0 {
+		return 0
+	}
+	return uint64(ratio * (1 << 63))
+}
+
+// SetProcessor 设置全局的 Processor，为nil时不导出；返回之前的 Processor
+func SetProcessor(p Processor) Processor {
+	providerMux.Lock()
+	defer providerMux.Unlock()
+	old := globalProvider.processor
+	globalProvider = &provider{
+		processor: p,
+		threshold: globalProvider.threshold,
+	}
+	return old
+}
+
+// SetSampleRatio 设置新链路的采样率，0~1
+func SetSampleRatio(ratio float64) {
+	providerMux.Lock()
+	defer providerMux.Unlock()
+	globalProvider = &provider{
+		processor: globalProvider.processor,
+		threshold: ratioThreshold(ratio),
+	}
+}
+
+// Init 读取 conf/trace/trace.toml 初始化链路追踪，配置文件不存在时只生成 ID、不导出
+//
+//	ctx 取消时导出剩余的 span 并关闭导出器
+func Init(ctx context.Context) error {
+	if !conf.Exists(traceConfName) {
+		return nil
+	}
+	var cfg *Config
+	if err := conf.Parse(traceConfName, &cfg); err != nil {
+		return err
+	}
+	return InitWithConfig(ctx, cfg)
+}
+
+// InitWithConfig 使用指定的配置初始化链路追踪
+func InitWithConfig(ctx context.Context, cfg *Config) error {
+	if cfg.SampleRatio != nil {
+		SetSampleRatio(*cfg.SampleRatio)
+	}
+	if cfg.Exporter == "" || cfg.Exporter == exporterNameNone {
+		return nil
+	}
+	fn := GetExporterFunc(cfg.Exporter)
+	if fn == nil {
+		return fmt.Errorf("trace exporter %q not found", cfg.Exporter)
+	}
+	exporter, err := fn(cfg)
+	if err != nil {
+		return err
+	}
+	p := NewBatchProcessor(exporter, &cfg.Batch)
+	if old := SetProcessor(p); old != nil {
+		_ = old.Shutdown(context.Background())
+	}
+
+	if ctx != nil {
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := p.Shutdown(shutdownCtx); err != nil {
+				fmt.Fprintf(os.Stderr, "%s trace shutdown failed: %v\n", time.Now(), err)
+			}
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 18:46:27
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 18:46:27
+ * @Description: span 的导出：Processor 负责攒批，Exporter 负责发送
+ */
+package trace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Exporter 将结束的 span 发送到后端
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []*SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Processor 接收结束的 span，交给 Exporter
+type Processor interface {
+	OnEnd(s *SpanData)
+	// ForceFlush 导出所有待发送的 span
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// NewSimpleProcessor 每个 span 结束时同步导出，一般用于测试
+func NewSimpleProcessor(exporter Exporter) Processor {
+	return &simpleProcessor{
+		exporter: exporter,
+	}
+}
+
+type simpleProcessor struct {
+	exporter Exporter
+}
+
+func (p *simpleProcessor) OnEnd(s *SpanData) {
+	if err := p.exporter.ExportSpans(context.Background(), []*SpanData{s}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s trace export failed: %v\n", time.Now(), err)
+	}
+}
+
+func (p *simpleProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func (p *simpleProcessor) Shutdown(ctx context.Context) error {
+	return p.exporter.Shutdown(ctx)
+}
+
+// BatchOptions NewBatchProcessor 的配置
+type BatchOptions struct {
+	// 待发送队列大小，队列满时丢弃新的 span，默认2048
+	MaxQueueSize int
+
+	// 每批最多发送的 span 数，默认512
+	MaxBatchSize int
+
+	// 发送间隔，毫秒，默认5000
+	Interval int
+}
+
+// NewBatchProcessor 在后台攒批导出，opts 可以为nil
+func NewBatchProcessor(exporter Exporter, opts *BatchOptions) Processor {
+	o := BatchOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.MaxQueueSize <= 0 {
+		o.MaxQueueSize = 2048
+	}
+	if o.MaxBatchSize <= 0 {
+		o.MaxBatchSize = 512
+	}
+	if o.Interval <= 0 {
+		o.Interval = 5000
+	}
+	p := &batchProcessor{
+		exporter: exporter,
+		opts:     o,
+		queue:    make(chan *SpanData, o.MaxQueueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+type batchProcessor struct {
+	exporter Exporter
+	opts     BatchOptions
+	queue    chan *SpanData
+	flush    chan chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+func (p *batchProcessor) OnEnd(s *SpanData) {
+	select {
+	case <-p.done:
+	case p.queue <- s:
+	default:
+		// 队列已满，丢弃
+	}
+}
+
+func (p *batchProcessor) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(time.Duration(p.opts.Interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, p.opts.MaxBatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.ExportSpans(context.Background(), batch); err != nil {
+			fmt.Fprintf(os.Stderr, "%s trace export %d spans failed: %v\n", time.Now(), len(batch), err)
+		}
+		batch = make([]*SpanData, 0, p.opts.MaxBatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case s := <-p.queue:
+				batch = append(batch, s)
+				if len(batch) >= p.opts.MaxBatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case s := <-p.queue:
+			batch = append(batch, s)
+			if len(batch) >= p.opts.MaxBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ch := <-p.flush:
+			drain()
+			close(ch)
+		case <-p.done:
+			drain()
+			return
+		}
+	}
+}
+
+func (p *batchProcessor) ForceFlush(ctx context.Context) error {
+	ch := make(chan struct{})
+	select {
+	case p.flush <- ch:
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *batchProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+	finished := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.exporter.Shutdown(ctx)
+}
+
+// InMemoryExporter 将 span 保存在内存中，用于测试
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+// NewInMemoryExporter 创建一个内存导出器
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []*SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Spans 已导出的 span，按结束顺序排列
+func (e *InMemoryExporter) Spans() []*SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := make([]*SpanData, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset 清空已导出的 span
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+var _ Exporter = (*InMemoryExporter)(nil)
@@ -0,0 +1,122 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 18:05:12
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 18:05:12
+ * @Description: trace-id、span-id 及其生成
+ */
+package trace
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+)
+
+// TraceID 16字节的链路ID，全0为非法值
+type TraceID [16]byte
+
+// SpanID 8字节的span ID，全0为非法值
+type SpanID [8]byte
+
+// IsValid 是否合法
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String 32位小写十六进制
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid 是否合法
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// String 16位小写十六进制
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// TraceIDFromHex 解析32位小写十六进制的 trace-id
+func TraceIDFromHex(h string) (TraceID, error) {
+	var t TraceID
+	if err := decodeHexID(t[:], h); err != nil {
+		return t, fmt.Errorf("invalid trace-id %q: %w", h, err)
+	}
+	return t, nil
+}
+
+// SpanIDFromHex 解析16位小写十六进制的 span-id
+func SpanIDFromHex(h string) (SpanID, error) {
+	var s SpanID
+	if err := decodeHexID(s[:], h); err != nil {
+		return s, fmt.Errorf("invalid span-id %q: %w", h, err)
+	}
+	return s, nil
+}
+
+// decodeHexID W3C 要求使用小写十六进制，且不能全为0
+func decodeHexID(dst []byte, h string) error {
+	if len(h) != len(dst)*2 {
+		return fmt.Errorf("length should be %d", len(dst)*2)
+	}
+	for i := 0; i < len(h); i++ {
+		if !isLowerHex(h[i]) {
+			return fmt.Errorf("should be lowercase hex")
+		}
+	}
+	if _, err := hex.Decode(dst, []byte(h)); err != nil {
+		return err
+	}
+	for _, b := range dst {
+		if b != 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("all zero")
+}
+
+func isLowerHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f')
+}
+
+// idGenerator 随机 ID 生成器，使用加密随机数作为种子
+type idGenerator struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newIDGenerator() *idGenerator {
+	var seed int64
+	_ = binary.Read(crand.Reader, binary.LittleEndian, &seed)
+	return &idGenerator{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (g *idGenerator) newTraceID() TraceID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var t TraceID
+	for !t.IsValid() {
+		_, _ = g.rand.Read(t[:])
+	}
+	return t
+}
+
+func (g *idGenerator) newSpanID() SpanID {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var s SpanID
+	for !s.IsValid() {
+		_, _ = g.rand.Read(s[:])
+	}
+	return s
+}
+
+var ids = newIDGenerator()
@@ -0,0 +1,226 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 19:02:55
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 19:02:55
+ * @Description: 通过 OTLP/HTTP(json 编码) 导出 span，可直接对接 OpenTelemetry Collector、Jaeger 等
+ */
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultOTLPEndpoint OpenTelemetry Collector 默认的 OTLP/HTTP 地址
+	DefaultOTLPEndpoint = "http://127.0.0.1:4318/v1/traces"
+
+	otlpScopeName = "github.com/liziwei01/gin-lib/library/trace"
+)
+
+// OTLPOptions NewOTLPHTTPExporter 的配置
+type OTLPOptions struct {
+	// 完整的上报地址，默认 DefaultOTLPEndpoint
+	Endpoint string
+
+	// 额外的请求头，如鉴权信息
+	Headers map[string]string
+
+	// 请求超时，毫秒，默认3000
+	Timeout int
+
+	// 上报的 service.name
+	ServiceName string
+}
+
+// NewOTLPHTTPExporter 创建 OTLP/HTTP 导出器，opts 可以为nil
+func NewOTLPHTTPExporter(opts *OTLPOptions) Exporter {
+	o := OTLPOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Endpoint == "" {
+		o.Endpoint = DefaultOTLPEndpoint
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 3000
+	}
+	return &otlpHTTPExporter{
+		opts: o,
+		client: &http.Client{
+			Timeout: time.Duration(o.Timeout) * time.Millisecond,
+		},
+	}
+}
+
+type otlpHTTPExporter struct {
+	opts   OTLPOptions
+	client *http.Client
+}
+
+func (e *otlpHTTPExporter) ExportSpans(ctx context.Context, spans []*SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest(e.opts.ServiceName, spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (e *otlpHTTPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// 以下为 OTLP ExportTraceServiceRequest 的 json 映射
+// trace-id、span-id 使用十六进制字符串，64位整数使用字符串
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		TraceState        string         `json:"traceState,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpStatus struct {
+		Code    StatusCode `json:"code,omitempty"`
+		Message string     `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+func otlpRequest(serviceName string, spans []*SpanData) *otlpTraces {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		item := otlpSpan{
+			TraceID:           s.SpanContext.TraceID.String(),
+			SpanID:            s.SpanContext.SpanID.String(),
+			TraceState:        s.SpanContext.TraceState,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.Attributes),
+			Status: otlpStatus{
+				Code:    s.StatusCode,
+				Message: s.StatusMessage,
+			},
+		}
+		if s.Parent.IsValid() {
+			item.ParentSpanID = s.Parent.SpanID.String()
+		}
+		for _, e := range s.Events {
+			item.Events = append(item.Events, otlpEvent{
+				TimeUnixNano: strconv.FormatInt(e.Time.UnixNano(), 10),
+				Name:         e.Name,
+				Attributes:   otlpAttributes(e.Attributes),
+			})
+		}
+		out = append(out, item)
+	}
+	return &otlpTraces{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes([]Attribute{String("service.name", serviceName)}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: otlpScopeName},
+				Spans: out,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v otlpAnyValue
+		switch val := a.Value.(type) {
+		case string:
+			v.StringValue = &val
+		case bool:
+			v.BoolValue = &val
+		case int64:
+			s := strconv.FormatInt(val, 10)
+			v.IntValue = &s
+		case int:
+			s := strconv.Itoa(val)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &val
+		default:
+			s := fmt.Sprint(val)
+			v.StringValue = &s
+		}
+		kvs = append(kvs, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return kvs
+}
+
+var _ Exporter = (*otlpHTTPExporter)(nil)
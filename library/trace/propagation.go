@@ -0,0 +1,168 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 18:11:40
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 18:11:40
+ * @Description: W3C Trace Context(traceparent、tracestate) 的解析与传递
+ */
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderTraceparent W3C traceparent 请求头
+	HeaderTraceparent = "traceparent"
+
+	// HeaderTracestate W3C tracestate 请求头
+	HeaderTracestate = "tracestate"
+
+	// 当前支持的 traceparent 版本
+	traceparentVersion = "00"
+
+	// version-traceid-spanid-flags
+	traceparentLen = 2 + 1 + 32 + 1 + 16 + 1 + 2
+
+	// tracestate 最多32个成员
+	maxTracestateMembers = 32
+
+	// tracestate 超长时整体丢弃
+	maxTracestateLen = 512
+)
+
+// TraceFlags traceparent 中的 trace-flags
+type TraceFlags byte
+
+// FlagsSampled 已采样
+const FlagsSampled TraceFlags = 0x01
+
+// IsSampled 是否已采样
+func (f TraceFlags) IsSampled() bool {
+	return f&FlagsSampled == FlagsSampled
+}
+
+// SpanContext span 中需要跨进程传递的部分
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	TraceFlags TraceFlags
+
+	// 原样透传的 tracestate
+	TraceState string
+
+	// 是否从上游解析得到
+	Remote bool
+}
+
+// IsValid trace-id 与 span-id 均合法
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled 是否已采样
+func (sc SpanContext) IsSampled() bool {
+	return sc.TraceFlags.IsSampled()
+}
+
+// ParseTraceparent 解析 traceparent
+//
+//	格式为 version-traceid-spanid-flags，如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+//	高于 00 的版本按 00 的格式解析前四段，忽略之后的内容
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	value = strings.TrimSpace(value)
+	if len(value) < traceparentLen {
+		return sc, fmt.Errorf("invalid traceparent %q: too short", value)
+	}
+	version := value[:2]
+	if !isLowerHex(version[0]) || !isLowerHex(version[1]) || version == "ff" {
+		return sc, fmt.Errorf("invalid traceparent version %q", version)
+	}
+	if version == traceparentVersion && len(value) != traceparentLen {
+		return sc, fmt.Errorf("invalid traceparent %q: wrong length", value)
+	}
+	if len(value) > traceparentLen && value[traceparentLen] != '-' {
+		return sc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, fmt.Errorf("invalid traceparent %q: wrong delimiter", value)
+	}
+
+	var err error
+	if sc.TraceID, err = TraceIDFromHex(value[3:35]); err != nil {
+		return sc, err
+	}
+	if sc.SpanID, err = SpanIDFromHex(value[36:52]); err != nil {
+		return sc, err
+	}
+	flags := value[53:55]
+	if !isLowerHex(flags[0]) || !isLowerHex(flags[1]) {
+		return sc, fmt.Errorf("invalid trace-flags %q", flags)
+	}
+	f, _ := hex.DecodeString(flags)
+	sc.TraceFlags = TraceFlags(f[0])
+	sc.Remote = true
+	return sc, nil
+}
+
+// FormatTraceparent 生成 traceparent，SpanContext 非法时返回空串
+func FormatTraceparent(sc SpanContext) string {
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceparentVersion, sc.TraceID, sc.SpanID, byte(sc.TraceFlags&FlagsSampled))
+}
+
+// normalizeTracestate 校验 tracestate，成员过多或过长时丢弃
+func normalizeTracestate(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > maxTracestateLen {
+		return ""
+	}
+	members := make([]string, 0, 4)
+	for _, m := range strings.Split(value, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		if i := strings.IndexByte(m, '='); i <= 0 || i == len(m)-1 {
+			return ""
+		}
+		members = append(members, m)
+	}
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+// Extract 从请求头中解析上游的 SpanContext，存入 ctx，之后 Start 的 span 会以其为 parent
+//
+//	traceparent 不合法时返回原 ctx
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get(HeaderTraceparent))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = normalizeTracestate(strings.Join(header.Values(HeaderTracestate), ","))
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject 将 ctx 中当前的 SpanContext 写入请求头，用于调用下游服务
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(HeaderTraceparent, FormatTraceparent(sc))
+	if sc.TraceState != "" {
+		header.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		header.Del(HeaderTracestate)
+	}
+}
@@ -0,0 +1,364 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 18:24:03
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 10:52:27
+ * @Description: span 的创建与记录
+ */
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+const (
+	// FieldTraceID 日志中 trace-id 的字段名
+	FieldTraceID = "traceID"
+
+	// FieldSpanID 日志中 span-id 的字段名
+	FieldSpanID = "spanID"
+)
+
+// SpanKind span 类型，取值与 OTLP 一致
+type SpanKind int
+
+const (
+	SpanKindUnspecified SpanKind = iota
+	SpanKindInternal
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// StatusCode span 状态，取值与 OTLP 一致
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// Attribute span 属性，Value 支持 string、bool、int、int64、float64
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String string 类型的属性
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int int 类型的属性
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Int64 int64 类型的属性
+func Int64(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool bool 类型的属性
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Float64 float64 类型的属性
+func Float64(key string, value float64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Event span 内的事件
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// SpanData 结束后的 span，交给 Exporter 导出
+type SpanData struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+
+	// 为空表示根 span
+	Parent SpanContext
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	Attributes    []Attribute
+	Events        []Event
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span 一次操作的记录
+//
+//	方法均是并发安全的，nil 也可以调用，不会有任何效果
+//	未采样的 span 只用于传递 ID，不记录属性、不导出
+type Span struct {
+	mu        sync.Mutex
+	data      SpanData
+	recording bool
+	ended     bool
+}
+
+type spanContextKey int
+
+const (
+	ctxSpanKey spanContextKey = iota
+	ctxRemoteSpanContextKey
+)
+
+// ContextWithRemoteSpanContext 将上游的 SpanContext 存入 ctx
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, ctxRemoteSpanContextKey, sc)
+}
+
+// SpanFromContext 获取 ctx 中当前的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(ctxSpanKey).(*Span)
+	return s
+}
+
+// SpanContextFromContext 获取 ctx 中当前的 SpanContext，本进程的 span 优先，其次是上游传入的
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.SpanContext()
+	}
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(ctxRemoteSpanContextKey).(SpanContext)
+	return sc
+}
+
+// Start 创建一个 span，并返回包含该 span 的 ctx
+//
+//	ctx 中已有 span 或上游 SpanContext 时，作为其子 span，沿用 trace-id 与采样结果
+//	本进程内的第一个 span 会将 traceID、spanID 写入 logit meta fields，让该请求的所有日志都可以关联到链路；
+//	client、producer span 是 mysql、redis 等调用方库创建的，没有父 span 时（如定时任务）只作为独立的链路，
+//	不修改 meta fields，避免调用方日志的 traceID 在每次调用后变化
+//	使用完毕后需要调用 End
+func Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cfg := &spanConfig{kind: SpanKindInternal}
+	for _, opt := range opts {
+		opt.apply(cfg)
+	}
+
+	localParent := SpanFromContext(ctx)
+	parent := SpanContextFromContext(ctx)
+	if cfg.newRoot {
+		localParent, parent = nil, SpanContext{}
+	}
+
+	sc := SpanContext{
+		SpanID: ids.newSpanID(),
+	}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.TraceFlags = parent.TraceFlags
+		sc.TraceState = parent.TraceState
+	} else {
+		sc.TraceID = ids.newTraceID()
+		if getProvider().shouldSample(sc.TraceID) {
+			sc.TraceFlags = FlagsSampled
+		}
+	}
+
+	s := &Span{
+		recording: sc.IsSampled(),
+		data: SpanData{
+			Name:        name,
+			Kind:        cfg.kind,
+			SpanContext: sc,
+			StartTime:   cfg.startTime,
+		},
+	}
+	if parent.IsValid() {
+		s.data.Parent = parent
+	}
+	if s.data.StartTime.IsZero() {
+		s.data.StartTime = time.Now()
+	}
+	if s.recording {
+		s.data.Attributes = append(s.data.Attributes, cfg.attributes...)
+	}
+
+	ctx = context.WithValue(ctx, ctxSpanKey, s)
+	if localParent == nil && cfg.kind != SpanKindClient && cfg.kind != SpanKindProducer {
+		ctx = logit.WithContext(ctx)
+		logit.ReplaceMetaFields(ctx,
+			logit.String(FieldTraceID, sc.TraceID.String()),
+			logit.String(FieldSpanID, sc.SpanID.String()),
+		)
+	}
+	return ctx, s
+}
+
+// SpanContext 获取 span 的 SpanContext
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	// 创建后不会修改，不需要加锁
+	return s.data.SpanContext
+}
+
+// IsRecording 是否会记录属性并导出
+func (s *Span) IsRecording() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recording && !s.ended
+}
+
+// SetName 修改 span 名称，如 HTTP 路由在处理完成后才能确定
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttributes 添加属性
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+// AddEvent 添加事件
+func (s *Span) AddEvent(name string, attrs ...Attribute) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Events = append(s.data.Events, Event{
+		Name:       name,
+		Time:       time.Now(),
+		Attributes: attrs,
+	})
+}
+
+// RecordError 记录错误事件，并将状态置为 StatusError，err 为 nil 时不做任何事
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.AddEvent("exception", String("exception.message", err.Error()))
+	s.SetStatus(StatusError, err.Error())
+}
+
+// SetStatus 设置状态
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.StatusCode = code
+	if code == StatusError {
+		s.data.StatusMessage = message
+	} else {
+		s.data.StatusMessage = ""
+	}
+}
+
+// End 结束 span，已采样的 span 交给 Processor 导出；多次调用只有第一次生效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	recording := s.recording
+	data := s.data
+	s.mu.Unlock()
+
+	if recording {
+		getProvider().onEnd(&data)
+	}
+}
+
+// SpanOption Start 的可选参数
+type SpanOption interface {
+	apply(*spanConfig)
+}
+
+type spanConfig struct {
+	kind       SpanKind
+	attributes []Attribute
+	startTime  time.Time
+	newRoot    bool
+}
+
+type funcSpanOption struct {
+	f func(*spanConfig)
+}
+
+func (fo *funcSpanOption) apply(c *spanConfig) {
+	fo.f(c)
+}
+
+func newFuncSpanOption(f func(*spanConfig)) *funcSpanOption {
+	return &funcSpanOption{
+		f: f,
+	}
+}
+
+// WithSpanKind 设置 span 类型，默认为 SpanKindInternal
+func WithSpanKind(kind SpanKind) SpanOption {
+	return newFuncSpanOption(func(c *spanConfig) {
+		c.kind = kind
+	})
+}
+
+// WithAttributes 设置初始属性
+func WithAttributes(attrs ...Attribute) SpanOption {
+	return newFuncSpanOption(func(c *spanConfig) {
+		c.attributes = append(c.attributes, attrs...)
+	})
+}
+
+// WithStartTime 设置开始时间，默认为当前时间
+func WithStartTime(t time.Time) SpanOption {
+	return newFuncSpanOption(func(c *spanConfig) {
+		c.startTime = t
+	})
+}
+
+// WithNewRoot 忽略 ctx 中的 parent，开始一条新的链路
+func WithNewRoot() SpanOption {
+	return newFuncSpanOption(func(c *spanConfig) {
+		c.newRoot = true
+	})
+}
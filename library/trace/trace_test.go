@@ -0,0 +1,173 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 20:31:47
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-22 10:52:27
+ * @Description: 链路追踪用例
+ */
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liziwei01/gin-lib/library/logit"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.IsSampled() || !sc.Remote {
+		t.Errorf("unexpected span context %+v", sc)
+	}
+	if got := FormatTraceparent(sc); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("format got %q", got)
+	}
+	// 更高的版本只解析前四段
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future version should be accepted: %v", err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("%q should be invalid", bad)
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	h := http.Header{}
+	h.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Add(HeaderTracestate, "congo=t61rcWkgMzE")
+	h.Add(HeaderTracestate, "rojo=00f067aa0ba902b7")
+	ctx := Extract(context.Background(), h)
+
+	ctx, span := Start(ctx, "server", WithSpanKind(SpanKindServer))
+	defer span.End()
+	sc := span.SpanContext()
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() == "00f067aa0ba902b7" {
+		t.Errorf("child should keep trace-id and get new span-id: %+v", sc)
+	}
+
+	out := http.Header{}
+	Inject(ctx, out)
+	if out.Get(HeaderTraceparent) != FormatTraceparent(sc) || out.Get(HeaderTracestate) != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Errorf("unexpected injected header %v", out)
+	}
+}
+
+func TestSpanExport(t *testing.T) {
+	exp := NewInMemoryExporter()
+	old := SetProcessor(NewSimpleProcessor(exp))
+	defer SetProcessor(old)
+
+	ctx := logit.WithContext(context.Background())
+	ctx, root := Start(ctx, "root", WithSpanKind(SpanKindServer))
+	_, child := Start(ctx, "mysql SELECT", WithSpanKind(SpanKindClient), WithAttributes(String("db.system", "mysql")))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End()
+
+	if f := logit.FindMetaField(ctx, FieldTraceID); f == nil || f.Value() != root.SpanContext().TraceID.String() {
+		t.Errorf("traceID meta field should be set, got %v", f)
+	}
+	if f := logit.FindMetaField(ctx, FieldSpanID); f == nil || f.Value() != root.SpanContext().SpanID.String() {
+		t.Errorf("spanID meta field should be the entry span, got %v", f)
+	}
+
+	spans := exp.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(spans))
+	}
+	c, r := spans[0], spans[1]
+	if c.Parent.SpanID != r.SpanContext.SpanID || c.SpanContext.TraceID != r.SpanContext.TraceID || r.Parent.IsValid() {
+		t.Errorf("wrong parent relation: child=%+v root=%+v", c, r)
+	}
+	if c.StatusCode != StatusError || c.StatusMessage != "boom" || len(c.Events) != 1 || len(c.Attributes) != 1 {
+		t.Errorf("unexpected child span %+v", c)
+	}
+
+	// 没有父 span 的 client span（如定时任务中的 mysql 调用）不修改调用方的 meta fields
+	jobCtx := logit.WithContext(context.Background())
+	logit.ReplaceMetaFields(jobCtx, logit.String(FieldTraceID, "job"))
+	_, call := Start(jobCtx, "redis GET", WithSpanKind(SpanKindClient))
+	call.End()
+	if f := logit.FindMetaField(jobCtx, FieldTraceID); f == nil || f.Value() != "job" {
+		t.Errorf("client span without parent should not replace the traceID meta field, got %v", f)
+	}
+	if f := logit.FindMetaField(jobCtx, FieldSpanID); f != nil {
+		t.Errorf("client span without parent should not set the spanID meta field, got %v", f)
+	}
+
+	// 未采样的 span 不导出，但会传递 ID
+	exp.Reset()
+	SetSampleRatio(0)
+	defer SetSampleRatio(1)
+	ctx, span := Start(context.Background(), "unsampled")
+	span.SetAttributes(String("k", "v"))
+	span.End()
+	if len(exp.Spans()) != 0 || span.IsRecording() || !SpanContextFromContext(ctx).IsValid() {
+		t.Errorf("unsampled span should not be exported")
+	}
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+	}))
+	defer srv.Close()
+
+	exp := NewOTLPHTTPExporter(&OTLPOptions{
+		Endpoint:    srv.URL,
+		Headers:     map[string]string{"X-Token": "t"},
+		ServiceName: "svc",
+	})
+	p := NewBatchProcessor(exp, &BatchOptions{Interval: 60000})
+	old := SetProcessor(p)
+	defer SetProcessor(old)
+
+	_, span := Start(context.Background(), "op", WithAttributes(Int("n", 1), Bool("b", true)))
+	span.End()
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	svc := rs["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	if svc["value"].(map[string]interface{})["stringValue"] != "svc" {
+		t.Errorf("unexpected resource %v", rs["resource"])
+	}
+	s := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	if s["traceId"] != span.SpanContext().TraceID.String() || s["name"] != "op" {
+		t.Errorf("unexpected span %v", s)
+	}
+	attr := s["attributes"].([]interface{})[0].(map[string]interface{})
+	if attr["value"].(map[string]interface{})["intValue"] != "1" {
+		t.Errorf("int attribute should be encoded as string, got %v", attr)
+	}
+}
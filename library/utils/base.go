@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-03-03 19:52:24
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-28 14:12:00
+ * @LastEditTime: 2026-10-20 20:02:37
  * @Description:
  */
 package utils
@@ -23,6 +23,7 @@ type (
 	UTime    byte
 	UEncrypt byte
 	UXlsx    byte
+	UCsv     byte
 )
 
 var (
@@ -35,6 +36,7 @@ var (
 	Time    *UTime
 	Encrypt *UEncrypt
 	Xlsx    *UXlsx
+	Csv     *UCsv
 )
 
 type (
@@ -59,6 +61,13 @@ type (
 
 	// CallBack 回调执行函数,无参数且无返回值
 	CallBack func()
+
+	// RowReader 逐行读取的数据源,如 mysql.Rows
+	RowReader interface {
+		Next() bool
+		Strings() ([]string, error)
+		Err() error
+	}
 )
 
 const (
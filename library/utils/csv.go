@@ -0,0 +1,62 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-20 20:02:37
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-20 20:02:37
+ * @Description: 逐行写入csv文件
+ */
+package utils
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+)
+
+const (
+	// 每写入多少行刷新一次
+	CSV_FLUSH_LINES = 1000
+)
+
+/**
+ * @name:  逐行读取数据写入csv文件
+ * @description: 每 CSV_FLUSH_LINES 行刷新一次, w 为 http.ResponseWriter 时边查边下载
+ * @return {*}
+ */
+func (u *UCsv) WriteCsv(ctx context.Context, w io.Writer, header []string, rows RowReader) error {
+	writer := csv.NewWriter(w)
+	flush := func() error {
+		writer.Flush()
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return writer.Error()
+	}
+	if len(header) > 0 {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	for line := 1; rows.Next(); line++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := rows.Strings()
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+		if line%CSV_FLUSH_LINES == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
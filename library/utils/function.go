@@ -2,7 +2,7 @@
  * @Author: liziwei01
  * @Date: 2022-06-28 01:12:49
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-28 14:11:07
+ * @LastEditTime: 2026-10-23 11:44:20
  * @Description: file content
  */
 package utils
@@ -89,8 +89,8 @@ func reflectPtr(r reflect.Value) reflect.Value {
 	return r
 }
 
-// creditChecksum 计算身份证校验码,其中id为身份证号码.
-func creditChecksum(id string) byte {
+// CreditChecksum 计算18位身份证的校验码,其中id为身份证号码,只使用前17位.
+func CreditChecksum(id string) byte {
 	//∑(ai×Wi)(mod 11)
 	// 加权因子
 	factor := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
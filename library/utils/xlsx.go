@@ -2,16 +2,18 @@
  * @Author: liziwei01
  * @Date: 2023-10-28 14:00:38
  * @LastEditors: liziwei01
- * @LastEditTime: 2023-10-28 14:12:11
- * @Description: 读取xlsx文件
+ * @LastEditTime: 2026-10-23 13:32:18
+ * @Description: 读写xlsx文件
  */
 package utils
 
 import (
 	"context"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/360EntSecGroup-Skylar/excelize"
 )
@@ -20,6 +22,13 @@ const (
 	CONNECT_SIGN = ","
 )
 
+// xlsx 的行数、列数与表名长度的上限
+const (
+	xlsxMaxRows      = 1048576
+	xlsxMaxCols      = 16384
+	xlsxMaxSheetName = 31
+)
+
 /**
  * @name:  读取xlsx文件 跳过指定行
  * @return {*}
@@ -53,3 +62,66 @@ func (u *UXlsx) ReadXlsx(ctx context.Context, fileReader multipart.File, input m
 	}
 	return rst, nil
 }
+
+/**
+ * @name:  逐行读取数据写入xlsx文件
+ * @description: excelize 在内存中生成整个文件, 数据量很大时请使用 Csv.WriteCsv
+ * @return {*}
+ */
+func (u *UXlsx) WriteXlsx(ctx context.Context, w io.Writer, sheet string, header []string, rows RowReader) error {
+	file := excelize.NewFile()
+	if sheet == "" {
+		sheet = "Sheet1"
+	}
+	if err := checkSheetName(sheet); err != nil {
+		return err
+	}
+	file.SetSheetName("Sheet1", sheet)
+	line := 1
+	if len(header) > 0 {
+		if err := setSheetRow(file, sheet, line, header); err != nil {
+			return err
+		}
+		line++
+	}
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := rows.Strings()
+		if err != nil {
+			return err
+		}
+		if err := setSheetRow(file, sheet, line, row); err != nil {
+			return err
+		}
+		line++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return file.Write(w)
+}
+
+// checkSheetName excelize 会截断过长的表名，非法字符会生成损坏的文件
+func checkSheetName(sheet string) error {
+	if utf8.RuneCountInString(sheet) > xlsxMaxSheetName {
+		return fmt.Errorf("xlsx: sheet name %q is longer than %d characters", sheet, xlsxMaxSheetName)
+	}
+	if strings.ContainsAny(sheet, ":\\/?*[]") {
+		return fmt.Errorf("xlsx: sheet name %q contains invalid characters", sheet)
+	}
+	return nil
+}
+
+// setSheetRow 写入第 line 行，excelize 的 SetSheetRow 不返回错误，超出范围的行在这里检查
+func setSheetRow(file *excelize.File, sheet string, line int, row []string) error {
+	if line > xlsxMaxRows {
+		return fmt.Errorf("xlsx: more than %d rows", xlsxMaxRows)
+	}
+	if len(row) > xlsxMaxCols {
+		return fmt.Errorf("xlsx: row %d has %d columns, more than %d", line, len(row), xlsxMaxCols)
+	}
+	file.SetSheetRow(sheet, fmt.Sprintf("A%d", line), &row)
+	return nil
+}
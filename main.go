@@ -1,7 +1,7 @@
 /*
  * @Author: liziwei01
  * @Date: 2021-04-19 15:00:00
- * @LastEditTime: 2023-05-13 05:01:37
+ * @LastEditTime: 2026-10-22 09:40:18
  * @LastEditors: liziwei01
  * @Description: main
  * @FilePath: /github.com/liziwei01/gin-lib/main.go
@@ -10,6 +10,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/liziwei01/gin-lib/bootstrap"
 	"github.com/liziwei01/gin-lib/httpapi"
@@ -21,12 +22,20 @@ import (
  * @return {*}
  */
 func main() {
+	// ./gin-lib migrate [-servicer db_lib] [-dry-run] [-steps 1] up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := bootstrap.Migrate(os.Args[2:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
 	appServer, err := bootstrap.Setup()
 	if err != nil {
 		log.Fatalln(err)
 	}
 	// 注册接口路由
 	httpapi.InitRouters(appServer.Handler)
+	httpapi.InitAdminRouters(appServer.AdminHandler)
 
 	appServer.Start()
 }
@@ -10,6 +10,7 @@ package middleware
 import (
 	"context"
 	"path/filepath"
+	"time"
 
 	"github.com/liziwei01/gin-lib/library/conf"
 
@@ -21,12 +22,14 @@ const (
 	freqControlConfName = "freq_control.toml"
 	signConfName        = "sign.toml"
 	tokenConfName       = "token.toml"
+	tailLogConfName     = "tail_log.toml"
 )
 
 var (
 	freqControlConf *FreqControl
 	tokenConf       *Token
 	signConf        *Sign
+	tailLogConf     *TailLog
 	initConfig      = false
 
 	getLimiter  *rate.Limiter
@@ -59,12 +62,61 @@ type Sign struct {
 	NoSignPath []string
 }
 
+// TailLog 请求级别的调试日志缓冲配置
+type TailLog struct {
+	Enable bool
+
+	// 每个请求最多缓冲的日志行数
+	MaxLines int
+
+	// 请求耗时超过该值(ms)时输出缓冲的日志，0 表示不按耗时判断
+	LatencyThreshold int64
+
+	// 按路由单独配置
+	Routes []*TailLogRoute
+}
+
+// TailLogRoute 单个路由的缓冲配置，未配置(零值)的项沿用全局配置
+type TailLogRoute struct {
+	// gin 注册的路由，如 /user/:id
+	Path string
+
+	Disable          bool
+	MaxLines         int
+	LatencyThreshold int64
+}
+
+// route 获取路由生效的配置
+func (t *TailLog) route(path string) (enable bool, maxLines int, threshold time.Duration) {
+	if t == nil || !t.Enable {
+		return false, 0, 0
+	}
+	maxLines, ms := t.MaxLines, t.LatencyThreshold
+	for _, r := range t.Routes {
+		if r.Path != path {
+			continue
+		}
+		if r.Disable {
+			return false, 0, 0
+		}
+		if r.MaxLines > 0 {
+			maxLines = r.MaxLines
+		}
+		if r.LatencyThreshold > 0 {
+			ms = r.LatencyThreshold
+		}
+		break
+	}
+	return true, maxLines, time.Duration(ms) * time.Millisecond
+}
+
 func Init(ctx context.Context) {
 	if initConfig == false {
 		initConfig = true
 		getConfig(freqControlConfName, &freqControlConf)
 		getConfig(signConfName, &signConf)
 		getConfig(tokenConfName, &tokenConf)
+		getConfig(tailLogConfName, &tailLogConf)
 		// initFreqControl()
 	}
 }
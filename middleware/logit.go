@@ -14,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/liziwei01/gin-lib/library/logit"
+	"github.com/liziwei01/gin-lib/library/request"
 )
 
 // 创建一个全局的 sync.Pool
@@ -27,18 +28,32 @@ var fieldsPool = sync.Pool{
 // LogitMiddleware instance a Logger middleware
 func LogitMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		ctx.Request = ctx.Request.WithContext(logit.WithContext(ctx.Request.Context()))
 		// Start timer
 		start := time.Now()
 		path := ctx.Request.URL.Path
 		raw := ctx.Request.URL.RawQuery
 
-		requestID := ctx.Request.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = logit.NewRequestID()
+		// 已注册 RequestIDMiddleware 时直接复用其结果
+		requestID := request.EnsureRequestID(ctx)
+
+		// 调试日志缓冲，请求出错时才输出
+		enable, maxLines, threshold := tailLogConf.route(ctx.FullPath())
+		if enable {
+			reqCtx, tb := logit.WithTailBuffer(ctx.Request.Context(), 0, maxLines)
+			ctx.Request = ctx.Request.WithContext(reqCtx)
+			defer func() {
+				// panic 时先输出缓冲的日志，再交给 Recovery 处理
+				if r := recover(); r != nil {
+					tb.Flush(ctx, logit.SvrLogger)
+					panic(r)
+				}
+				if shouldFlushTail(ctx, time.Since(start), threshold) {
+					tb.Flush(ctx, logit.SvrLogger)
+				} else {
+					tb.Discard()
+				}
+			}()
 		}
-		ctx.Writer.Header().Set("X-Request-ID", requestID)
-		logit.SetRequestID(ctx, requestID)
 
 		// Process request
 		ctx.Next()
@@ -51,7 +66,7 @@ func LogitMiddleware() gin.HandlerFunc {
 		fields := fieldsPool.Get().([]logit.Field)
 		defer fieldsPool.Put(fields) // 确保在函数结束时将 fields 对象放回 Pool
 
-		fields[0] = logit.String("requestID", requestID)
+		fields[0] = logit.RequestIDField(requestID)
 		fields[1] = logit.Int("statusCode", ctx.Writer.Status())
 		fields[2] = logit.Duration("latency", time.Now().Sub(start))
 		fields[3] = logit.String("ip", ctx.ClientIP())
@@ -68,3 +83,14 @@ func LogitMiddleware() gin.HandlerFunc {
 		}
 	}
 }
+
+// shouldFlushTail 请求是否需要输出缓冲的调试日志：5xx、有 gin private error 或耗时超过阈值
+func shouldFlushTail(ctx *gin.Context, latency, threshold time.Duration) bool {
+	if ctx.Writer.Status() >= 500 {
+		return true
+	}
+	if len(ctx.Errors.ByType(gin.ErrorTypePrivate)) > 0 {
+		return true
+	}
+	return threshold > 0 && latency > threshold
+}
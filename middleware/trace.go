@@ -0,0 +1,60 @@
+/*
+ * @Author: liziwei01
+ * @Date: 2026-10-19 19:41:30
+ * @LastEditors: liziwei01
+ * @LastEditTime: 2026-10-19 19:41:30
+ * @Description: 链路追踪中间件，解析上游 traceparent 并为每个请求创建 server span
+ */
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liziwei01/gin-lib/library/trace"
+)
+
+// TraceMiddleware 为每个请求创建 server span
+//
+//	需注册在 LogitMiddleware 之后，traceID、spanID 才能写入该请求的日志
+//	响应头中返回 traceparent，便于调用方关联
+func TraceMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reqCtx := trace.Extract(ctx.Request.Context(), ctx.Request.Header)
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unknown"
+		}
+		reqCtx, span := trace.Start(reqCtx, ctx.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				trace.String("http.method", ctx.Request.Method),
+				trace.String("http.route", route),
+				trace.String("http.target", ctx.Request.URL.Path),
+				trace.String("net.peer.ip", ctx.ClientIP()),
+			),
+		)
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Header(trace.HeaderTraceparent, trace.FormatTraceparent(span.SpanContext()))
+
+		defer func() {
+			if r := recover(); r != nil {
+				span.SetAttributes(trace.Int("http.status_code", http.StatusInternalServerError))
+				span.SetStatus(trace.StatusError, "panic")
+				panic(r)
+			}
+		}()
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(trace.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(trace.StatusError, http.StatusText(status))
+		}
+		if errs := ctx.Errors.ByType(gin.ErrorTypePrivate); len(errs) > 0 {
+			span.RecordError(errs.Last())
+		}
+	}
+}